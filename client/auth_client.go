@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"log"
+	"pc_book/pd"
+	"sync"
+	"time"
+)
+
+// AuthClient is a client to call authentication RPC
+type AuthClient struct {
+	service  pd.AuthServiceClient
+	username string
+	password string
+
+	mutex        sync.Mutex
+	refreshToken string
+}
+
+// NewAuthClient returns a new auth client
+func NewAuthClient(cc *grpc.ClientConn, username string, password string) *AuthClient {
+	service := pd.NewAuthServiceClient(cc)
+	return &AuthClient{
+		service:  service,
+		username: username,
+		password: password,
+	}
+}
+
+// Login logs in with the configured credentials and returns the access token
+func (client *AuthClient) Login() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req := &pd.LoginRequest{
+		Username: client.username,
+		Password: client.password,
+	}
+
+	res, err := client.service.Login(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	client.setRefreshToken(res.GetRefreshToken())
+	return res.GetAccessToken(), nil
+}
+
+// Refresh returns a new access token using the refresh token of the last login,
+// it logs in again if there is no refresh token or the server rejects it
+func (client *AuthClient) Refresh() (string, error) {
+	client.mutex.Lock()
+	refreshToken := client.refreshToken
+	client.mutex.Unlock()
+
+	if refreshToken == "" {
+		return client.Login()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := client.service.RefreshToken(ctx, &pd.RefreshTokenRequest{RefreshToken: refreshToken})
+	if err != nil {
+		log.Printf("cannot refresh token, login again: %v", err)
+		return client.Login()
+	}
+
+	client.setRefreshToken(res.GetRefreshToken())
+	return res.GetAccessToken(), nil
+}
+
+// Logout revokes the given access token and the refresh token of the last login
+func (client *AuthClient) Logout(accessToken string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client.mutex.Lock()
+	refreshToken := client.refreshToken
+	client.refreshToken = ""
+	client.mutex.Unlock()
+
+	req := &pd.LogoutRequest{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+
+	_, err := client.service.Logout(ctx, req)
+	return err
+}
+
+func (client *AuthClient) setRefreshToken(refreshToken string) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.refreshToken = refreshToken
+}
@@ -0,0 +1,418 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// retryLoginDelay is how long to wait before trying again after a failed background refresh
+const retryLoginDelay = time.Second
+
+// maxReplaySize is the most bytes of sent messages kept to replay a stream call rejected as Unauthenticated
+const maxReplaySize = 1 << 20
+
+// AuthInterceptor is a client interceptor that attaches an access token to every call
+type AuthInterceptor struct {
+	authClient    *AuthClient
+	refreshBefore time.Duration
+
+	mutex       sync.RWMutex
+	accessToken string
+	expiresAt   time.Time
+
+	// refreshMutex serializes the refreshes, the refresh token can only be used once
+	refreshMutex sync.Mutex
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewAuthInterceptor logs in and returns a new auth interceptor.
+// The token is refreshed in the background refreshBefore its expiration time.
+func NewAuthInterceptor(authClient *AuthClient, refreshBefore time.Duration) (*AuthInterceptor, error) {
+	interceptor := &AuthInterceptor{
+		authClient:    authClient,
+		refreshBefore: refreshBefore,
+		done:          make(chan struct{}),
+	}
+
+	err := interceptor.refreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	go interceptor.scheduleRefreshToken()
+	return interceptor, nil
+}
+
+// Close stops the background token refresh
+func (interceptor *AuthInterceptor) Close() {
+	interceptor.once.Do(func() {
+		close(interceptor.done)
+	})
+}
+
+// Unary returns a client interceptor to authenticate unary RPC
+func (interceptor *AuthInterceptor) Unary() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		token := interceptor.token()
+		err := invoker(interceptor.attachToken(ctx, token), method, req, reply, cc, opts...)
+		if !isUnauthenticated(err) {
+			return err
+		}
+
+		token, refreshErr := interceptor.refreshAfterReject(token)
+		if refreshErr != nil {
+			return err
+		}
+		return invoker(interceptor.attachToken(ctx, token), method, req, reply, cc, opts...)
+	}
+}
+
+// Stream returns a client interceptor to authenticate stream RPC
+func (interceptor *AuthInterceptor) Stream() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		token := interceptor.token()
+		stream, err := streamer(interceptor.attachToken(ctx, token), desc, cc, method, opts...)
+		if isUnauthenticated(err) {
+			token, refreshErr := interceptor.refreshAfterReject(token)
+			if refreshErr != nil {
+				return nil, err
+			}
+			return streamer(interceptor.attachToken(ctx, token), desc, cc, method, opts...)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &retryClientStream{
+			ClientStream: stream,
+			interceptor:  interceptor,
+			token:        token,
+			reopen: func(token string) (grpc.ClientStream, error) {
+				return streamer(interceptor.attachToken(ctx, token), desc, cc, method, opts...)
+			},
+			replayable: true,
+		}, nil
+	}
+}
+
+func (interceptor *AuthInterceptor) attachToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", token)
+}
+
+func (interceptor *AuthInterceptor) token() string {
+	interceptor.mutex.RLock()
+	defer interceptor.mutex.RUnlock()
+
+	return interceptor.accessToken
+}
+
+// refreshAfterReject gets a new token unless another call has already replaced the rejected token.
+// The calls rejected at the same time wait for the first refresh and use its token.
+func (interceptor *AuthInterceptor) refreshAfterReject(rejected string) (string, error) {
+	interceptor.refreshMutex.Lock()
+	defer interceptor.refreshMutex.Unlock()
+
+	if token := interceptor.token(); token != rejected {
+		return token, nil
+	}
+
+	err := interceptor.refresh()
+	if err != nil {
+		log.Printf("cannot refresh token: %v", err)
+		return "", err
+	}
+	return interceptor.token(), nil
+}
+
+func (interceptor *AuthInterceptor) refreshToken() error {
+	interceptor.refreshMutex.Lock()
+	defer interceptor.refreshMutex.Unlock()
+
+	return interceptor.refresh()
+}
+
+// refresh replaces the token, refreshMutex must be held
+func (interceptor *AuthInterceptor) refresh() error {
+	accessToken, err := interceptor.authClient.Refresh()
+	if err != nil {
+		return err
+	}
+
+	expiresAt, err := tokenExpiresAt(accessToken)
+	if err != nil {
+		return err
+	}
+
+	interceptor.mutex.Lock()
+	interceptor.accessToken = accessToken
+	interceptor.expiresAt = expiresAt
+	interceptor.mutex.Unlock()
+
+	log.Printf("token refreshed, expires at %v", expiresAt)
+	return nil
+}
+
+func (interceptor *AuthInterceptor) scheduleRefreshToken() {
+	wait := interceptor.nextRefreshWait()
+
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-interceptor.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := interceptor.refreshToken()
+		if err != nil {
+			log.Printf("cannot refresh token: %v", err)
+			wait = retryLoginDelay
+			continue
+		}
+		wait = interceptor.nextRefreshWait()
+	}
+}
+
+func (interceptor *AuthInterceptor) nextRefreshWait() time.Duration {
+	interceptor.mutex.RLock()
+	expiresAt := interceptor.expiresAt
+	interceptor.mutex.RUnlock()
+
+	lifetime := time.Until(expiresAt)
+	if interceptor.refreshBefore >= lifetime {
+		return lifetime / 2
+	}
+	return lifetime - interceptor.refreshBefore
+}
+
+// tokenExpiresAt reads the expiration time of a token, the signature is checked by the server
+func tokenExpiresAt(accessToken string) (time.Time, error) {
+	claims := &jwt.StandardClaims{}
+	_, _, err := new(jwt.Parser).ParseUnverified(accessToken, claims)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse access token: %w", err)
+	}
+
+	if claims.ExpiresAt == 0 {
+		return time.Time{}, fmt.Errorf("access token has no expiration time")
+	}
+	return time.Unix(claims.ExpiresAt, 0), nil
+}
+
+func isUnauthenticated(err error) bool {
+	return status.Code(err) == codes.Unauthenticated
+}
+
+// retryClientStream re-opens a call once if it is rejected as Unauthenticated before the first response,
+// and sends again the messages already sent. The messages are kept until they reach maxReplaySize,
+// a bigger call (e.g. a large image upload) is not replayed and the caller gets the Unauthenticated error.
+type retryClientStream struct {
+	grpc.ClientStream
+	interceptor *AuthInterceptor
+	token       string
+	reopen      func(token string) (grpc.ClientStream, error)
+
+	// mutex protects the fields below, SendMsg and RecvMsg can be called by different goroutines.
+	// It is never held during a network call.
+	mutex      sync.Mutex
+	messages   []proto.Message
+	size       int
+	closed     bool
+	replayable bool
+	// rejected is set when the server has ended the current call, the sent messages are only kept for the replay
+	rejected bool
+	// replaying is closed when the replay is done, it is nil if no replay is running
+	replaying chan struct{}
+}
+
+func (stream *retryClientStream) SendMsg(m interface{}) error {
+	stream.mutex.Lock()
+	if stream.rejected || stream.replaying != nil {
+		if stream.keep(m) {
+			stream.mutex.Unlock()
+			return nil
+		}
+
+		replaying := stream.replaying
+		stream.mutex.Unlock()
+		if replaying == nil {
+			// 不能重新发送, RecvMsg 返回服务器的状态
+			return io.EOF
+		}
+		// too much data for the replay, wait until the new call can send it
+		<-replaying
+		return stream.SendMsg(m)
+	}
+
+	stream.keep(m)
+	current := stream.ClientStream
+	stream.mutex.Unlock()
+
+	err := current.SendMsg(m)
+	if err != io.EOF {
+		return err
+	}
+
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.ClientStream != current {
+		// the call has been re-opened meanwhile, the message was sent again
+		return nil
+	}
+	if !stream.replayable {
+		return err
+	}
+	// 服务器已经结束了调用, 它的状态由 RecvMsg 读取, 如果是 Unauthenticated 就重新发送
+	stream.rejected = true
+	return nil
+}
+
+func (stream *retryClientStream) CloseSend() error {
+	stream.mutex.Lock()
+	stream.closed = true
+	current := stream.ClientStream
+	stream.mutex.Unlock()
+
+	return current.CloseSend()
+}
+
+func (stream *retryClientStream) RecvMsg(m interface{}) error {
+	stream.mutex.Lock()
+	current := stream.ClientStream
+	stream.mutex.Unlock()
+
+	err := current.RecvMsg(m)
+	if err == nil {
+		// the server has accepted the token
+		stream.mutex.Lock()
+		stream.replayable = false
+		stream.messages = nil
+		stream.mutex.Unlock()
+		return nil
+	}
+	if !isUnauthenticated(err) {
+		return err
+	}
+
+	other, err := stream.replay(current, err)
+	if err != nil {
+		return err
+	}
+	return other.RecvMsg(m)
+}
+
+// replay re-opens the rejected call with a new token and sends the kept messages again,
+// it returns the rejection if the call cannot be replayed.
+// The messages sent meanwhile are kept and sent after them.
+func (stream *retryClientStream) replay(rejected grpc.ClientStream, rejection error) (grpc.ClientStream, error) {
+	stream.mutex.Lock()
+	if !stream.replayable || stream.replaying != nil || stream.ClientStream != rejected {
+		stream.mutex.Unlock()
+		return nil, rejection
+	}
+	replaying := make(chan struct{})
+	stream.replaying = replaying
+	stream.rejected = true
+	token := stream.token
+	stream.mutex.Unlock()
+
+	// the replay ends without a new call
+	fail := func(err error) (grpc.ClientStream, error) {
+		stream.mutex.Lock()
+		stream.replayable = false
+		stream.messages = nil
+		stream.replaying = nil
+		stream.mutex.Unlock()
+		close(replaying)
+		return nil, err
+	}
+
+	token, refreshErr := stream.interceptor.refreshAfterReject(token)
+	if refreshErr != nil {
+		return fail(rejection)
+	}
+	other, err := stream.reopen(token)
+	if err != nil {
+		return fail(err)
+	}
+
+	sendFailed := false
+	for {
+		stream.mutex.Lock()
+		messages := stream.messages
+		stream.messages = nil
+		if len(messages) == 0 {
+			closed := stream.closed
+			stream.ClientStream = other
+			stream.token = token
+			stream.replayable = false
+			stream.rejected = false
+			stream.replaying = nil
+			stream.mutex.Unlock()
+			close(replaying)
+
+			if closed && !sendFailed {
+				if err := other.CloseSend(); err != nil {
+					return nil, err
+				}
+			}
+			return other, nil
+		}
+		stream.mutex.Unlock()
+
+		for _, message := range messages {
+			// on io.EOF the status of the new call is read by RecvMsg
+			if sendFailed || other.SendMsg(message) != nil {
+				sendFailed = true
+			}
+		}
+	}
+}
+
+// keep copies a sent message for a replay, the caller may reuse it once SendMsg returns.
+// It returns false if the message is not kept, during a replay the kept messages are never dropped.
+func (stream *retryClientStream) keep(m interface{}) bool {
+	if !stream.replayable {
+		return false
+	}
+
+	message, ok := m.(proto.Message)
+	if !ok || stream.size+proto.Size(message) > maxReplaySize {
+		if stream.replaying == nil {
+			stream.replayable = false
+			stream.messages = nil
+		}
+		return false
+	}
+
+	stream.size += proto.Size(message)
+	stream.messages = append(stream.messages, proto.Clone(message))
+	return true
+}
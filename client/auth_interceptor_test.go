@@ -0,0 +1,336 @@
+package client_test
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"net"
+	"pc_book/client"
+	"pc_book/pd"
+	"pc_book/sample"
+	"pc_book/service"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testSecretKey = "test-secret"
+	testUsername  = "admin1"
+	testPassword  = "secret"
+)
+
+// countingAuthService counts the logins and can hand out broken or expired tokens for the first ones
+type countingAuthService struct {
+	*service.AuthService
+	logins         int32
+	refreshes      int32
+	badLogins      int32
+	badManager     *service.JWTManager
+	expiredLogins  int32
+	expiredManager *service.JWTManager
+	user           *service.User
+}
+
+func (server *countingAuthService) Login(ctx context.Context, req *pd.LoginRequest) (*pd.LoginResponse, error) {
+	n := atomic.AddInt32(&server.logins, 1)
+	if n <= atomic.LoadInt32(&server.expiredLogins) {
+		token, err := server.expiredManager.Generate(server.user)
+		if err != nil {
+			return nil, err
+		}
+		return &pd.LoginResponse{AccessToken: token}, nil
+	}
+	if n <= atomic.LoadInt32(&server.badLogins) {
+		token, err := server.badManager.Generate(server.user)
+		if err != nil {
+			return nil, err
+		}
+		return &pd.LoginResponse{AccessToken: token}, nil
+	}
+	return server.AuthService.Login(ctx, req)
+}
+
+func (server *countingAuthService) RefreshToken(ctx context.Context, req *pd.RefreshTokenRequest) (*pd.RefreshTokenResponse, error) {
+	atomic.AddInt32(&server.refreshes, 1)
+	return server.AuthService.RefreshToken(ctx, req)
+}
+
+type testServer struct {
+	address     string
+	authService *countingAuthService
+	calls       int32
+	streamCalls int32
+}
+
+func startTestServer(t *testing.T, tokenDuration time.Duration, badLogins int32) *testServer {
+	user, err := service.NewUser(testUsername, testPassword, "admin")
+	require.NoError(t, err)
+
+	userStore := service.NewInMemoryUserStore()
+	require.NoError(t, userStore.Save(user))
+
+	jwtManager := service.NewJWTManager(testSecretKey, tokenDuration, time.Hour)
+	server := &testServer{
+		authService: &countingAuthService{
+			AuthService: service.NewAuthService(userStore, jwtManager),
+			badLogins:   badLogins,
+			badManager:  service.NewJWTManager("wrong-secret", tokenDuration, time.Hour),
+			// 同一个密钥, 但是令牌一分钟前就过期了
+			expiredManager: service.NewJWTManager(testSecretKey, -time.Minute, time.Hour),
+			user:           user,
+		},
+	}
+
+	accessibleRoles := map[string][]string{
+		"/LaptopService/CreateLaptop":  {"admin"},
+		"/LaptopService/SearchLaptop":  {"admin"},
+		"/LaptopService/ImportLaptops": {"admin"},
+	}
+	interceptor := service.NewAuthInterceptor(jwtManager, accessibleRoles)
+	countCalls := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, "/LaptopService/") {
+			atomic.AddInt32(&server.calls, 1)
+		}
+		return interceptor.Unary()(ctx, req, info, handler)
+	}
+	countStreamCalls := func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		atomic.AddInt32(&server.streamCalls, 1)
+		return interceptor.Stream()(srv, stream, info, handler)
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(countCalls),
+		grpc.StreamInterceptor(countStreamCalls),
+	)
+	pd.RegisterAuthServiceServer(grpcServer, server.authService)
+	pd.RegisterLaptopServiceServer(grpcServer, service.NewLaptopService(service.NewInMemoryLaptopStore(), nil, nil))
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	server.address = listener.Addr().String()
+	return server
+}
+
+func newTestLaptopClient(t *testing.T, address string, refreshBefore time.Duration) (pd.LaptopServiceClient, error) {
+	cc1, err := grpc.Dial(address, grpc.WithInsecure())
+	require.NoError(t, err)
+	t.Cleanup(func() { cc1.Close() })
+
+	authClient := client.NewAuthClient(cc1, testUsername, testPassword)
+	interceptor, err := client.NewAuthInterceptor(authClient, refreshBefore)
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(interceptor.Close)
+
+	cc2, err := grpc.Dial(
+		address,
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(interceptor.Unary()),
+		grpc.WithStreamInterceptor(interceptor.Stream()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { cc2.Close() })
+
+	return pd.NewLaptopServiceClient(cc2), nil
+}
+
+func TestAuthInterceptorAttachesToken(t *testing.T) {
+	t.Parallel()
+
+	server := startTestServer(t, time.Minute, 0)
+	laptopClient, err := newTestLaptopClient(t, server.address, 10*time.Second)
+	require.NoError(t, err)
+
+	laptop := sample.NewLaptop()
+	_, err = laptopClient.CreateLaptop(context.Background(), &pd.CreateLaptopRequest{Laptop: laptop})
+	require.NoError(t, err)
+
+	stream, err := laptopClient.SearchLaptop(context.Background(), &pd.SearchLaptopRequest{Filter: &pd.Filter{MaxPriceUsd: 5000}})
+	require.NoError(t, err)
+
+	found := 0
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, laptop.GetId(), res.GetLaptop().GetId())
+		found++
+	}
+	require.Equal(t, 1, found)
+	require.EqualValues(t, 1, atomic.LoadInt32(&server.authService.logins))
+}
+
+func TestAuthInterceptorRefreshesToken(t *testing.T) {
+	t.Parallel()
+
+	server := startTestServer(t, 2*time.Second, 0)
+	laptopClient, err := newTestLaptopClient(t, server.address, time.Second)
+	require.NoError(t, err)
+
+	// wait until the first token has expired
+	time.Sleep(3 * time.Second)
+
+	_, err = laptopClient.CreateLaptop(context.Background(), &pd.CreateLaptopRequest{Laptop: sample.NewLaptop()})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, atomic.LoadInt32(&server.authService.logins))
+	require.GreaterOrEqual(t, atomic.LoadInt32(&server.authService.refreshes), int32(1))
+	require.EqualValues(t, 1, atomic.LoadInt32(&server.calls), "the refreshed token should be accepted at once")
+}
+
+func TestAuthInterceptorRetriesOnce(t *testing.T) {
+	t.Parallel()
+
+	server := startTestServer(t, time.Minute, 1)
+	laptopClient, err := newTestLaptopClient(t, server.address, 10*time.Second)
+	require.NoError(t, err)
+
+	_, err = laptopClient.CreateLaptop(context.Background(), &pd.CreateLaptopRequest{Laptop: sample.NewLaptop()})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, atomic.LoadInt32(&server.calls))
+	require.EqualValues(t, 2, atomic.LoadInt32(&server.authService.logins))
+}
+
+func TestAuthInterceptorRetriesServerStreamOnce(t *testing.T) {
+	t.Parallel()
+
+	server := startTestServer(t, time.Minute, 1)
+	laptopClient, err := newTestLaptopClient(t, server.address, 10*time.Second)
+	require.NoError(t, err)
+
+	stream, err := laptopClient.SearchLaptop(context.Background(), &pd.SearchLaptopRequest{Filter: &pd.Filter{MaxPriceUsd: 5000}})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+	require.EqualValues(t, 2, atomic.LoadInt32(&server.authService.logins))
+}
+
+func TestAuthInterceptorRefreshesOnceForConcurrentRejects(t *testing.T) {
+	t.Parallel()
+
+	server := startTestServer(t, time.Minute, 1)
+	laptopClient, err := newTestLaptopClient(t, server.address, 10*time.Second)
+	require.NoError(t, err)
+
+	// every call is rejected with the first token, only one of them gets a new token
+	const calls = 10
+	errs := make(chan error, calls)
+	for i := 0; i < calls; i++ {
+		go func() {
+			_, err := laptopClient.CreateLaptop(context.Background(), &pd.CreateLaptopRequest{Laptop: sample.NewLaptop()})
+			errs <- err
+		}()
+	}
+	for i := 0; i < calls; i++ {
+		require.NoError(t, <-errs)
+	}
+	require.EqualValues(t, 2, atomic.LoadInt32(&server.authService.logins))
+}
+
+func TestAuthInterceptorGivesUpAfterOneRetry(t *testing.T) {
+	t.Parallel()
+
+	server := startTestServer(t, time.Minute, 100)
+	laptopClient, err := newTestLaptopClient(t, server.address, 10*time.Second)
+	require.NoError(t, err)
+
+	_, err = laptopClient.CreateLaptop(context.Background(), &pd.CreateLaptopRequest{Laptop: sample.NewLaptop()})
+	require.Error(t, err)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.EqualValues(t, 2, atomic.LoadInt32(&server.calls))
+}
+
+// importLaptops sends the laptops in a transactional import and returns the results,
+// like the client it stops sending when the server has ended the call
+func importLaptops(laptopClient pd.LaptopServiceClient, laptops []*pd.Laptop) ([]*pd.ImportLaptopsResponse, error) {
+	stream, err := laptopClient.ImportLaptops(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	requests := []*pd.ImportLaptopsRequest{{
+		Data: &pd.ImportLaptopsRequest_Options{Options: &pd.ImportOptions{Transactional: true}},
+	}}
+	for _, laptop := range laptops {
+		requests = append(requests, &pd.ImportLaptopsRequest{Data: &pd.ImportLaptopsRequest_Laptop{Laptop: laptop}})
+	}
+	for _, req := range requests {
+		if err := stream.Send(req); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	var results []*pd.ImportLaptopsResponse
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+}
+
+func TestAuthInterceptorRetriesClientStreamWithExpiredToken(t *testing.T) {
+	t.Parallel()
+
+	server := startTestServer(t, time.Minute, 0)
+	atomic.StoreInt32(&server.authService.expiredLogins, 1)
+	// a negative refreshBefore keeps the background refresh from replacing the expired token first
+	laptopClient, err := newTestLaptopClient(t, server.address, -time.Hour)
+	require.NoError(t, err)
+
+	laptops := []*pd.Laptop{sample.NewLaptop(), sample.NewLaptop(), sample.NewLaptop()}
+	results, err := importLaptops(laptopClient, laptops)
+	require.NoError(t, err)
+	require.Len(t, results, len(laptops))
+	for i, res := range results {
+		require.Equal(t, pd.ImportLaptopsResponse_OK, res.GetResult())
+		require.Equal(t, laptops[i].GetId(), res.GetId())
+	}
+
+	require.EqualValues(t, 2, atomic.LoadInt32(&server.authService.logins))
+	require.EqualValues(t, 2, atomic.LoadInt32(&server.streamCalls), "the rejected call should be replayed once")
+}
+
+func TestAuthInterceptorDoesNotReplayLargeClientStream(t *testing.T) {
+	t.Parallel()
+
+	server := startTestServer(t, time.Minute, 0)
+	atomic.StoreInt32(&server.authService.expiredLogins, 1)
+	laptopClient, err := newTestLaptopClient(t, server.address, -time.Hour)
+	require.NoError(t, err)
+
+	// the laptops are more than the messages kept for a replay
+	var laptops []*pd.Laptop
+	for size := 0; size <= 1<<20; {
+		laptop := sample.NewLaptop()
+		laptops = append(laptops, laptop)
+		size += proto.Size(laptop)
+	}
+
+	_, err = importLaptops(laptopClient, laptops)
+	require.Error(t, err)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.EqualValues(t, 1, atomic.LoadInt32(&server.streamCalls))
+}
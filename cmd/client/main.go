@@ -3,7 +3,10 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"github.com/google/uuid"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -11,6 +14,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"pc_book/client"
 	"pc_book/pd"
 	"pc_book/sample"
 	"strings"
@@ -44,6 +48,52 @@ func createLaptop(laptopClient pd.LaptopServiceClient, laptop *pd.Laptop) {
 	log.Printf("create laptop with id: %s", res.Id)
 }
 
+// importLaptops creates the laptops with one stream, the results are received while the laptops are sent
+func importLaptops(laptopClient pd.LaptopServiceClient, laptops []*pd.Laptop) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := laptopClient.ImportLaptops(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot import laptops: %v", err)
+	}
+
+	waitResponse := make(chan error)
+	go func() {
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				waitResponse <- nil
+				return
+			}
+			if err != nil {
+				waitResponse <- fmt.Errorf("cannot receive stream response: %v", err)
+				return
+			}
+
+			if res.GetResult() == pd.ImportLaptopsResponse_OK {
+				log.Printf("import laptop with id: %s", res.GetId())
+			} else {
+				log.Printf("cannot import laptop %d: %v %s", res.GetIndex(), res.GetResult(), res.GetError())
+			}
+		}
+	}()
+
+	for _, laptop := range laptops {
+		req := &pd.ImportLaptopsRequest{
+			Data: &pd.ImportLaptopsRequest_Laptop{Laptop: laptop},
+		}
+		if err = stream.Send(req); err != nil {
+			return fmt.Errorf("cannot send stream request: %v - %v", err, stream.RecvMsg(nil))
+		}
+	}
+	if err = stream.CloseSend(); err != nil {
+		return fmt.Errorf("cannot close send: %v", err)
+	}
+
+	return <-waitResponse
+}
+
 func searchLaptop(laptopClient pd.LaptopServiceClient, filter *pd.Filter)  {
 	log.Printf("search filter: %v", filter)
 
@@ -76,6 +126,9 @@ func searchLaptop(laptopClient pd.LaptopServiceClient, filter *pd.Filter)  {
 	}
 }
 
+// maxUploadAttempts is the number of times an image upload is tried, it is resumed after a failure
+const maxUploadAttempts = 3
+
 func uploadImage(laptopClient pd.LaptopServiceClient, laptopID string, imagePath string) {
 	file, err := os.Open(imagePath)
 	if err != nil {
@@ -83,26 +136,72 @@ func uploadImage(laptopClient pd.LaptopServiceClient, laptopID string, imagePath
 	}
 	defer file.Close()
 
+	// 先计算digest, 与服务器返回的digest比较
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		log.Fatal("cannot read image file: ", err)
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	// 上传失败后从服务器收到的offset继续上传
+	uploadID := uuid.New().String()
+	var offset int64
+	var res *pd.UploadImageResponse
+	for attempt := 1; ; attempt++ {
+		res, err = sendImage(laptopClient, laptopID, filepath.Ext(imagePath), uploadID, file, offset)
+		if err == nil {
+			break
+		}
+		if attempt == maxUploadAttempts {
+			log.Fatal("cannot upload image: ", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		query, queryErr := laptopClient.QueryUpload(ctx, &pd.QueryUploadRequest{UploadId: uploadID})
+		cancel()
+		if queryErr != nil {
+			log.Fatal("cannot upload image: ", err)
+		}
+		offset = int64(query.GetOffset())
+		log.Printf("upload failed: %v, resume from offset %d", err, offset)
+	}
+
+	if res.GetDigest() != digest {
+		log.Fatalf("image digest mismatch: server has %s, client sent %s", res.GetDigest(), digest)
+	}
+
+	log.Printf("image upload with id: %s, size: %d, digest: %s", res.GetId(), res.GetSize(), res.GetDigest())
+}
+
+// sendImage sends the data of the file from offset with a new stream
+func sendImage(laptopClient pd.LaptopServiceClient, laptopID string, imageType string, uploadID string, file *os.File, offset int64) (*pd.UploadImageResponse, error) {
+	_, err := file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read image file: %w", err)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	stream, err := laptopClient.UploadImage(ctx)
 	if err != nil {
-		log.Fatal("cannot upload image: ", err)
+		return nil, err
 	}
 
 	req := &pd.UploadImageRequest{
 		Data: &pd.UploadImageRequest_Info{
 			Info: &pd.ImageInfo{
 				LaptopId: laptopID,
-				ImageType: filepath.Ext(imagePath),
+				ImageType: imageType,
+				UploadId: uploadID,
 			},
 		},
 	}
 
 	err = stream.Send(req)
 	if err != nil {
-		log.Fatal("can not send image: ", err, stream.RecvMsg(nil))
+		return nil, sendError(stream, err)
 	}
 
 	reader := bufio.NewReader(file)
@@ -115,29 +214,32 @@ func uploadImage(laptopClient pd.LaptopServiceClient, laptopID string, imagePath
 		}
 
 		if err != nil {
-			log.Fatal("cannot read chunk to buffer: ", err)
+			return nil, fmt.Errorf("cannot read chunk to buffer: %w", err)
 		}
 
 		req := &pd.UploadImageRequest{
-			Data: &pd.UploadImageRequest_ChunkData{
-				ChunkData: buffer[:n],
+			Data: &pd.UploadImageRequest_Chunk{
+				Chunk: &pd.ImageChunk{Data: buffer[:n], Offset: uint64(offset)},
 			},
 		}
 
 		err = stream.Send(req)
 		if err != nil {
-			log.Fatal("can not send chunk to server: ", err)
+			return nil, sendError(stream, err)
 		}
+		offset += int64(n)
 	}
 
-	res, err := stream.CloseAndRecv()
-	if err != nil {
-		log.Fatal("cannot receive response: ", err)
-	}
-
-	log.Printf("image upload with id: %s, size: %d", res.GetId(), res.GetSize())
-
+	return stream.CloseAndRecv()
+}
 
+// sendError returns the error of the server that closed the stream, Send only returns io.EOF
+func sendError(stream pd.LaptopService_UploadImageClient, err error) error {
+	_, recvErr := stream.CloseAndRecv()
+	if recvErr != nil {
+		return recvErr
+	}
+	return err
 }
 
 func rateLaptop(laptopClient pd.LaptopServiceClient, laptopIDs []string, scores []float64) error {
@@ -198,8 +300,12 @@ func testCreateLaptop(laptopClient pd.LaptopServiceClient) {
 }
 
 func testSearchLaptop(laptopClient pd.LaptopServiceClient) {
-	for i := 0; i < 10; i++ {
-		createLaptop(laptopClient, sample.NewLaptop())
+	laptops := make([]*pd.Laptop, 10)
+	for i := range laptops {
+		laptops[i] = sample.NewLaptop()
+	}
+	if err := importLaptops(laptopClient, laptops); err != nil {
+		log.Fatal(err)
 	}
 
 	filter := &pd.Filter{
@@ -247,6 +353,12 @@ func testRateLaptop(laptopClient pd.LaptopServiceClient) {
 	}
 }
 
+const (
+	username        = "admin1"
+	password        = "secret"
+	refreshDuration = 30 * time.Second
+)
+
 func main() {
 	fmt.Println("grpc client")
 
@@ -255,15 +367,33 @@ func main() {
 	//flag.Parse()
 	fmt.Printf("dial server %s", serverAddress)
 
-	// 获取一个ClientConn对象
-	conn, err := grpc.Dial(serverAddress, grpc.WithInsecure())
+	// 用于登录的连接，不经过拦截器
+	cc1, err := grpc.Dial(serverAddress, grpc.WithInsecure())
+	if err != nil {
+		log.Fatal("can not dail server: ", err)
+	}
+
+	authClient := client.NewAuthClient(cc1, username, password)
+	interceptor, err := client.NewAuthInterceptor(authClient, refreshDuration)
+	if err != nil {
+		log.Fatal("cannot create auth interceptor: ", err)
+	}
+	defer interceptor.Close()
+
+	// 获取一个ClientConn对象, 每次调用都会带上access token
+	cc2, err := grpc.Dial(
+		serverAddress,
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(interceptor.Unary()),
+		grpc.WithStreamInterceptor(interceptor.Stream()),
+	)
 	if err != nil {
 		log.Fatal("can not dail server: ", err)
 	}
 
 	// 将ClientConn传递给pd中的Client Service
 	// 返回的是一个laptopClient
-	laptopClient := pd.NewLaptopServiceClient(conn)
+	laptopClient := pd.NewLaptopServiceClient(cc2)
 
 	testRateLaptop(laptopClient)
 }
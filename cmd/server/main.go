@@ -1,51 +1,229 @@
 package main
 
 import (
-	"context"
 	"flag"
 	"fmt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"log"
 	"net"
+	"os"
+	"path/filepath"
 	"pc_book/pd"
 	"pc_book/service"
+	"strings"
 	"time"
 )
 
-func unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	log.Println("--> unary interceptor: ", info.FullMethod)
-	return handler(ctx, req)
-}
-
-func streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-	log.Println("--> stream interceptor: ", info.FullMethod)
-	return handler(srv, stream)
-}
-
 const (
 	secretKey = "secret"
 	tokenDuration = 15*time.Minute
+	refreshTokenDuration = 24*time.Hour
 )
 
+// accessibleRoles returns the roles that are allowed to call each protected RPC
+func accessibleRoles() map[string][]string {
+	const laptopServicePath = "/LaptopService/"
+	const authServicePath = "/AuthService/"
+
+	return map[string][]string{
+		laptopServicePath + "CreateLaptop":  {service.RoleAdmin},
+		laptopServicePath + "ImportLaptops": {service.RoleAdmin},
+		laptopServicePath + "UpdateLaptop":  {service.RoleAdmin},
+		laptopServicePath + "DeleteLaptop":  {service.RoleAdmin},
+		laptopServicePath + "UploadImage":   {service.RoleAdmin},
+		laptopServicePath + "QueryUpload":   {service.RoleAdmin},
+		laptopServicePath + "DeleteImage":   {service.RoleAdmin},
+		laptopServicePath + "RateLaptop":    {service.RoleAdmin, service.RoleUser},
+
+		authServicePath + "CreateUser":     {service.RoleAdmin},
+		authServicePath + "DeleteUser":     {service.RoleAdmin},
+		authServicePath + "ChangePassword": {service.RoleAdmin},
+		authServicePath + "ListUsers":      {service.RoleAdmin},
+	}
+}
+
+func seedUsers(userStore service.UserStore, filename string) error {
+	users, err := service.LoadUserSeedFile(filename)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("seed %d users from %s", len(users), filename)
+	return service.SeedUsers(userStore, users)
+}
+
+// newJWTManager signs tokens with the first PEM key file, or with secretKey if there is none.
+// The key ID of each file is its name without extension.
+func newJWTManager(keyFiles string) (*service.JWTManager, error) {
+	if keyFiles == "" {
+		return service.NewJWTManager(secretKey, tokenDuration, refreshTokenDuration), nil
+	}
+
+	var keys []*service.SigningKey
+	for _, filename := range strings.Split(keyFiles, ",") {
+		id := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+		key, err := service.LoadSigningKeyFromPEM(id, filename)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("load jwt key %s (%s)", id, key.Method.Alg())
+		keys = append(keys, key)
+	}
+	return service.NewJWTManagerWithKeys(keys, tokenDuration, refreshTokenDuration)
+}
+
+func newLaptopStore(dbFile string, dataDir string) (service.LaptopStore, error) {
+	if dbFile != "" {
+		log.Printf("store laptops in database %s", dbFile)
+		return service.OpenSQLiteLaptopStore(dbFile)
+	}
+	if dataDir == "" {
+		return service.NewInMemoryLaptopStore(), nil
+	}
+
+	log.Printf("persist laptops in %s", dataDir)
+	return service.NewFileLaptopStore(dataDir)
+}
+
+// imageReconciler is an image store that can check its images, see DiskImageStore.Reconcile
+type imageReconciler interface {
+	Reconcile(laptopExists func(laptopID string) (bool, error), clean bool) (*service.ImageReport, error)
+}
+
+// newImageStore loads the images of the folder and reports the files and images that don't match
+func newImageStore(imageDir string, laptopStore service.LaptopStore, checkLaptops bool, clean bool) (*service.DiskImageStore, error) {
+	imageStore, err := service.NewDiskImageStore(imageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = reconcileImages("image folder "+imageDir, imageStore, laptopStore, checkLaptops, clean)
+	if err != nil {
+		return nil, err
+	}
+	return imageStore, nil
+}
+
+// newS3ImageStore opens the bucket and reports the objects and images that don't match
+func newS3ImageStore(config service.S3Config, laptopStore service.LaptopStore, checkLaptops bool, clean bool) (*service.S3ImageStore, error) {
+	imageStore, err := service.NewS3ImageStore(config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = reconcileImages("image bucket "+config.Bucket, imageStore, laptopStore, checkLaptops, clean)
+	if err != nil {
+		return nil, err
+	}
+	return imageStore, nil
+}
+
+// reconcileImages logs the problems found in the images.
+// The laptops are only checked if they are persistent, they are all gone after a restart otherwise.
+func reconcileImages(name string, imageStore imageReconciler, laptopStore service.LaptopStore, checkLaptops bool, clean bool) error {
+	var laptopExists func(laptopID string) (bool, error)
+	if checkLaptops {
+		laptopExists = func(laptopID string) (bool, error) {
+			laptop, err := laptopStore.Find(laptopID)
+			return laptop != nil, err
+		}
+	}
+
+	report, err := imageStore.Reconcile(laptopExists, clean)
+	if err != nil {
+		return err
+	}
+	if !report.Empty() {
+		log.Printf("%s: %d orphan files %v, %d broken images %v, %d images of deleted laptops %v, cleaned: %v",
+			name, len(report.OrphanFiles), report.OrphanFiles, len(report.BrokenImages), report.BrokenImages,
+			len(report.OrphanImages), report.OrphanImages, report.Cleaned)
+	}
+	return nil
+}
+
+// expireUploads removes the uploads that are not resumed within ttl, it is checked every minute or every ttl if it is shorter
+func expireUploads(imageStore service.ResumableImageStore, ttl time.Duration) {
+	interval := time.Minute
+	if ttl < interval {
+		interval = ttl
+	}
+
+	for range time.Tick(interval) {
+		count, err := imageStore.ExpireUploads(time.Now().Add(-ttl))
+		if err != nil {
+			log.Print("cannot expire uploads: ", err)
+		}
+		if count > 0 {
+			log.Printf("expired %d uploads", count)
+		}
+	}
+}
+
 func main() {
 	fmt.Println("grpc server")
 
 	port := flag.Int("port", 0, "the server port")
+	usersFile := flag.String("users", "", "the JSON file with the users to create at startup")
+	dataDir := flag.String("data-dir", "", "the folder to persist laptops in, they are kept in memory only if empty")
+	dbFile := flag.String("db", "", "the SQLite database file to store laptops in, takes precedence over -data-dir")
+	imageDir := flag.String("image-dir", "img", "the folder to store laptop images in")
+	cleanImages := flag.Bool("clean-images", false, "remove the orphan files and images found in the image folder or bucket at startup")
+	uploadTTL := flag.Duration("upload-ttl", 24*time.Hour, "the time after which an upload that is not resumed is removed")
+	s3Endpoint := flag.String("s3-endpoint", "", "the URL of an S3-compatible service to store laptop images in instead of -image-dir, "+
+		"the credentials are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	s3Bucket := flag.String("s3-bucket", "laptop-images", "the bucket of the laptop images of -s3-endpoint")
+	s3Region := flag.String("s3-region", "us-east-1", "the region of -s3-endpoint")
+	jwtKeys := flag.String("jwt-keys", "", "comma separated PEM key files to sign and verify tokens, the first one signs")
 	flag.Parse()
 	log.Printf("start server on port: %d", *port)
 
 	userStore := service.NewInMemoryUserStore()
-	jwtManager := service.NewJWTManager(secretKey, tokenDuration)
+	if *usersFile != "" {
+		err := seedUsers(userStore, *usersFile)
+		if err != nil {
+			log.Fatal("cannot seed users: ", err)
+		}
+	}
+	jwtManager, err := newJWTManager(*jwtKeys)
+	if err != nil {
+		log.Fatal("cannot create jwt manager: ", err)
+	}
 	authServer := service.NewAuthService(userStore, jwtManager)
 
-	laptopStore := service.NewInMemoryLaptopStore()
-	imageStore := service.NewDiskImageStore("img")
+	laptopStore, err := newLaptopStore(*dbFile, *dataDir)
+	if err != nil {
+		log.Fatal("cannot open laptop store: ", err)
+	}
+	var imageStore service.ImageStore
+	if *s3Endpoint != "" {
+		log.Printf("store images in bucket %s of %s", *s3Bucket, *s3Endpoint)
+		imageStore, err = newS3ImageStore(service.S3Config{
+			Endpoint: *s3Endpoint,
+			Bucket: *s3Bucket,
+			Region: *s3Region,
+			AccessKeyID: os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		}, laptopStore, *dbFile != "" || *dataDir != "", *cleanImages)
+		if err != nil {
+			log.Fatal("cannot open image store: ", err)
+		}
+	} else {
+		imageStore, err = newImageStore(*imageDir, laptopStore, *dbFile != "" || *dataDir != "", *cleanImages)
+		if err != nil {
+			log.Fatal("cannot open image store: ", err)
+		}
+	}
+	if resumable, ok := imageStore.(service.ResumableImageStore); ok && *uploadTTL > 0 {
+		go expireUploads(resumable, *uploadTTL)
+	}
 	ratingStore := service.NewInMemoryRatingStore()
 
+	interceptor := service.NewAuthInterceptor(jwtManager, accessibleRoles())
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(unaryInterceptor),
-		grpc.StreamInterceptor(streamInterceptor),
+		grpc.UnaryInterceptor(interceptor.Unary()),
+		grpc.StreamInterceptor(interceptor.Stream()),
 		)
 
 	laptopServer := service.NewLaptopService(laptopStore, imageStore, ratingStore)
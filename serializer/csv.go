@@ -0,0 +1,337 @@
+package serializer
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+	"strconv"
+)
+
+// jsonOptions writes the messages of the JSON columns with the field names of the proto files
+var jsonOptions = protojson.MarshalOptions{UseProtoNames: true}
+
+// csvColumn is a field of a message or of one of its nested messages
+type csvColumn struct {
+	// name is the path of the field, like cpu.min_ghz
+	name string
+	path []protoreflect.FieldDescriptor
+}
+
+// csvColumns returns a column for each field of the message, nested messages are flattened into their fields.
+// Repeated fields, maps and recursive messages are a single column holding JSON.
+func csvColumns(desc protoreflect.MessageDescriptor) []csvColumn {
+	var columns []csvColumn
+	var add func(desc protoreflect.MessageDescriptor, prefix string, path []protoreflect.FieldDescriptor, parents map[protoreflect.FullName]bool)
+	add = func(desc protoreflect.MessageDescriptor, prefix string, path []protoreflect.FieldDescriptor, parents map[protoreflect.FullName]bool) {
+		parents[desc.FullName()] = true
+		defer delete(parents, desc.FullName())
+
+		fields := desc.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			name := prefix + string(field.Name())
+			fieldPath := append(append([]protoreflect.FieldDescriptor(nil), path...), field)
+
+			if field.Message() != nil && !field.IsList() && !field.IsMap() && !parents[field.Message().FullName()] {
+				add(field.Message(), name+".", fieldPath, parents)
+				continue
+			}
+			columns = append(columns, csvColumn{name: name, path: fieldPath})
+		}
+	}
+
+	add(desc, "", nil, make(map[protoreflect.FullName]bool))
+	return columns
+}
+
+// CSVWriter writes messages as CSV rows, with a header of the flattened field names
+type CSVWriter struct {
+	writer      *csv.Writer
+	columns     []csvColumn
+	wroteHeader bool
+}
+
+// NewCSVWriter returns a writer of CSV to w for messages of the same type as message
+func NewCSVWriter(w io.Writer, message proto.Message) *CSVWriter {
+	return &CSVWriter{
+		writer:  csv.NewWriter(w),
+		columns: csvColumns(proto.MessageReflect(message).Descriptor()),
+	}
+}
+
+// Write writes a message as one row, the header is written before the first row
+func (w *CSVWriter) Write(message proto.Message) error {
+	if !w.wroteHeader {
+		err := w.writeHeader()
+		if err != nil {
+			return err
+		}
+	}
+
+	m := proto.MessageReflect(message)
+	row := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		cell, err := csvCell(m, column.path)
+		if err != nil {
+			return fmt.Errorf("cannot write column %s: %w", column.name, err)
+		}
+		row[i] = cell
+	}
+	return w.writer.Write(row)
+}
+
+// Flush writes the header if no row was written, then the buffered rows
+func (w *CSVWriter) Flush() error {
+	if !w.wroteHeader {
+		err := w.writeHeader()
+		if err != nil {
+			return err
+		}
+	}
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *CSVWriter) writeHeader() error {
+	header := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		header[i] = column.name
+	}
+
+	w.wroteHeader = true
+	return w.writer.Write(header)
+}
+
+// csvCell returns the value of the field at path, or an empty string if it is not set.
+// A field without presence in a message that is set is always written, so that the message is set again when read.
+func csvCell(m protoreflect.Message, path []protoreflect.FieldDescriptor) (string, error) {
+	for _, field := range path[:len(path)-1] {
+		if !m.Has(field) {
+			return "", nil
+		}
+		m = m.Get(field).Message()
+	}
+
+	field := path[len(path)-1]
+	if field.HasPresence() && !m.Has(field) {
+		return "", nil
+	}
+	value := m.Get(field)
+
+	switch {
+	case field.IsList():
+		list := value.List()
+		items := make([]interface{}, list.Len())
+		for i := range items {
+			item, err := jsonValue(field, list.Get(i))
+			if err != nil {
+				return "", err
+			}
+			items[i] = item
+		}
+		data, err := json.Marshal(items)
+		return string(data), err
+	case field.IsMap():
+		return "", fmt.Errorf("map fields are not supported")
+	case field.Message() != nil:
+		data, err := jsonOptions.Marshal(value.Message().Interface())
+		return string(data), err
+	default:
+		return formatScalar(field, value), nil
+	}
+}
+
+// jsonValue returns a list item as JSON: a message in its JSON mapping, a scalar as the string of its cell
+func jsonValue(field protoreflect.FieldDescriptor, value protoreflect.Value) (interface{}, error) {
+	if field.Message() != nil {
+		data, err := jsonOptions.Marshal(value.Message().Interface())
+		return json.RawMessage(data), err
+	}
+	return formatScalar(field, value), nil
+}
+
+func formatScalar(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return strconv.Itoa(int(value.Enum()))
+	case protoreflect.FloatKind:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 32)
+	case protoreflect.DoubleKind:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64)
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(value.Bytes())
+	default:
+		// bool, string and integers
+		return value.String()
+	}
+}
+
+// CSVReader reads messages written by CSVWriter, the columns are found by the names of the header
+type CSVReader struct {
+	reader  *csv.Reader
+	desc    protoreflect.MessageDescriptor
+	columns []*csvColumn
+	line    int
+}
+
+// NewCSVReader returns a reader of CSV from r for messages of the same type as message
+func NewCSVReader(r io.Reader, message proto.Message) *CSVReader {
+	return &CSVReader{
+		reader: csv.NewReader(r),
+		desc:   proto.MessageReflect(message).Descriptor(),
+	}
+}
+
+// Read reads the message of the next row
+func (r *CSVReader) Read(message proto.Message) error {
+	if r.columns == nil {
+		err := r.readHeader()
+		if err != nil {
+			return err
+		}
+	}
+
+	row, err := r.reader.Read()
+	if err != nil {
+		return err
+	}
+	r.line++
+
+	message.Reset()
+	m := proto.MessageReflect(message)
+	for i, cell := range row {
+		if cell == "" {
+			continue
+		}
+
+		column := r.columns[i]
+		err := setCell(m, column.path, cell)
+		if err != nil {
+			return fmt.Errorf("cannot read column %s of row %d: %w", column.name, r.line, err)
+		}
+	}
+	return nil
+}
+
+func (r *CSVReader) readHeader() error {
+	header, err := r.reader.Read()
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]csvColumn)
+	for _, column := range csvColumns(r.desc) {
+		byName[column.name] = column
+	}
+
+	r.columns = make([]*csvColumn, len(header))
+	for i, name := range header {
+		column, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("unknown column %s", name)
+		}
+		r.columns[i] = &column
+	}
+	return nil
+}
+
+// setCell sets the field at path to the value of a cell, creating the nested messages
+func setCell(m protoreflect.Message, path []protoreflect.FieldDescriptor, cell string) error {
+	for _, field := range path[:len(path)-1] {
+		m = m.Mutable(field).Message()
+	}
+
+	field := path[len(path)-1]
+	switch {
+	case field.IsList():
+		var items []json.RawMessage
+		err := json.Unmarshal([]byte(cell), &items)
+		if err != nil {
+			return err
+		}
+
+		list := m.Mutable(field).List()
+		for _, item := range items {
+			value, err := parseJSONValue(field, list.NewElement(), item)
+			if err != nil {
+				return err
+			}
+			list.Append(value)
+		}
+		return nil
+	case field.IsMap():
+		return fmt.Errorf("map fields are not supported")
+	case field.Message() != nil:
+		return protojson.Unmarshal([]byte(cell), m.Mutable(field).Message().Interface())
+	default:
+		value, err := parseScalar(field, cell)
+		if err != nil {
+			return err
+		}
+		m.Set(field, value)
+		return nil
+	}
+}
+
+// parseJSONValue parses a list item written by jsonValue, element is a new element of the list
+func parseJSONValue(field protoreflect.FieldDescriptor, element protoreflect.Value, data json.RawMessage) (protoreflect.Value, error) {
+	if field.Message() != nil {
+		err := protojson.Unmarshal(data, element.Message().Interface())
+		return element, err
+	}
+
+	var cell string
+	err := json.Unmarshal(data, &cell)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	return parseScalar(field, cell)
+}
+
+func parseScalar(field protoreflect.FieldDescriptor, cell string) (protoreflect.Value, error) {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(cell)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByName(protoreflect.Name(cell)); enumValue != nil {
+			return protoreflect.ValueOfEnum(enumValue.Number()), nil
+		}
+		v, err := strconv.ParseInt(cell, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(cell, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(cell, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(cell, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(cell, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(cell, 32)
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(cell, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(cell), nil
+	case protoreflect.BytesKind:
+		v, err := base64.StdEncoding.DecodeString(cell)
+		return protoreflect.ValueOfBytes(v), err
+	default:
+		return protoreflect.Value{}, fmt.Errorf("unsupported field kind %v", field.Kind())
+	}
+}
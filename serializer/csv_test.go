@@ -0,0 +1,105 @@
+package serializer_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+	"io"
+	"pc_book/pd"
+	"pc_book/sample"
+	"pc_book/serializer"
+	"strings"
+	"testing"
+)
+
+// readCSVRows returns the cells of each row by the name of their column
+func readCSVRows(t *testing.T, data []byte) []map[string]string {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+
+	header := records[0]
+	var rows []map[string]string
+	for _, record := range records[1:] {
+		row := make(map[string]string)
+		for i, cell := range record {
+			row[header[i]] = cell
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestCSVColumns(t *testing.T) {
+	t.Parallel()
+
+	laptops := testLaptops()
+	// the oneof is set with its zero value
+	laptops[0].Weight = &pd.Laptop_WeightKg{WeightKg: 0}
+
+	var buffer bytes.Buffer
+	writer := serializer.NewCSVWriter(&buffer, &pd.Laptop{})
+	for _, laptop := range laptops {
+		require.NoError(t, writer.Write(laptop))
+	}
+	require.NoError(t, writer.Flush())
+
+	rows := readCSVRows(t, buffer.Bytes())
+	require.Len(t, rows, len(laptops))
+
+	// a set oneof field is written even with its zero value, the other field of the oneof is empty
+	require.Equal(t, "0", rows[0]["weight_kg"])
+	require.Equal(t, "", rows[0]["weight_lb"])
+	require.Equal(t, "", rows[1]["weight_kg"])
+	require.Equal(t, "4.5", rows[1]["weight_lb"])
+	require.Equal(t, "", rows[2]["weight_kg"])
+	require.Equal(t, "", rows[2]["weight_lb"])
+
+	// the fields of an unset message are empty, those of a set message are written even with their zero values
+	require.Equal(t, "", rows[1]["keyboard.layout"])
+	require.Equal(t, "", rows[1]["keyboard.backlit"])
+	require.Equal(t, "", rows[1]["updated_at.seconds"])
+	require.Equal(t, "UNKNOWN", rows[2]["keyboard.layout"])
+	require.Equal(t, "false", rows[2]["keyboard.backlit"])
+
+	// the nested messages are flattened, the repeated fields hold JSON
+	require.Equal(t, laptops[0].GetCpu().GetBrand(), rows[0]["cpu.brand"])
+	require.True(t, strings.HasPrefix(rows[0]["gpus"], "[{"))
+	require.Equal(t, "[]", rows[2]["gpus"])
+
+	// the presence is kept when the rows are read again
+	reader := serializer.NewCSVReader(bytes.NewReader(buffer.Bytes()), &pd.Laptop{})
+	for _, laptop := range laptops {
+		other := &pd.Laptop{}
+		require.NoError(t, reader.Read(other))
+		require.True(t, proto.Equal(laptop, other), "expected %v, got %v", laptop, other)
+	}
+	require.Equal(t, io.EOF, reader.Read(&pd.Laptop{}))
+}
+
+func TestCSVEmpty(t *testing.T) {
+	t.Parallel()
+
+	// an empty export has the header only
+	var buffer bytes.Buffer
+	writer := serializer.NewCSVWriter(&buffer, &pd.Laptop{})
+	require.NoError(t, writer.Flush())
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	require.Len(t, lines, 1)
+	require.True(t, strings.HasPrefix(lines[0], "id,brand,name,cpu.brand,"))
+
+	reader := serializer.NewCSVReader(&buffer, &pd.Laptop{})
+	require.Equal(t, io.EOF, reader.Read(&pd.Laptop{}))
+}
+
+func TestCSVReaderUnknownColumn(t *testing.T) {
+	t.Parallel()
+
+	data := "id,brand,colour\n" + sample.NewLaptop().GetId() + ",Apple,silver\n"
+	reader := serializer.NewCSVReader(strings.NewReader(data), &pd.Laptop{})
+	err := reader.Read(&pd.Laptop{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown column colour")
+}
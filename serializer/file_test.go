@@ -3,6 +3,7 @@ package serializer_test
 import (
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/require"
+	"path/filepath"
 	"pc_book/pd"
 	"pc_book/sample"
 	"pc_book/serializer"
@@ -12,8 +13,9 @@ import (
 func TestFileSerializer(t *testing.T) {
 	t.Parallel()
 
-	binaryFile := "../tmp/laptop.bin"
-	jsonFile := "../tmp/laptop.json"
+	dir := t.TempDir()
+	binaryFile := filepath.Join(dir, "laptop.bin")
+	jsonFile := filepath.Join(dir, "laptop.json")
 
 	laptop1 := sample.NewLaptop()
 
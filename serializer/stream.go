@@ -0,0 +1,156 @@
+package serializer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"io"
+)
+
+// maxDelimitedSize protects against allocating a huge buffer for a corrupted length
+const maxDelimitedSize = 64 << 20
+
+// MessageWriter writes a stream of protocol buffer messages
+type MessageWriter interface {
+	Write(message proto.Message) error
+	// Flush writes the buffered data to the underlying writer
+	Flush() error
+}
+
+// MessageReader reads a stream of protocol buffer messages, Read returns io.EOF after the last one
+type MessageReader interface {
+	Read(message proto.Message) error
+}
+
+// JSONLinesWriter writes each message as JSON on its own line
+type JSONLinesWriter struct {
+	writer    *bufio.Writer
+	marshaler jsonpb.Marshaler
+}
+
+// NewJSONLinesWriter returns a writer of JSON Lines to w
+func NewJSONLinesWriter(w io.Writer) *JSONLinesWriter {
+	return &JSONLinesWriter{
+		writer:    bufio.NewWriter(w),
+		marshaler: jsonpb.Marshaler{OrigName: true},
+	}
+}
+
+// Write writes a message followed by a newline
+func (w *JSONLinesWriter) Write(message proto.Message) error {
+	err := w.marshaler.Marshal(w.writer, message)
+	if err != nil {
+		return fmt.Errorf("cannot marshal proto message to JSON: %w", err)
+	}
+	return w.writer.WriteByte('\n')
+}
+
+// Flush writes the buffered lines
+func (w *JSONLinesWriter) Flush() error {
+	return w.writer.Flush()
+}
+
+// JSONLinesReader reads messages written by JSONLinesWriter, empty lines are skipped
+type JSONLinesReader struct {
+	reader *bufio.Reader
+	line   int
+}
+
+// NewJSONLinesReader returns a reader of JSON Lines from r
+func NewJSONLinesReader(r io.Reader) *JSONLinesReader {
+	return &JSONLinesReader{reader: bufio.NewReader(r)}
+}
+
+// Read reads the message of the next line
+func (r *JSONLinesReader) Read(message proto.Message) error {
+	for {
+		data, err := r.reader.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(data) == 0) {
+			return err
+		}
+		r.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		err = jsonpb.Unmarshal(bytes.NewReader(data), message)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal JSON of line %d: %w", r.line, err)
+		}
+		return nil
+	}
+}
+
+// DelimitedWriter writes each message in binary, prefixed with its size as a uvarint
+type DelimitedWriter struct {
+	writer *bufio.Writer
+}
+
+// NewDelimitedWriter returns a writer of length-delimited messages to w
+func NewDelimitedWriter(w io.Writer) *DelimitedWriter {
+	return &DelimitedWriter{writer: bufio.NewWriter(w)}
+}
+
+// Write writes the size and then the binary data of a message
+func (w *DelimitedWriter) Write(message proto.Message) error {
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("cannot marshal proto message to binary: %w", err)
+	}
+
+	size := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(size, uint64(len(data)))
+	_, err = w.writer.Write(size[:n])
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.Write(data)
+	return err
+}
+
+// Flush writes the buffered messages
+func (w *DelimitedWriter) Flush() error {
+	return w.writer.Flush()
+}
+
+// DelimitedReader reads messages written by DelimitedWriter
+type DelimitedReader struct {
+	reader *bufio.Reader
+}
+
+// NewDelimitedReader returns a reader of length-delimited messages from r
+func NewDelimitedReader(r io.Reader) *DelimitedReader {
+	return &DelimitedReader{reader: bufio.NewReader(r)}
+}
+
+// Read reads the next message, it returns io.ErrUnexpectedEOF if the stream ends inside a message
+func (r *DelimitedReader) Read(message proto.Message) error {
+	size, err := binary.ReadUvarint(r.reader)
+	if err != nil {
+		return err
+	}
+	if size > maxDelimitedSize {
+		return fmt.Errorf("invalid message size %d", size)
+	}
+
+	data := make([]byte, size)
+	_, err = io.ReadFull(r.reader, data)
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+
+	err = proto.Unmarshal(data, message)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal binary to proto message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package serializer_test
+
+import (
+	"bytes"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+	"io"
+	"pc_book/pd"
+	"pc_book/sample"
+	"pc_book/serializer"
+	"testing"
+)
+
+// testLaptops returns laptops with the optional fields set in different ways
+func testLaptops() []*pd.Laptop {
+	laptop1 := sample.NewLaptop()
+
+	// weight in pounds, without keyboard nor update time
+	laptop2 := sample.NewLaptop()
+	laptop2.Weight = &pd.Laptop_WeightLb{WeightLb: 4.5}
+	laptop2.Keyboard = nil
+	laptop2.UpdatedAt = nil
+
+	// set fields with zero values, and no weight
+	laptop3 := sample.NewLaptop()
+	laptop3.Weight = nil
+	laptop3.Keyboard = &pd.Keyboard{}
+	laptop3.Gpus = nil
+
+	return []*pd.Laptop{laptop1, laptop2, laptop3}
+}
+
+func TestMessageStreams(t *testing.T) {
+	t.Parallel()
+
+	formats := []struct {
+		name      string
+		newWriter func(w io.Writer) serializer.MessageWriter
+		newReader func(r io.Reader) serializer.MessageReader
+	}{
+		{
+			name:      "json lines",
+			newWriter: func(w io.Writer) serializer.MessageWriter { return serializer.NewJSONLinesWriter(w) },
+			newReader: func(r io.Reader) serializer.MessageReader { return serializer.NewJSONLinesReader(r) },
+		},
+		{
+			name:      "delimited",
+			newWriter: func(w io.Writer) serializer.MessageWriter { return serializer.NewDelimitedWriter(w) },
+			newReader: func(r io.Reader) serializer.MessageReader { return serializer.NewDelimitedReader(r) },
+		},
+		{
+			name:      "csv",
+			newWriter: func(w io.Writer) serializer.MessageWriter { return serializer.NewCSVWriter(w, &pd.Laptop{}) },
+			newReader: func(r io.Reader) serializer.MessageReader { return serializer.NewCSVReader(r, &pd.Laptop{}) },
+		},
+	}
+
+	for _, format := range formats {
+		format := format
+		t.Run(format.name, func(t *testing.T) {
+			t.Parallel()
+
+			laptops := testLaptops()
+			var buffer bytes.Buffer
+			writer := format.newWriter(&buffer)
+			for _, laptop := range laptops {
+				require.NoError(t, writer.Write(laptop))
+			}
+			require.NoError(t, writer.Flush())
+
+			reader := format.newReader(&buffer)
+			for _, laptop := range laptops {
+				other := &pd.Laptop{}
+				require.NoError(t, reader.Read(other))
+				require.True(t, proto.Equal(laptop, other), "expected %v, got %v", laptop, other)
+			}
+			require.Equal(t, io.EOF, reader.Read(&pd.Laptop{}))
+		})
+	}
+}
+
+func TestDelimitedReaderTruncated(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	writer := serializer.NewDelimitedWriter(&buffer)
+	require.NoError(t, writer.Write(sample.NewLaptop()))
+	require.NoError(t, writer.Flush())
+
+	data := buffer.Bytes()
+	// a laptop is longer than 127 bytes, its size takes 2 bytes
+	require.Greater(t, len(data), 130)
+
+	// the stream ends inside the message, then inside its size
+	for _, size := range []int{len(data) - 1, 1} {
+		reader := serializer.NewDelimitedReader(bytes.NewReader(data[:size]))
+		require.Equal(t, io.ErrUnexpectedEOF, reader.Read(&pd.Laptop{}))
+	}
+
+	reader := serializer.NewDelimitedReader(bytes.NewReader(nil))
+	require.Equal(t, io.EOF, reader.Read(&pd.Laptop{}))
+}
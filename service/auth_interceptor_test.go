@@ -0,0 +1,243 @@
+package service_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"net"
+	"pc_book/pd"
+	"pc_book/sample"
+	"pc_book/service"
+	"testing"
+	"time"
+)
+
+const (
+	testSecretKey     = "test-secret"
+	testTokenDuration = time.Minute
+
+	testRefreshTokenDuration = time.Hour
+)
+
+func testAccessibleRoles() map[string][]string {
+	return map[string][]string{
+		"/LaptopService/CreateLaptop": {"admin"},
+		"/LaptopService/SearchLaptop": {"admin", "user"},
+		"/LaptopService/UploadImage":  {"admin"},
+		"/LaptopService/RateLaptop":   {"admin", "user"},
+	}
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	t.Parallel()
+
+	jwtManager := service.NewJWTManager(testSecretKey, testTokenDuration, testRefreshTokenDuration)
+	otherManager := service.NewJWTManager("other-secret", testTokenDuration, testRefreshTokenDuration)
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore, err := service.NewDiskImageStore(t.TempDir())
+	require.NoError(t, err)
+	ratingStore := service.NewInMemoryRatingStore()
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	serverAddress := startTestAuthLaptopServer(t, jwtManager, laptopStore, imageStore, ratingStore)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	adminToken := generateTestToken(t, jwtManager, "admin")
+	userToken := generateTestToken(t, jwtManager, "user")
+	guestToken := generateTestToken(t, jwtManager, "guest")
+	forgedToken := generateTestToken(t, otherManager, "admin")
+
+	testCases := []struct {
+		name       string
+		token      string
+		createCode codes.Code
+		searchCode codes.Code
+		uploadCode codes.Code
+		rateCode   codes.Code
+	}{
+		{
+			name:       "no_token",
+			token:      "",
+			createCode: codes.Unauthenticated,
+			searchCode: codes.Unauthenticated,
+			uploadCode: codes.Unauthenticated,
+			rateCode:   codes.Unauthenticated,
+		},
+		{
+			name:       "invalid_token",
+			token:      "invalid-token",
+			createCode: codes.Unauthenticated,
+			searchCode: codes.Unauthenticated,
+			uploadCode: codes.Unauthenticated,
+			rateCode:   codes.Unauthenticated,
+		},
+		{
+			name:       "forged_token",
+			token:      forgedToken,
+			createCode: codes.Unauthenticated,
+			searchCode: codes.Unauthenticated,
+			uploadCode: codes.Unauthenticated,
+			rateCode:   codes.Unauthenticated,
+		},
+		{
+			name:       "guest",
+			token:      guestToken,
+			createCode: codes.PermissionDenied,
+			searchCode: codes.PermissionDenied,
+			uploadCode: codes.PermissionDenied,
+			rateCode:   codes.PermissionDenied,
+		},
+		{
+			name:       "user",
+			token:      userToken,
+			createCode: codes.PermissionDenied,
+			searchCode: codes.OK,
+			uploadCode: codes.PermissionDenied,
+			rateCode:   codes.OK,
+		},
+		{
+			name:       "admin",
+			token:      adminToken,
+			createCode: codes.OK,
+			searchCode: codes.OK,
+			uploadCode: codes.OK,
+			rateCode:   codes.OK,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if tc.token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tc.token)
+			}
+
+			// unary
+			_, err := laptopClient.CreateLaptop(ctx, &pd.CreateLaptopRequest{Laptop: sample.NewLaptop()})
+			requireCode(t, tc.createCode, err)
+
+			// server streaming
+			searchStream, err := laptopClient.SearchLaptop(ctx, &pd.SearchLaptopRequest{Filter: &pd.Filter{MaxPriceUsd: 5000}})
+			require.NoError(t, err)
+			for {
+				_, err = searchStream.Recv()
+				if err != nil {
+					break
+				}
+			}
+			if err == io.EOF {
+				err = nil
+			}
+			requireCode(t, tc.searchCode, err)
+
+			// client streaming
+			uploadStream, err := laptopClient.UploadImage(ctx)
+			require.NoError(t, err)
+			_ = uploadStream.Send(&pd.UploadImageRequest{
+				Data: &pd.UploadImageRequest_Info{
+					Info: &pd.ImageInfo{LaptopId: laptop.GetId(), ImageType: ".png"},
+				},
+			})
+			_ = uploadStream.Send(&pd.UploadImageRequest{
+				Data: &pd.UploadImageRequest_ChunkData{ChunkData: []byte("\x89PNG\r\n\x1a\nimage")},
+			})
+			_, err = uploadStream.CloseAndRecv()
+			requireCode(t, tc.uploadCode, err)
+
+			// bidirectional streaming
+			rateStream, err := laptopClient.RateLaptop(ctx)
+			require.NoError(t, err)
+			_ = rateStream.Send(&pd.RateLaptopRequest{LaptopId: laptop.GetId(), Score: 8})
+			require.NoError(t, rateStream.CloseSend())
+			for {
+				_, err = rateStream.Recv()
+				if err != nil {
+					break
+				}
+			}
+			if err == io.EOF {
+				err = nil
+			}
+			requireCode(t, tc.rateCode, err)
+		})
+	}
+}
+
+func TestAuthInterceptorPublicMethod(t *testing.T) {
+	t.Parallel()
+
+	jwtManager := service.NewJWTManager(testSecretKey, testTokenDuration, testRefreshTokenDuration)
+	interceptor := service.NewAuthInterceptor(jwtManager, map[string][]string{})
+
+	laptopServer := service.NewLaptopService(service.NewInMemoryLaptopStore(), nil, nil)
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(interceptor.Unary()),
+		grpc.StreamInterceptor(interceptor.Stream()),
+	)
+	pd.RegisterLaptopServiceServer(grpcServer, laptopServer)
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	laptopClient := newTestLaptopClient(t, listener.Addr().String())
+	_, err = laptopClient.CreateLaptop(context.Background(), &pd.CreateLaptopRequest{Laptop: sample.NewLaptop()})
+	require.NoError(t, err)
+}
+
+func startTestAuthLaptopServer(
+	t *testing.T,
+	jwtManager *service.JWTManager,
+	laptopStore service.LaptopStore,
+	imageStore service.ImageStore,
+	ratingStore service.RatingStore,
+) string {
+	interceptor := service.NewAuthInterceptor(jwtManager, testAccessibleRoles())
+	laptopServer := service.NewLaptopService(laptopStore, imageStore, ratingStore)
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(interceptor.Unary()),
+		grpc.StreamInterceptor(interceptor.Stream()),
+	)
+	pd.RegisterLaptopServiceServer(grpcServer, laptopServer)
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().String()
+}
+
+func generateTestToken(t *testing.T, jwtManager *service.JWTManager, role string) string {
+	user, err := service.NewUser(role+"1", "secret", role)
+	require.NoError(t, err)
+
+	token, err := jwtManager.Generate(user)
+	require.NoError(t, err)
+	return token
+}
+
+func requireCode(t *testing.T, code codes.Code, err error) {
+	if code == codes.OK {
+		require.NoError(t, err)
+		return
+	}
+
+	require.Error(t, err)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, code, st.Code(), st.Message())
+}
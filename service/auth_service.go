@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"pc_book/pd"
@@ -22,6 +23,7 @@ func NewAuthService(userStore UserStore, jwtManager *JWTManager) *AuthService {
 	}
 }
 
+// Login is a unary RPC to login user
 func (server *AuthService) Login(ctx context.Context, req *pd.LoginRequest) (*pd.LoginResponse, error) {
 	user, err := server.userStore.Find(req.GetUsername())
 	if err != nil {
@@ -32,15 +34,196 @@ func (server *AuthService) Login(ctx context.Context, req *pd.LoginRequest) (*pd
 		return nil, status.Errorf(codes.NotFound, "incorrect username or password")
 	}
 
-	token, err := server.jwtManager.Generate(user)
+	return server.newTokenPair(user)
+}
+
+// RefreshToken is a unary RPC to exchange a refresh token for a new pair of tokens
+func (server *AuthService) RefreshToken(ctx context.Context, req *pd.RefreshTokenRequest) (*pd.RefreshTokenResponse, error) {
+	claims, err := server.jwtManager.VerifyRefreshToken(req.GetRefreshToken())
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "refresh token is invalid: %v", err)
+	}
+
+	user, err := server.userStore.Find(claims.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot find user: %v", err)
+	}
+	if user == nil {
+		return nil, status.Errorf(codes.Unauthenticated, "user %s no longer exists", claims.Username)
+	}
+
+	// refresh token只能使用一次
+	if !server.jwtManager.Revoke(claims) {
+		return nil, status.Errorf(codes.Unauthenticated, "refresh token has already been used")
+	}
+
+	tokens, err := server.newTokenPair(user)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pd.RefreshTokenResponse{
+		AccessToken:  tokens.GetAccessToken(),
+		RefreshToken: tokens.GetRefreshToken(),
+	}
+	return res, nil
+}
+
+// Logout is a unary RPC to revoke an access token and/or a refresh token
+func (server *AuthService) Logout(ctx context.Context, req *pd.LogoutRequest) (*pd.LogoutResponse, error) {
+	if req.GetAccessToken() == "" && req.GetRefreshToken() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "no token to revoke")
+	}
+
+	var revoked []*UserClaims
+	if req.GetAccessToken() != "" {
+		claims, err := server.jwtManager.Verify(req.GetAccessToken())
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "access token is invalid: %v", err)
+		}
+		revoked = append(revoked, claims)
+	}
+
+	if req.GetRefreshToken() != "" {
+		claims, err := server.jwtManager.VerifyRefreshToken(req.GetRefreshToken())
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "refresh token is invalid: %v", err)
+		}
+		revoked = append(revoked, claims)
+	}
+
+	for _, claims := range revoked {
+		server.jwtManager.Revoke(claims)
+	}
+	return &pd.LogoutResponse{}, nil
+}
+
+// GetPublicKeys is a unary RPC to list the public keys that verify our tokens
+func (server *AuthService) GetPublicKeys(ctx context.Context, req *pd.GetPublicKeysRequest) (*pd.GetPublicKeysResponse, error) {
+	res := &pd.GetPublicKeysResponse{}
+	for _, key := range server.jwtManager.PublicKeys() {
+		jwk, err := toJWK(key)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "cannot convert public key: %v", err)
+		}
+		res.Keys = append(res.Keys, jwk)
+	}
+	return res, nil
+}
+
+func (server *AuthService) newTokenPair(user *User) (*pd.LoginResponse, error) {
+	accessToken, err := server.jwtManager.Generate(user)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "cannot generate access token")
 	}
 
+	refreshToken, err := server.jwtManager.GenerateRefreshToken(user)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot generate refresh token")
+	}
+
 	res := &pd.LoginResponse{
-		AccessToken: token,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	}
 	return res, nil
 }
 
+// CreateUser is a unary RPC to create a new user
+func (server *AuthService) CreateUser(ctx context.Context, req *pd.CreateUserRequest) (*pd.CreateUserResponse, error) {
+	if req.GetUsername() == "" || req.GetPassword() == "" || req.GetRole() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "username, password and role are required")
+	}
+	if !isUserRole(req.GetRole()) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown role %q", req.GetRole())
+	}
 
+	user, err := NewUser(req.GetUsername(), req.GetPassword(), req.GetRole())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot create user: %v", err)
+	}
+
+	err = server.userStore.Save(user)
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrAlreadyExists) {
+			code = codes.AlreadyExists
+		}
+		return nil, status.Errorf(code, "cannot save user: %v", err)
+	}
+
+	res := &pd.CreateUserResponse{
+		User: toUserInfo(user),
+	}
+	return res, nil
+}
+
+// DeleteUser is a unary RPC to delete a user
+func (server *AuthService) DeleteUser(ctx context.Context, req *pd.DeleteUserRequest) (*pd.DeleteUserResponse, error) {
+	err := server.userStore.Delete(req.GetUsername())
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrNotFound) {
+			code = codes.NotFound
+		}
+		return nil, status.Errorf(code, "cannot delete user: %v", err)
+	}
+
+	// 已经发出的令牌不能再使用
+	server.jwtManager.RevokeUser(req.GetUsername())
+	return &pd.DeleteUserResponse{}, nil
+}
+
+// ChangePassword is a unary RPC to set a new password for a user
+func (server *AuthService) ChangePassword(ctx context.Context, req *pd.ChangePasswordRequest) (*pd.ChangePasswordResponse, error) {
+	if req.GetNewPassword() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "new password is required")
+	}
+
+	user, err := server.userStore.Find(req.GetUsername())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot find user: %v", err)
+	}
+	if user == nil {
+		return nil, status.Errorf(codes.NotFound, "user %s is not found", req.GetUsername())
+	}
+
+	err = user.SetPassword(req.GetNewPassword())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot set password: %v", err)
+	}
+
+	err = server.userStore.Update(user)
+	if err != nil {
+		code := codes.Internal
+		if errors.Is(err, ErrNotFound) {
+			code = codes.NotFound
+		}
+		return nil, status.Errorf(code, "cannot update user: %v", err)
+	}
+
+	// the user must login again with the new password
+	server.jwtManager.RevokeUser(user.UserName)
+	return &pd.ChangePasswordResponse{}, nil
+}
+
+// ListUsers is a unary RPC to list all users
+func (server *AuthService) ListUsers(ctx context.Context, req *pd.ListUsersRequest) (*pd.ListUsersResponse, error) {
+	users, err := server.userStore.List()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot list users: %v", err)
+	}
+
+	res := &pd.ListUsersResponse{}
+	for _, user := range users {
+		res.Users = append(res.Users, toUserInfo(user))
+	}
+	return res, nil
+}
+
+func toUserInfo(user *User) *pd.UserInfo {
+	return &pd.UserInfo{
+		Username: user.UserName,
+		Role:     user.Role,
+	}
+}
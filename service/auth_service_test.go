@@ -0,0 +1,278 @@
+package service_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"pc_book/pd"
+	"pc_book/service"
+	"testing"
+)
+
+func TestServerLogin(t *testing.T) {
+	t.Parallel()
+
+	userStore := service.NewInMemoryUserStore()
+	user, err := service.NewUser("user1", "secret", "user")
+	require.NoError(t, err)
+	require.NoError(t, userStore.Save(user))
+
+	server := service.NewAuthService(userStore, service.NewJWTManager(testSecretKey, testTokenDuration, testRefreshTokenDuration))
+
+	res, err := server.Login(context.Background(), &pd.LoginRequest{Username: "user1", Password: "secret"})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.GetAccessToken())
+
+	_, err = server.Login(context.Background(), &pd.LoginRequest{Username: "user1", Password: "wrong"})
+	requireCode(t, codes.NotFound, err)
+
+	_, err = server.Login(context.Background(), &pd.LoginRequest{Username: "unknown", Password: "secret"})
+	requireCode(t, codes.NotFound, err)
+}
+
+func TestServerManageUsers(t *testing.T) {
+	t.Parallel()
+
+	userStore := service.NewInMemoryUserStore()
+	server := service.NewAuthService(userStore, service.NewJWTManager(testSecretKey, testTokenDuration, testRefreshTokenDuration))
+	ctx := context.Background()
+
+	res, err := server.CreateUser(ctx, &pd.CreateUserRequest{Username: "user1", Password: "secret", Role: "user"})
+	require.NoError(t, err)
+	require.Equal(t, "user1", res.GetUser().GetUsername())
+	require.Equal(t, "user", res.GetUser().GetRole())
+
+	_, err = server.CreateUser(ctx, &pd.CreateUserRequest{Username: "user1", Password: "other", Role: "user"})
+	requireCode(t, codes.AlreadyExists, err)
+
+	_, err = server.CreateUser(ctx, &pd.CreateUserRequest{Username: "user2", Role: "user"})
+	requireCode(t, codes.InvalidArgument, err)
+
+	// the role must be one that the RPCs are given to
+	_, err = server.CreateUser(ctx, &pd.CreateUserRequest{Username: "user2", Password: "secret", Role: "superuser"})
+	requireCode(t, codes.InvalidArgument, err)
+
+	_, err = server.CreateUser(ctx, &pd.CreateUserRequest{Username: "admin1", Password: "secret", Role: "admin"})
+	require.NoError(t, err)
+
+	list, err := server.ListUsers(ctx, &pd.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GetUsers(), 2)
+	require.Equal(t, "admin1", list.GetUsers()[0].GetUsername())
+	require.Equal(t, "user1", list.GetUsers()[1].GetUsername())
+
+	_, err = server.ChangePassword(ctx, &pd.ChangePasswordRequest{Username: "user1", NewPassword: "new-secret"})
+	require.NoError(t, err)
+
+	_, err = server.Login(ctx, &pd.LoginRequest{Username: "user1", Password: "secret"})
+	requireCode(t, codes.NotFound, err)
+	_, err = server.Login(ctx, &pd.LoginRequest{Username: "user1", Password: "new-secret"})
+	require.NoError(t, err)
+
+	_, err = server.ChangePassword(ctx, &pd.ChangePasswordRequest{Username: "unknown", NewPassword: "secret"})
+	requireCode(t, codes.NotFound, err)
+
+	_, err = server.DeleteUser(ctx, &pd.DeleteUserRequest{Username: "user1"})
+	require.NoError(t, err)
+	_, err = server.DeleteUser(ctx, &pd.DeleteUserRequest{Username: "user1"})
+	requireCode(t, codes.NotFound, err)
+
+	list, err = server.ListUsers(ctx, &pd.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GetUsers(), 1)
+}
+
+func TestUserManagementRequiresAdmin(t *testing.T) {
+	t.Parallel()
+
+	jwtManager := service.NewJWTManager(testSecretKey, testTokenDuration, testRefreshTokenDuration)
+	interceptor := service.NewAuthInterceptor(jwtManager, map[string][]string{
+		"/AuthService/CreateUser": {"admin"},
+		"/AuthService/ListUsers":  {"admin"},
+	})
+
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(interceptor.Unary()))
+	pd.RegisterAuthServiceServer(grpcServer, service.NewAuthService(service.NewInMemoryUserStore(), jwtManager))
+
+	listener, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	authClient := pd.NewAuthServiceClient(conn)
+
+	userCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", generateTestToken(t, jwtManager, "user"))
+	_, err = authClient.ListUsers(userCtx, &pd.ListUsersRequest{})
+	requireCode(t, codes.PermissionDenied, err)
+
+	adminCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", generateTestToken(t, jwtManager, "admin"))
+	_, err = authClient.CreateUser(adminCtx, &pd.CreateUserRequest{Username: "user2", Password: "secret", Role: "user"})
+	require.NoError(t, err)
+
+	res, err := authClient.ListUsers(adminCtx, &pd.ListUsersRequest{})
+	require.NoError(t, err)
+	require.Len(t, res.GetUsers(), 1)
+}
+
+func TestSeedUsers(t *testing.T) {
+	t.Parallel()
+
+	admin, err := service.NewUser("admin1", "secret", "admin")
+	require.NoError(t, err)
+
+	seedFile := filepath.Join(t.TempDir(), "users.json")
+	data := `{"users": [{"username": "admin1", "password_hash": "` + admin.HashedPassword + `", "role": "admin"}]}`
+	require.NoError(t, ioutil.WriteFile(seedFile, []byte(data), 0644))
+
+	users, err := service.LoadUserSeedFile(seedFile)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+
+	userStore := service.NewInMemoryUserStore()
+	require.NoError(t, service.SeedUsers(userStore, users))
+	// seeding twice keeps the existing users
+	require.NoError(t, service.SeedUsers(userStore, users))
+
+	found, err := userStore.Find("admin1")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	require.Equal(t, "admin", found.Role)
+	require.True(t, found.IsCorrectPassword("secret"))
+
+	badFile := filepath.Join(t.TempDir(), "users.json")
+	data = `{"users": [{"username": "admin1", "password_hash": "secret", "role": "admin"}]}`
+	require.NoError(t, ioutil.WriteFile(badFile, []byte(data), 0644))
+
+	_, err = service.LoadUserSeedFile(badFile)
+	require.Error(t, err)
+
+	data = `{"users": [{"username": "admin1", "password_hash": "` + admin.HashedPassword + `", "role": "root"}]}`
+	require.NoError(t, ioutil.WriteFile(badFile, []byte(data), 0644))
+
+	_, err = service.LoadUserSeedFile(badFile)
+	require.Error(t, err)
+}
+
+func TestServerRevokesTokensOfChangedUser(t *testing.T) {
+	t.Parallel()
+
+	userStore := service.NewInMemoryUserStore()
+	jwtManager := service.NewJWTManager(testSecretKey, testTokenDuration, testRefreshTokenDuration)
+	server := service.NewAuthService(userStore, jwtManager)
+	ctx := context.Background()
+
+	_, err := server.CreateUser(ctx, &pd.CreateUserRequest{Username: "user1", Password: "secret", Role: "user"})
+	require.NoError(t, err)
+	_, err = server.CreateUser(ctx, &pd.CreateUserRequest{Username: "user2", Password: "secret", Role: "user"})
+	require.NoError(t, err)
+
+	login1, err := server.Login(ctx, &pd.LoginRequest{Username: "user1", Password: "secret"})
+	require.NoError(t, err)
+	login2, err := server.Login(ctx, &pd.LoginRequest{Username: "user2", Password: "secret"})
+	require.NoError(t, err)
+
+	// the tokens given before the password is changed are revoked
+	_, err = server.ChangePassword(ctx, &pd.ChangePasswordRequest{Username: "user1", NewPassword: "new-secret"})
+	require.NoError(t, err)
+
+	_, err = jwtManager.Verify(login1.GetAccessToken())
+	require.Error(t, err)
+	_, err = server.RefreshToken(ctx, &pd.RefreshTokenRequest{RefreshToken: login1.GetRefreshToken()})
+	requireCode(t, codes.Unauthenticated, err)
+
+	// the tokens of the new login and of the other users are valid
+	login1, err = server.Login(ctx, &pd.LoginRequest{Username: "user1", Password: "new-secret"})
+	require.NoError(t, err)
+	_, err = jwtManager.Verify(login1.GetAccessToken())
+	require.NoError(t, err)
+	_, err = jwtManager.Verify(login2.GetAccessToken())
+	require.NoError(t, err)
+
+	// the tokens of a deleted user are revoked
+	_, err = server.DeleteUser(ctx, &pd.DeleteUserRequest{Username: "user1"})
+	require.NoError(t, err)
+
+	_, err = jwtManager.Verify(login1.GetAccessToken())
+	require.Error(t, err)
+	_, err = jwtManager.VerifyRefreshToken(login1.GetRefreshToken())
+	require.Error(t, err)
+	_, err = jwtManager.Verify(login2.GetAccessToken())
+	require.NoError(t, err)
+}
+
+func TestServerRefreshToken(t *testing.T) {
+	t.Parallel()
+
+	userStore := service.NewInMemoryUserStore()
+	user, err := service.NewUser("user1", "secret", "user")
+	require.NoError(t, err)
+	require.NoError(t, userStore.Save(user))
+
+	jwtManager := service.NewJWTManager(testSecretKey, testTokenDuration, testRefreshTokenDuration)
+	server := service.NewAuthService(userStore, jwtManager)
+	ctx := context.Background()
+
+	login, err := server.Login(ctx, &pd.LoginRequest{Username: "user1", Password: "secret"})
+	require.NoError(t, err)
+	require.NotEmpty(t, login.GetRefreshToken())
+
+	res, err := server.RefreshToken(ctx, &pd.RefreshTokenRequest{RefreshToken: login.GetRefreshToken()})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.GetAccessToken())
+	require.NotEqual(t, login.GetRefreshToken(), res.GetRefreshToken())
+
+	claims, err := jwtManager.Verify(res.GetAccessToken())
+	require.NoError(t, err)
+	require.Equal(t, "user1", claims.Username)
+
+	// the old refresh token can only be used once
+	_, err = server.RefreshToken(ctx, &pd.RefreshTokenRequest{RefreshToken: login.GetRefreshToken()})
+	requireCode(t, codes.Unauthenticated, err)
+
+	// an access token is not a refresh token
+	_, err = server.RefreshToken(ctx, &pd.RefreshTokenRequest{RefreshToken: res.GetAccessToken()})
+	requireCode(t, codes.Unauthenticated, err)
+
+	// deleted users cannot refresh their tokens
+	require.NoError(t, userStore.Delete("user1"))
+	_, err = server.RefreshToken(ctx, &pd.RefreshTokenRequest{RefreshToken: res.GetRefreshToken()})
+	requireCode(t, codes.Unauthenticated, err)
+}
+
+func TestServerLogout(t *testing.T) {
+	t.Parallel()
+
+	userStore := service.NewInMemoryUserStore()
+	user, err := service.NewUser("user1", "secret", "user")
+	require.NoError(t, err)
+	require.NoError(t, userStore.Save(user))
+
+	jwtManager := service.NewJWTManager(testSecretKey, testTokenDuration, testRefreshTokenDuration)
+	server := service.NewAuthService(userStore, jwtManager)
+	ctx := context.Background()
+
+	login, err := server.Login(ctx, &pd.LoginRequest{Username: "user1", Password: "secret"})
+	require.NoError(t, err)
+
+	_, err = server.Logout(ctx, &pd.LogoutRequest{})
+	requireCode(t, codes.InvalidArgument, err)
+
+	_, err = server.Logout(ctx, &pd.LogoutRequest{
+		AccessToken:  login.GetAccessToken(),
+		RefreshToken: login.GetRefreshToken(),
+	})
+	require.NoError(t, err)
+
+	_, err = jwtManager.Verify(login.GetAccessToken())
+	require.Error(t, err)
+
+	_, err = server.RefreshToken(ctx, &pd.RefreshTokenRequest{RefreshToken: login.GetRefreshToken()})
+	requireCode(t, codes.Unauthenticated, err)
+}
@@ -0,0 +1,580 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	_ "modernc.org/sqlite" // pure Go SQLite driver
+	"pc_book/pd"
+	"strings"
+)
+
+// DBLaptopStore store laptop DB
+// 存储在数据库中, 主要字段作为列用于查询, 完整的laptop以protobuf格式保存在data列
+type DBLaptopStore struct {
+	db *sql.DB
+}
+
+// NewDBLaptopStore returns a new DBLaptopStore, the schema migrations are applied to db
+func NewDBLaptopStore(db *sql.DB) (*DBLaptopStore, error) {
+	err := migrateDB(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DBLaptopStore{db: db}, nil
+}
+
+// OpenSQLiteLaptopStore opens or creates a SQLite database file and returns a DBLaptopStore on it
+func OpenSQLiteLaptopStore(path string) (*DBLaptopStore, error) {
+	// WAL mode lets searches run while a laptop is being saved
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open database: %w", err)
+	}
+
+	store, err := NewDBLaptopStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Close closes the database
+func (store *DBLaptopStore) Close() error {
+	return store.db.Close()
+}
+
+// laptopColumns are the columns of the laptops table besides id and data
+var laptopColumns = []string{
+	"brand", "name", "cpu_brand", "cpu_name", "cpu_cores", "cpu_threads", "cpu_min_ghz", "cpu_max_ghz",
+	"ram_bits", "price_usd", "release_year", "updated_at", "weight_kg",
+}
+
+// laptopColumnValues returns the values of laptopColumns
+func laptopColumnValues(laptop *pd.Laptop) []interface{} {
+	return []interface{}{
+		laptop.GetBrand(),
+		laptop.GetName(),
+		laptop.GetCpu().GetBrand(),
+		laptop.GetCpu().GetName(),
+		laptop.GetCpu().GetNumberCores(),
+		laptop.GetCpu().GetNumberThreads(),
+		laptop.GetCpu().GetMinGhz(),
+		laptop.GetCpu().GetMaxGhz(),
+		int64(toBit(laptop.GetRam())),
+		laptop.GetPriceUsd(),
+		laptop.GetReleaseYear(),
+		laptop.GetUpdatedAt().AsTime().UnixNano(),
+		nullableKg(laptop),
+	}
+}
+
+// nullableKg returns the weight of the laptop in kilograms, or nil if it is unknown
+func nullableKg(laptop *pd.Laptop) interface{} {
+	if laptop.GetWeight() == nil {
+		return nil
+	}
+	return toKg(laptop)
+}
+
+// Save saves the laptop and its words for the text search to the database
+func (store *DBLaptopStore) Save(laptop *pd.Laptop) error {
+	return store.inTx(func(tx *sql.Tx) error {
+		return insertLaptop(tx, laptop)
+	})
+}
+
+// SaveBatch saves all the laptops in one transaction
+func (store *DBLaptopStore) SaveBatch(laptops []*pd.Laptop) error {
+	return store.inTx(func(tx *sql.Tx) error {
+		for i, laptop := range laptops {
+			err := insertLaptop(tx, laptop)
+			if err != nil {
+				return &BatchError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+}
+
+// insertLaptop adds a new laptop row and its words, it returns ErrAlreadyExists if the id is used
+func insertLaptop(tx *sql.Tx, laptop *pd.Laptop) error {
+	data, err := proto.Marshal(laptop)
+	if err != nil {
+		return fmt.Errorf("cannot marshal laptop: %w", err)
+	}
+
+	placeholders := strings.Repeat(", ?", len(laptopColumns))
+	query := `INSERT INTO laptops (id, ` + strings.Join(laptopColumns, ", ") + `, data)
+		VALUES (?` + placeholders + `, ?)
+		ON CONFLICT (id) DO NOTHING`
+
+	args := append([]interface{}{laptop.GetId()}, laptopColumnValues(laptop)...)
+	args = append(args, data)
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("cannot insert laptop: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot insert laptop: %w", err)
+	}
+	if rows == 0 {
+		return ErrAlreadyExists
+	}
+
+	return insertTerms(tx, laptop)
+}
+
+// Update replaces the laptop with the same id.
+// The row is only written if its data is still the one the etag was checked against.
+func (store *DBLaptopStore) Update(laptop *pd.Laptop, etag string) error {
+	current, err := store.checkEtag(laptop.GetId(), etag)
+	if err != nil {
+		return err
+	}
+
+	data, err := proto.Marshal(laptop)
+	if err != nil {
+		return fmt.Errorf("cannot marshal laptop: %w", err)
+	}
+
+	query := `UPDATE laptops SET ` + strings.Join(laptopColumns, " = ?, ") + ` = ?, data = ?
+		WHERE id = ? AND data = ?`
+	args := append(laptopColumnValues(laptop), data, laptop.GetId(), current)
+
+	return store.inTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return fmt.Errorf("cannot update laptop: %w", err)
+		}
+
+		err = checkRowChanged(result)
+		if err != nil {
+			return err
+		}
+
+		err = deleteTerms(tx, laptop.GetId())
+		if err != nil {
+			return err
+		}
+		return insertTerms(tx, laptop)
+	})
+}
+
+// Delete deletes a laptop by id
+func (store *DBLaptopStore) Delete(id string, etag string) error {
+	current, err := store.checkEtag(id, etag)
+	if err != nil {
+		return err
+	}
+
+	return store.inTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`DELETE FROM laptops WHERE id = ? AND data = ?`, id, current)
+		if err != nil {
+			return fmt.Errorf("cannot delete laptop: %w", err)
+		}
+
+		err = checkRowChanged(result)
+		if err != nil {
+			return err
+		}
+		return deleteTerms(tx, id)
+	})
+}
+
+// inTx runs fn in a transaction, which is committed if fn returns no error
+func (store *DBLaptopStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertTerms writes the words of the laptop for the text search
+func insertTerms(tx *sql.Tx, laptop *pd.Laptop) error {
+	for token, weight := range laptopTerms(laptop) {
+		_, err := tx.Exec(`INSERT INTO laptop_terms (token, laptop_id, weight) VALUES (?, ?, ?)`,
+			token, laptop.GetId(), weight)
+		if err != nil {
+			return fmt.Errorf("cannot insert laptop terms: %w", err)
+		}
+	}
+	return nil
+}
+
+func deleteTerms(tx *sql.Tx, id string) error {
+	_, err := tx.Exec(`DELETE FROM laptop_terms WHERE laptop_id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("cannot delete laptop terms: %w", err)
+	}
+	return nil
+}
+
+// checkEtag returns the stored data of the laptop if it matches etag
+func (store *DBLaptopStore) checkEtag(id string, etag string) ([]byte, error) {
+	data, err := store.findData(id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, ErrNotFound
+	}
+
+	if etag != "" {
+		laptop, err := unmarshalLaptop(data)
+		if err != nil {
+			return nil, err
+		}
+		if LaptopEtag(laptop) != etag {
+			return nil, ErrConflict
+		}
+	}
+	return data, nil
+}
+
+// checkRowChanged returns ErrConflict if another writer changed the row in the meantime
+func checkRowChanged(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot get affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// Find finds a laptop by id
+func (store *DBLaptopStore) Find(id string) (*pd.Laptop, error) {
+	data, err := store.findData(id)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	return unmarshalLaptop(data)
+}
+
+// findData returns the protobuf data of a laptop, or nil if it doesn't exist
+func (store *DBLaptopStore) findData(id string) ([]byte, error) {
+	var data []byte
+	err := store.db.QueryRow(`SELECT data FROM laptops WHERE id = ?`, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot query laptop: %w", err)
+	}
+	return data, nil
+}
+
+// sortColumns are the columns of the sort keys, laptops are sorted by id only if there is none.
+// The rating is not stored in the database, so laptops sorted by rating are sorted in memory.
+var sortColumns = map[pd.SearchLaptopRequest_SortBy]string{
+	pd.SearchLaptopRequest_PRICE:        "price_usd",
+	pd.SearchLaptopRequest_CPU_GHZ:      "cpu_min_ghz",
+	pd.SearchLaptopRequest_RAM:          "ram_bits",
+	pd.SearchLaptopRequest_RELEASE_YEAR: "release_year",
+}
+
+// Search searches for laptops with query, returns one by one via the found function
+func (store *DBLaptopStore) Search(ctx context.Context, query *SearchQuery, found func(laptop *pd.Laptop, score float64) error) error {
+	var results []searchResult
+	err := store.readTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if tokens := query.tokens(); len(tokens) > 0 {
+			results, err = searchText(ctx, tx, query, tokens)
+		} else if query.SortBy == pd.SearchLaptopRequest_RATING {
+			results, err = searchByRating(ctx, tx, query)
+		} else {
+			results, err = searchSorted(ctx, tx, query)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// the transaction is closed before found is called, so a slow client doesn't keep it open
+	for _, result := range results {
+		err := found(result.laptop, result.score)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Facets counts the laptops that match the filter and the text of the query for each facet value
+func (store *DBLaptopStore) Facets(ctx context.Context, query *SearchQuery) (*FacetCounts, error) {
+	facets := newFacetCounts()
+	err := store.readTx(ctx, func(tx *sql.Tx) error {
+		add := func(laptop *pd.Laptop, score float64) bool {
+			facets.add(laptop)
+			return true
+		}
+
+		if tokens := query.tokens(); len(tokens) > 0 {
+			return scanText(ctx, tx, query, tokens, add)
+		}
+
+		where, args := filterToSQL(query.Filter)
+		return scan(ctx, tx, query.Filter, `SELECT data FROM laptops WHERE `+where, args, func(laptop *pd.Laptop) bool {
+			return add(laptop, 0)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+// readTx runs fn in a transaction that is rolled back, so that all its queries see the same snapshot
+func (store *DBLaptopStore) readTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	return fn(tx)
+}
+
+// searchSorted lets the database sort the laptops and stops reading once the page is full
+func searchSorted(ctx context.Context, tx *sql.Tx, query *SearchQuery) ([]searchResult, error) {
+	where, args := filterToSQL(query.Filter)
+
+	direction, operator := "ASC", ">"
+	if query.Descending {
+		direction, operator = "DESC", "<"
+	}
+
+	column := sortColumns[query.SortBy]
+	orderBy := "id " + direction
+	if column != "" {
+		orderBy = column + " " + direction + ", " + orderBy
+	}
+
+	if query.After != nil {
+		if column == "" {
+			where += " AND id " + operator + " ?"
+			args = append(args, query.After.ID)
+		} else {
+			where += fmt.Sprintf(" AND (%s %s ? OR (%s = ? AND id %s ?))", column, operator, column, operator)
+			args = append(args, query.After.Value, query.After.Value, query.After.ID)
+		}
+	}
+
+	var results []searchResult
+	err := scan(ctx, tx, query.Filter, `SELECT data FROM laptops WHERE `+where+` ORDER BY `+orderBy, args, func(laptop *pd.Laptop) bool {
+		results = append(results, searchResult{laptop: laptop, position: query.cursor(laptop, 0)})
+		return query.Limit == 0 || len(results) < query.Limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// searchByRating reads every laptop that matches the filter and keeps the best rated ones
+func searchByRating(ctx context.Context, tx *sql.Tx, query *SearchQuery) ([]searchResult, error) {
+	where, args := filterToSQL(query.Filter)
+
+	top := newTopResults(query)
+	err := scan(ctx, tx, query.Filter, `SELECT data FROM laptops WHERE `+where, args, func(laptop *pd.Laptop) bool {
+		top.add(laptop, 0)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return top.sorted(), nil
+}
+
+// searchText reads the laptops that match the words of the text query and sorts them in memory
+func searchText(ctx context.Context, tx *sql.Tx, query *SearchQuery, tokens []string) ([]searchResult, error) {
+	top := newTopResults(query)
+	err := scanText(ctx, tx, query, tokens, func(laptop *pd.Laptop, score float64) bool {
+		top.add(laptop, score)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return top.sorted(), nil
+}
+
+// maxIDsPerQuery keeps the number of parameters of a query below the limit of SQLite
+const maxIDsPerQuery = 500
+
+// scanText calls next with every laptop that matches the filter and the words of the text query
+func scanText(ctx context.Context, tx *sql.Tx, query *SearchQuery, tokens []string, next func(laptop *pd.Laptop, score float64) bool) error {
+	scores, err := textScores(ctx, tx, tokens)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+
+	where, args := filterToSQL(query.Filter)
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxIDsPerQuery {
+			n = maxIDsPerQuery
+		}
+
+		batch := append([]interface{}(nil), args...)
+		for _, id := range ids[:n] {
+			batch = append(batch, id)
+		}
+		ids = ids[n:]
+
+		sqlQuery := `SELECT data FROM laptops WHERE ` + where + ` AND id IN (?` + strings.Repeat(", ?", n-1) + `)`
+		stopped := false
+		err := scan(ctx, tx, query.Filter, sqlQuery, batch, func(laptop *pd.Laptop) bool {
+			stopped = !next(laptop, scores[laptop.GetId()])
+			return !stopped
+		})
+		if err != nil || stopped {
+			return err
+		}
+	}
+	return nil
+}
+
+// textScores returns the relevance of the laptops that match every word, using the laptop_terms table
+func textScores(ctx context.Context, tx *sql.Tx, tokens []string) (map[string]float64, error) {
+	var total int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM laptops`).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("cannot count laptops: %w", err)
+	}
+
+	matches := make([][]posting, len(tokens))
+	for i, token := range tokens {
+		// the words only have letters and digits, so they have no GLOB wildcard
+		rows, err := tx.QueryContext(ctx,
+			`SELECT laptop_id, token, weight FROM laptop_terms WHERE token GLOB ?`, token+"*")
+		if err != nil {
+			return nil, fmt.Errorf("cannot query laptop terms: %w", err)
+		}
+
+		for rows.Next() {
+			var p posting
+			err := rows.Scan(&p.laptopID, &p.token, &p.weight)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("cannot scan laptop term: %w", err)
+			}
+			matches[i] = append(matches[i], p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("cannot query laptop terms: %w", err)
+		}
+	}
+
+	return scoreText(tokens, matches, total), nil
+}
+
+// scan calls next with every laptop of the query that is qualified for the filter, until next returns false
+func scan(ctx context.Context, tx *sql.Tx, filter *pd.Filter, query string, args []interface{}, next func(laptop *pd.Laptop) bool) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("cannot query laptops: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		err := rows.Scan(&data)
+		if err != nil {
+			return fmt.Errorf("cannot scan laptop: %w", err)
+		}
+
+		laptop, err := unmarshalLaptop(data)
+		if err != nil {
+			return err
+		}
+
+		// the same check as the other stores, in case the columns are not enough
+		if !isQualified(filter, laptop) {
+			continue
+		}
+
+		if !next(laptop) {
+			break
+		}
+	}
+
+	return rows.Err()
+}
+
+// filterToSQL converts a filter to a WHERE clause and its arguments.
+// Only the conditions on columns are converted, the rest are checked by isQualified.
+func filterToSQL(filter *pd.Filter) (string, []interface{}) {
+	conditions := []string{
+		"price_usd <= ?",
+		"cpu_cores >= ?",
+		"cpu_min_ghz >= ?",
+		"ram_bits >= ?",
+		"release_year >= ?",
+	}
+	args := []interface{}{
+		filter.GetMaxPriceUsd(),
+		filter.GetMinCpuCores(),
+		filter.GetMinCpuGhz(),
+		int64(toBit(filter.GetMinRam())),
+		filter.GetMinReleaseYear(),
+	}
+
+	if filter.GetMaxReleaseYear() > 0 {
+		conditions = append(conditions, "release_year <= ?")
+		args = append(args, filter.GetMaxReleaseYear())
+	}
+
+	if len(filter.GetBrands()) > 0 {
+		conditions = append(conditions, "brand IN (?"+strings.Repeat(", ?", len(filter.GetBrands())-1)+")")
+		for _, brand := range filter.GetBrands() {
+			args = append(args, brand)
+		}
+	}
+
+	if filter.GetMaxWeight() != nil {
+		// NULL <= ? is not true, so laptops without weight are excluded like in isQualified
+		conditions = append(conditions, "weight_kg <= ?")
+		args = append(args, maxWeightKg(filter))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+func unmarshalLaptop(data []byte) (*pd.Laptop, error) {
+	laptop := &pd.Laptop{}
+	err := proto.Unmarshal(data, laptop)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unmarshal laptop: %w", err)
+	}
+	return laptop, nil
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"pc_book/pd"
+	"time"
+)
+
+// dbMigration changes the schema inside a transaction
+type dbMigration func(tx *sql.Tx) error
+
+// dbMigrations are the schema changes of DBLaptopStore, each one is applied exactly once in order.
+// Never edit a migration that has been released, add a new one instead.
+var dbMigrations = []dbMigration{
+	// 1: laptops table, the main fields are columns so that a filter becomes a WHERE clause
+	execMigration(`CREATE TABLE laptops (
+		id           TEXT PRIMARY KEY,
+		brand        TEXT NOT NULL,
+		name         TEXT NOT NULL,
+		cpu_brand    TEXT NOT NULL,
+		cpu_name     TEXT NOT NULL,
+		cpu_cores    INTEGER NOT NULL,
+		cpu_threads  INTEGER NOT NULL,
+		cpu_min_ghz  REAL NOT NULL,
+		cpu_max_ghz  REAL NOT NULL,
+		ram_bits     INTEGER NOT NULL,
+		price_usd    REAL NOT NULL,
+		release_year INTEGER NOT NULL,
+		updated_at   INTEGER NOT NULL,
+		data         BLOB NOT NULL
+	);
+	CREATE INDEX laptops_price_usd ON laptops (price_usd);
+	CREATE INDEX laptops_cpu ON laptops (cpu_cores, cpu_min_ghz);
+	CREATE INDEX laptops_ram_bits ON laptops (ram_bits);`),
+	// 2: weight in kilograms, NULL if the laptop has no weight
+	migrateWeight,
+	// 3: inverted index of the text search
+	migrateTerms,
+}
+
+// execMigration returns a migration that runs SQL statements
+func execMigration(query string) dbMigration {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// migrateWeight adds the weight_kg column and fills it from the stored laptops
+func migrateWeight(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE laptops ADD COLUMN weight_kg REAL;
+		CREATE INDEX laptops_weight_kg ON laptops (weight_kg);`)
+	if err != nil {
+		return err
+	}
+
+	laptops, err := loadLaptops(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, laptop := range laptops {
+		_, err := tx.Exec(`UPDATE laptops SET weight_kg = ? WHERE id = ?`, nullableKg(laptop), laptop.GetId())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateDB applies the migrations that are not in the schema_migrations table yet
+func migrateDB(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("cannot create schema_migrations table: %w", err)
+	}
+
+	var current int
+	err = db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("cannot read schema version: %w", err)
+	}
+
+	if current > len(dbMigrations) {
+		return fmt.Errorf("database schema version %d is newer than this server (%d)", current, len(dbMigrations))
+	}
+
+	for version := current + 1; version <= len(dbMigrations); version++ {
+		err := applyMigration(db, version, dbMigrations[version-1])
+		if err != nil {
+			return fmt.Errorf("cannot apply migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+func applyMigration(db *sql.DB, version int, migration dbMigration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = migration(tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// migrateTerms creates the laptop_terms table and fills it with the words of the stored laptops
+func migrateTerms(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE laptop_terms (
+		token     TEXT NOT NULL,
+		laptop_id TEXT NOT NULL,
+		weight    REAL NOT NULL,
+		PRIMARY KEY (token, laptop_id)
+	);
+	CREATE INDEX laptop_terms_laptop_id ON laptop_terms (laptop_id);`)
+	if err != nil {
+		return err
+	}
+
+	laptops, err := loadLaptops(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, laptop := range laptops {
+		err := insertTerms(tx, laptop)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadLaptops reads every laptop of the table, for the migrations that fill a new column or table
+func loadLaptops(tx *sql.Tx) ([]*pd.Laptop, error) {
+	rows, err := tx.Query(`SELECT data FROM laptops`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var laptops []*pd.Laptop
+	for rows.Next() {
+		var data []byte
+		err := rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		laptop, err := unmarshalLaptop(data)
+		if err != nil {
+			return nil, err
+		}
+		laptops = append(laptops, laptop)
+	}
+	return laptops, rows.Err()
+}
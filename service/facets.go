@@ -0,0 +1,96 @@
+package service
+
+import (
+	"pc_book/pd"
+	"sort"
+)
+
+// names of the facets returned by GetSearchFacets
+const (
+	brandFacet         = "brand"
+	ramFacet           = "ram"
+	cpuBrandFacet      = "cpu_brand"
+	storageDriverFacet = "storage_driver"
+	screenPanelFacet   = "screen_panel"
+)
+
+var facetNames = []string{brandFacet, ramFacet, cpuBrandFacet, storageDriverFacet, screenPanelFacet}
+
+// ramTiers are the RAM facet values, from the largest minimum size in gigabytes
+var ramTiers = []struct {
+	minGigabytes uint64
+	label        string
+}{
+	{64, "64GB+"},
+	{32, "32GB"},
+	{16, "16GB"},
+	{8, "8GB"},
+	{0, "<8GB"},
+}
+
+// FacetCounts counts the laptops that match a query for each value of the facet fields
+type FacetCounts struct {
+	Total int
+	// Counts maps a facet name to the number of laptops for each value
+	Counts map[string]map[string]int
+}
+
+// newFacetCounts returns empty counts for every facet
+func newFacetCounts() *FacetCounts {
+	counts := make(map[string]map[string]int)
+	for _, name := range facetNames {
+		counts[name] = make(map[string]int)
+	}
+	return &FacetCounts{Counts: counts}
+}
+
+// add counts a laptop that matches the query
+func (facets *FacetCounts) add(laptop *pd.Laptop) {
+	facets.Total++
+	facets.Counts[brandFacet][laptop.GetBrand()]++
+	facets.Counts[ramFacet][ramTier(laptop.GetRam())]++
+	facets.Counts[cpuBrandFacet][laptop.GetCpu().GetBrand()]++
+	facets.Counts[screenPanelFacet][laptop.GetScreen().GetPanel().String()]++
+
+	// 一台laptop同时有SSD和HDD时两个值都计数
+	drivers := make(map[pd.Storage_Driver]bool)
+	for _, storage := range laptop.GetStorages() {
+		drivers[storage.GetDriver()] = true
+	}
+	for driver := range drivers {
+		facets.Counts[storageDriverFacet][driver.String()]++
+	}
+}
+
+// ramTier returns the RAM facet value of a memory size
+func ramTier(memory *pd.Memory) string {
+	gigabytes := toBit(memory) >> 33
+	for _, tier := range ramTiers {
+		if gigabytes >= tier.minGigabytes {
+			return tier.label
+		}
+	}
+	return ramTiers[len(ramTiers)-1].label
+}
+
+// toProto returns the facets with their values sorted by count, then by value
+func (facets *FacetCounts) toProto() *pd.GetSearchFacetsResponse {
+	res := &pd.GetSearchFacetsResponse{Total: uint32(facets.Total)}
+
+	for _, name := range facetNames {
+		facet := &pd.Facet{Name: name}
+		for value, count := range facets.Counts[name] {
+			facet.Values = append(facet.Values, &pd.FacetValue{Value: value, Count: uint32(count)})
+		}
+
+		sort.Slice(facet.Values, func(i, j int) bool {
+			a, b := facet.Values[i], facet.Values[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Value < b.Value
+		})
+		res.Facets = append(res.Facets, facet)
+	}
+	return res
+}
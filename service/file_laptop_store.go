@@ -0,0 +1,607 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"pc_book/pd"
+	"sync"
+)
+
+const (
+	walFileName      = "laptops.wal"
+	snapshotFileName = "laptops.snapshot"
+
+	// defaultCompactThreshold is the number of log records after which the log is compacted
+	defaultCompactThreshold = 1000
+
+	// recordHeaderSize is the size of the length and checksum in front of each record
+	recordHeaderSize = 8
+	// maxRecordSize protects against allocating a huge buffer for a corrupted length
+	maxRecordSize = 64 << 20
+)
+
+// log record kinds
+const (
+	recordSave byte = iota + 1
+	recordUpdate
+	// recordDelete only holds the id of the laptop
+	recordDelete
+	// recordBatch holds several saved laptops, each one prefixed with its length
+	recordBatch
+)
+
+// errTornRecord is returned when the last record of a file was only partially written
+var errTornRecord = errors.New("torn record")
+
+// FileLaptopStore stores laptops in memory and persists every change to an append-only log on disk.
+// Each record is [4 bytes length][4 bytes CRC-32][1 byte kind][protobuf laptop],
+// a batch record holds the laptops of SaveBatch so that they are replayed all together.
+type FileLaptopStore struct {
+	// mutex serializes the writers, readers only use the in-memory store
+	mutex            sync.Mutex
+	memory           *InMemoryLaptopStore
+	dir              string
+	wal              logFile
+	// size is the end of the last record written to the log, failed is set if the log could not be restored to it
+	size             int64
+	failed           error
+	records          int
+	compactThreshold int
+}
+
+// logFile is the file of the log, *os.File in production
+type logFile interface {
+	io.WriteSeeker
+	io.Closer
+	Sync() error
+	Truncate(size int64) error
+}
+
+// NewFileLaptopStore opens the store in dir, replaying the snapshot and the log found there
+func NewFileLaptopStore(dir string) (*FileLaptopStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create data folder: %w", err)
+	}
+
+	store := &FileLaptopStore{
+		memory:           NewInMemoryLaptopStore(),
+		dir:              dir,
+		compactThreshold: defaultCompactThreshold,
+	}
+
+	_, err = store.replay(store.snapshotPath(), false)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot load snapshot: %w", err)
+	}
+
+	valid, err := store.replay(store.walPath(), true)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot replay log: %w", err)
+	}
+
+	wal, err := os.OpenFile(store.walPath(), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open log: %w", err)
+	}
+
+	// 丢弃最后一条没有写完整的记录
+	err = wal.Truncate(valid)
+	if err != nil {
+		wal.Close()
+		return nil, fmt.Errorf("cannot truncate log: %w", err)
+	}
+
+	_, err = wal.Seek(valid, io.SeekStart)
+	if err != nil {
+		wal.Close()
+		return nil, fmt.Errorf("cannot seek log: %w", err)
+	}
+
+	store.wal = wal
+	store.size = valid
+	return store, nil
+}
+
+// SetCompactThreshold sets the number of log records after which the log is compacted into a snapshot
+func (store *FileLaptopStore) SetCompactThreshold(threshold int) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.compactThreshold = threshold
+}
+
+// Close closes the log file
+func (store *FileLaptopStore) Close() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.wal.Close()
+}
+
+// Save saves the laptop to the log and then to memory
+func (store *FileLaptopStore) Save(laptop *pd.Laptop) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	found, err := store.memory.Find(laptop.Id)
+	if err != nil {
+		return err
+	}
+	if found != nil {
+		return ErrAlreadyExists
+	}
+
+	err = store.append(recordSave, laptop)
+	if err != nil {
+		return err
+	}
+
+	err = store.memory.Save(laptop)
+	if err != nil {
+		return err
+	}
+
+	store.maybeCompact()
+	return nil
+}
+
+// SaveBatch writes all the laptops to one log record and then saves them to memory
+func (store *FileLaptopStore) SaveBatch(laptops []*pd.Laptop) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.memory.verifyBatch(laptops)
+	if err != nil {
+		return err
+	}
+
+	record, err := encodeBatchRecord(laptops)
+	if err != nil {
+		return err
+	}
+
+	err = store.write(record)
+	if err != nil {
+		return err
+	}
+
+	err = store.memory.SaveBatch(laptops)
+	if err != nil {
+		return err
+	}
+
+	store.maybeCompact()
+	return nil
+}
+
+// Update writes the new laptop to the log and then replaces it in memory
+func (store *FileLaptopStore) Update(laptop *pd.Laptop, etag string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.memory.verifyEtag(laptop.Id, etag)
+	if err != nil {
+		return err
+	}
+
+	err = store.append(recordUpdate, laptop)
+	if err != nil {
+		return err
+	}
+
+	err = store.memory.Update(laptop, "")
+	if err != nil {
+		return err
+	}
+
+	store.maybeCompact()
+	return nil
+}
+
+// Delete writes the deletion to the log and then removes the laptop from memory
+func (store *FileLaptopStore) Delete(id string, etag string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.memory.verifyEtag(id, etag)
+	if err != nil {
+		return err
+	}
+
+	err = store.append(recordDelete, &pd.Laptop{Id: id})
+	if err != nil {
+		return err
+	}
+
+	err = store.memory.Delete(id, "")
+	if err != nil {
+		return err
+	}
+
+	store.maybeCompact()
+	return nil
+}
+
+// Find finds a laptop by id
+func (store *FileLaptopStore) Find(id string) (*pd.Laptop, error) {
+	return store.memory.Find(id)
+}
+
+// Search searches for laptops with query, returns one by one via the found function
+func (store *FileLaptopStore) Search(ctx context.Context, query *SearchQuery, found func(laptop *pd.Laptop, score float64) error) error {
+	return store.memory.Search(ctx, query, found)
+}
+
+// Facets counts the laptops that match the query for each facet value
+func (store *FileLaptopStore) Facets(ctx context.Context, query *SearchQuery) (*FacetCounts, error) {
+	return store.memory.Facets(ctx, query)
+}
+
+// Compact writes every laptop to a new snapshot and empties the log
+func (store *FileLaptopStore) Compact() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.compact()
+}
+
+func (store *FileLaptopStore) walPath() string {
+	return filepath.Join(store.dir, walFileName)
+}
+
+func (store *FileLaptopStore) snapshotPath() string {
+	return filepath.Join(store.dir, snapshotFileName)
+}
+
+// append writes a record to the log and waits until it is on disk
+func (store *FileLaptopStore) append(kind byte, laptop *pd.Laptop) error {
+	record, err := encodeRecord(kind, laptop)
+	if err != nil {
+		return err
+	}
+	return store.write(record)
+}
+
+// write writes an encoded record to the log and waits until it is on disk.
+// If it fails, the log is cut back to its previous size so that the next records follow the last complete one.
+func (store *FileLaptopStore) write(record []byte) error {
+	if store.failed != nil {
+		return fmt.Errorf("laptop log is unusable: %w", store.failed)
+	}
+
+	_, err := store.wal.Write(record)
+	if err != nil {
+		err = fmt.Errorf("cannot write log record: %w", err)
+	} else if err = store.wal.Sync(); err != nil {
+		err = fmt.Errorf("cannot sync log: %w", err)
+	}
+	if err != nil {
+		store.rollback()
+		return err
+	}
+
+	store.size += int64(len(record))
+	store.records++
+	return nil
+}
+
+// rollback removes the bytes written after the last complete record,
+// the store refuses every change if they cannot be removed
+func (store *FileLaptopStore) rollback() {
+	err := store.wal.Truncate(store.size)
+	if err == nil {
+		_, err = store.wal.Seek(store.size, io.SeekStart)
+	}
+	if err == nil {
+		err = store.wal.Sync()
+	}
+	if err != nil {
+		log.Printf("cannot restore laptop log to %d bytes: %v", store.size, err)
+		store.failed = err
+	}
+}
+
+func (store *FileLaptopStore) maybeCompact() {
+	if store.compactThreshold <= 0 || store.records < store.compactThreshold {
+		return
+	}
+
+	err := store.compact()
+	if err != nil {
+		// the log is still complete, so compaction can be tried again later
+		log.Printf("cannot compact laptop log: %v", err)
+	}
+}
+
+func (store *FileLaptopStore) compact() error {
+	tmpPath := store.snapshotPath() + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("cannot create snapshot: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	err = store.memory.forEach(func(laptop *pd.Laptop) error {
+		record, err := encodeRecord(recordSave, laptop)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(record)
+		return err
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("cannot write snapshot: %w", err)
+	}
+
+	// 先替换快照，再清空日志；中途崩溃时重放日志也只会得到相同的数据
+	err = os.Rename(tmpPath, store.snapshotPath())
+	if err != nil {
+		return fmt.Errorf("cannot replace snapshot: %w", err)
+	}
+
+	err = syncDir(store.dir)
+	if err != nil {
+		return err
+	}
+
+	err = store.wal.Truncate(0)
+	if err != nil {
+		return fmt.Errorf("cannot truncate log: %w", err)
+	}
+	store.size = 0
+
+	_, err = store.wal.Seek(0, io.SeekStart)
+	if err != nil {
+		// the next records would be written after a hole
+		store.failed = err
+		return fmt.Errorf("cannot seek log: %w", err)
+	}
+
+	err = store.wal.Sync()
+	if err != nil {
+		return fmt.Errorf("cannot sync log: %w", err)
+	}
+
+	store.records = 0
+	return nil
+}
+
+// replay applies every record of a file to memory and returns the size of its valid part.
+// A torn record at the end is expected in the log after a crash, but never in a snapshot
+// nor before the last bytes of the log.
+func (store *FileLaptopStore) replay(path string, isLog bool) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	reader := bufio.NewReader(file)
+	var valid int64
+
+	for {
+		kind, laptops, size, err := decodeRecord(reader)
+		if err == io.EOF {
+			return valid, nil
+		}
+		if errors.Is(err, errTornRecord) && isLog {
+			// 只有文件最后的记录可能没有写完整, 之后的记录会丢失
+			tail, tailErr := isTornTail(path, valid, size, stat.Size())
+			if tailErr != nil {
+				return valid, tailErr
+			}
+			if !tail {
+				return valid, fmt.Errorf("corrupted record at offset %d of %s: %w", valid, path, err)
+			}
+			log.Printf("ignore torn record at offset %d of %s", valid, path)
+			return valid, nil
+		}
+		if err != nil {
+			return valid, err
+		}
+
+		for _, laptop := range laptops {
+			err = store.apply(kind, laptop)
+			if err != nil {
+				return valid, err
+			}
+		}
+
+		valid += size
+		if isLog {
+			store.records++
+		}
+	}
+}
+
+// isTornTail returns true if the torn record at offset is the end of the file: it would reach the end of the file,
+// or its header is invalid and the rest of the file is zeros, like a file extended by a crash before it was written
+func isTornTail(path string, offset int64, recordSize int64, fileSize int64) (bool, error) {
+	if recordSize > 0 {
+		return offset+recordSize >= fileSize, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return false, err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if b != 0 {
+			return false, nil
+		}
+	}
+}
+
+func (store *FileLaptopStore) apply(kind byte, laptop *pd.Laptop) error {
+	switch kind {
+	case recordSave, recordBatch:
+		err := store.memory.Save(laptop)
+		if errors.Is(err, ErrAlreadyExists) {
+			// a laptop of the snapshot can appear again in a log that was not truncated
+			return nil
+		}
+		return err
+	case recordUpdate:
+		err := store.memory.Update(laptop, "")
+		if errors.Is(err, ErrNotFound) {
+			// the laptop was deleted later in the log, replaying the rest deletes it again
+			return store.memory.Save(laptop)
+		}
+		return err
+	case recordDelete:
+		err := store.memory.Delete(laptop.Id, "")
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown record kind: %d", kind)
+	}
+}
+
+func encodeRecord(kind byte, laptop *pd.Laptop) ([]byte, error) {
+	data, err := proto.Marshal(laptop)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal laptop: %w", err)
+	}
+
+	return frameRecord(kind, data), nil
+}
+
+// encodeBatchRecord puts the laptops in one record, each one prefixed with its length as a uvarint
+func encodeBatchRecord(laptops []*pd.Laptop) ([]byte, error) {
+	var data []byte
+	for _, laptop := range laptops {
+		laptopData, err := proto.Marshal(laptop)
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal laptop: %w", err)
+		}
+		data = protowire.AppendBytes(data, laptopData)
+	}
+	return frameRecord(recordBatch, data), nil
+}
+
+// frameRecord adds the kind, the length and the checksum to the data of a record
+func frameRecord(kind byte, data []byte) []byte {
+	payload := make([]byte, 0, len(data)+1)
+	payload = append(payload, kind)
+	payload = append(payload, data...)
+
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	return append(record, payload...)
+}
+
+// decodeRecord reads the laptops of the next record, it returns errTornRecord if the record is incomplete or corrupted.
+// With errTornRecord the size is the one given by the header, or 0 if the header is invalid.
+func decodeRecord(reader io.Reader) (byte, []*pd.Laptop, int64, error) {
+	header := make([]byte, recordHeaderSize)
+	n, err := io.ReadFull(reader, header)
+	if err == io.EOF {
+		return 0, nil, 0, io.EOF
+	}
+	if err != nil {
+		return 0, nil, recordHeaderSize, fmt.Errorf("%w: short header of %d bytes", errTornRecord, n)
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	checksum := binary.BigEndian.Uint32(header[4:8])
+	if size == 0 || size > maxRecordSize {
+		return 0, nil, 0, fmt.Errorf("%w: invalid record size %d", errTornRecord, size)
+	}
+
+	recordSize := int64(recordHeaderSize) + int64(size)
+	payload := make([]byte, size)
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		return 0, nil, recordSize, fmt.Errorf("%w: short payload", errTornRecord)
+	}
+
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return 0, nil, recordSize, fmt.Errorf("%w: checksum mismatch", errTornRecord)
+	}
+
+	kind, data := payload[0], payload[1:]
+	var laptops []*pd.Laptop
+	if kind == recordBatch {
+		for len(data) > 0 {
+			laptopData, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return 0, nil, 0, fmt.Errorf("cannot read laptop of batch: %w", protowire.ParseError(n))
+			}
+			data = data[n:]
+
+			laptop := &pd.Laptop{}
+			err = proto.Unmarshal(laptopData, laptop)
+			if err != nil {
+				return 0, nil, 0, fmt.Errorf("cannot unmarshal laptop: %w", err)
+			}
+			laptops = append(laptops, laptop)
+		}
+	} else {
+		laptop := &pd.Laptop{}
+		err = proto.Unmarshal(data, laptop)
+		if err != nil {
+			return 0, nil, 0, fmt.Errorf("cannot unmarshal laptop: %w", err)
+		}
+		laptops = append(laptops, laptop)
+	}
+
+	return kind, laptops, recordSize, nil
+}
+
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("cannot open folder: %w", err)
+	}
+	defer file.Close()
+
+	err = file.Sync()
+	if err != nil {
+		return fmt.Errorf("cannot sync folder: %w", err)
+	}
+	return nil
+}
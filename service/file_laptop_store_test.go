@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"os"
+	"pc_book/pd"
+	"pc_book/sample"
+	"testing"
+)
+
+// failingLogFile writes only the first half of a record when failWrite is set, or fails the next sync when failSync is set
+type failingLogFile struct {
+	*os.File
+	failWrite bool
+	failSync  bool
+}
+
+func (file *failingLogFile) Write(p []byte) (int, error) {
+	if file.failWrite {
+		n, _ := file.File.Write(p[:len(p)/2])
+		return n, errors.New("disk full")
+	}
+	return file.File.Write(p)
+}
+
+func (file *failingLogFile) Sync() error {
+	if file.failSync {
+		file.failSync = false
+		return errors.New("i/o error")
+	}
+	return file.File.Sync()
+}
+
+func TestFileLaptopStoreWriteFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := NewFileLaptopStore(dir)
+	require.NoError(t, err)
+
+	wal := &failingLogFile{File: store.wal.(*os.File)}
+	store.wal = wal
+
+	laptop1 := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop1))
+
+	// the failed records are removed from the log, the next ones follow the last complete record
+	wal.failWrite = true
+	require.Error(t, store.Save(sample.NewLaptop()))
+	wal.failWrite = false
+	wal.failSync = true
+	require.Error(t, store.Save(sample.NewLaptop()))
+
+	laptop2 := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop2))
+	require.NoError(t, store.Close())
+
+	store, err = NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	count := 0
+	err = store.memory.forEach(func(laptop *pd.Laptop) error {
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	for _, id := range []string{laptop1.Id, laptop2.Id} {
+		found, err := store.Find(id)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+)
+
+// imageHeaderSize is the number of bytes needed to detect the format of an image
+const imageHeaderSize = 12
+
+// imageFormat is an image format accepted by UploadImage
+type imageFormat struct {
+	name string
+	// extension is the extension of the stored files, it never comes from the client
+	extension string
+	mimeType  string
+	// aliases are the image types a client can declare for this format
+	aliases []string
+	match   func(header []byte) bool
+}
+
+// imageFormats is the allow-list of the formats of uploaded images
+var imageFormats = []*imageFormat{
+	{
+		name:      "PNG",
+		extension: ".png",
+		mimeType:  "image/png",
+		aliases:   []string{"png"},
+		match: func(header []byte) bool {
+			return bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n"))
+		},
+	},
+	{
+		name:      "JPEG",
+		extension: ".jpg",
+		mimeType:  "image/jpeg",
+		aliases:   []string{"jpg", "jpeg"},
+		match: func(header []byte) bool {
+			return bytes.HasPrefix(header, []byte("\xff\xd8\xff"))
+		},
+	},
+	{
+		name:      "GIF",
+		extension: ".gif",
+		mimeType:  "image/gif",
+		aliases:   []string{"gif"},
+		match: func(header []byte) bool {
+			return bytes.HasPrefix(header, []byte("GIF87a")) || bytes.HasPrefix(header, []byte("GIF89a"))
+		},
+	},
+	{
+		name:      "WebP",
+		extension: ".webp",
+		mimeType:  "image/webp",
+		aliases:   []string{"webp"},
+		match: func(header []byte) bool {
+			// RIFF, the size of the file, then WEBP
+			return len(header) >= 12 && bytes.HasPrefix(header, []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP"))
+		},
+	},
+}
+
+// detectImageFormat returns the format of an image from its first bytes, or nil if it is not allowed
+func detectImageFormat(header []byte) *imageFormat {
+	for _, format := range imageFormats {
+		if format.match(header) {
+			return format
+		}
+	}
+	return nil
+}
+
+// declaredImageFormat returns the format of an image type declared by a client, like .png, jpeg or image/webp.
+// It returns nil if the type is not allowed.
+func declaredImageFormat(imageType string) *imageFormat {
+	imageType = strings.ToLower(strings.TrimSpace(imageType))
+	for _, format := range imageFormats {
+		if imageType == format.mimeType {
+			return format
+		}
+		for _, alias := range format.aliases {
+			if imageType == alias || imageType == "."+alias {
+				return format
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sidecarSuffix ends the name of the file that holds the info of an image, next to the image file
+const sidecarSuffix = ".info.json"
+
+// imageSidecar is the content of a sidecar file.
+// The image file is relative to the folder, so that the folder can be moved.
+// It is a blob like blobs/<checksum>, or the name of a file of the folder for the images saved before the blobs.
+type imageSidecar struct {
+	ID        string    `json:"id"`
+	LaptopID  string    `json:"laptop_id"`
+	Type      string    `json:"type"`
+	File      string    `json:"file"`
+	Size      int64     `json:"size"`
+	Checksum  string    `json:"checksum"`
+	CreatedAt time.Time `json:"created_at"`
+	// Renditions 的文件也是相对路径
+	Renditions []sidecarRendition `json:"renditions,omitempty"`
+}
+
+type sidecarRendition struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	File   string `json:"file"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Size   int64  `json:"size"`
+}
+
+// ImageReport lists the problems found by DiskImageStore.Reconcile and S3ImageStore.Reconcile
+type ImageReport struct {
+	// OrphanFiles are the files of the folder, or the objects of the bucket, that belong to no image
+	OrphanFiles []string
+	// BrokenImages are the ids of the images whose file is missing or doesn't have the recorded size,
+	// or whose laptop object is missing from the bucket
+	BrokenImages []string
+	// OrphanImages are the ids of the images whose laptop doesn't exist anymore,
+	// the images adopted from files without info have no laptop and are never orphans
+	OrphanImages []string
+	// Cleaned is true if the files and images of the report have been removed
+	Cleaned bool
+}
+
+// Empty returns true if no problem was found
+func (report *ImageReport) Empty() bool {
+	return len(report.OrphanFiles) == 0 && len(report.BrokenImages) == 0 && len(report.OrphanImages) == 0
+}
+
+func (store *DiskImageStore) sidecarPath(imageID string) string {
+	return filepath.Join(store.imageFolder, imageID+sidecarSuffix)
+}
+
+// newImageSidecar returns the sidecar of an image, file returns the file of a path
+func newImageSidecar(info *ImageInfo, file func(path string) string) imageSidecar {
+	sidecar := imageSidecar{
+		ID:        info.ID,
+		LaptopID:  info.LaptopID,
+		Type:      info.Type,
+		File:      file(info.Path),
+		Size:      info.Size,
+		Checksum:  info.Checksum,
+		CreatedAt: info.CreatedAt,
+	}
+	for _, rendition := range info.Renditions {
+		sidecar.Renditions = append(sidecar.Renditions, sidecarRendition{
+			Name:   rendition.Name,
+			Type:   rendition.Type,
+			File:   file(rendition.Path),
+			Width:  rendition.Width,
+			Height: rendition.Height,
+			Size:   rendition.Size,
+		})
+	}
+	return sidecar
+}
+
+// imageInfo returns the info of the sidecar, path returns the path of a file or false if the file is invalid
+func (sidecar *imageSidecar) imageInfo(path func(file string) (string, bool)) (*ImageInfo, error) {
+	imagePath, ok := path(sidecar.File)
+	if !ok {
+		return nil, fmt.Errorf("invalid image file %q", sidecar.File)
+	}
+
+	info := &ImageInfo{
+		ID:        sidecar.ID,
+		LaptopID:  sidecar.LaptopID,
+		Type:      sidecar.Type,
+		Path:      imagePath,
+		Size:      sidecar.Size,
+		Checksum:  sidecar.Checksum,
+		CreatedAt: sidecar.CreatedAt,
+	}
+	for _, rendition := range sidecar.Renditions {
+		renditionPath, ok := path(rendition.File)
+		if !ok {
+			return nil, fmt.Errorf("invalid image file %q", rendition.File)
+		}
+		info.Renditions = append(info.Renditions, &ImageRendition{
+			Name:   rendition.Name,
+			Type:   rendition.Type,
+			Path:   renditionPath,
+			Width:  rendition.Width,
+			Height: rendition.Height,
+			Size:   rendition.Size,
+		})
+	}
+	return info, nil
+}
+
+// writeImageSidecar writes the info of an image to a temporary file, then renames it to path
+func writeImageSidecar(path string, info *ImageInfo) error {
+	folder := filepath.Dir(path)
+	sidecar := newImageSidecar(info, func(path string) string {
+		return sidecarFile(folder, path)
+	})
+
+	data, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal image info: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot write image info: %w", err)
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("cannot write image info: %w", err)
+	}
+	return nil
+}
+
+// sidecarFile returns the path of a file relative to the folder, with slashes
+func sidecarFile(folder string, path string) string {
+	rel, err := filepath.Rel(folder, path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// sidecarFilePath returns the path of a file of a sidecar, or false if it is not a file of the folder or of the blob folder
+func sidecarFilePath(folder string, file string) (string, bool) {
+	name := strings.TrimPrefix(file, blobFolder+"/")
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	return filepath.Join(folder, filepath.FromSlash(file)), true
+}
+
+// load reads the info of every sidecar of the folder, a sidecar that cannot be read is left to Reconcile
+func (store *DiskImageStore) load() error {
+	entries, err := ioutil.ReadDir(store.imageFolder)
+	if err != nil {
+		return fmt.Errorf("cannot read image folder: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sidecarSuffix) {
+			continue
+		}
+
+		path := filepath.Join(store.imageFolder, entry.Name())
+		info, err := store.readSidecar(path)
+		if err != nil {
+			log.Printf("ignore image info %s: %v", path, err)
+			continue
+		}
+		store.images[info.ID] = info
+		for _, path := range info.files() {
+			store.refs[path]++
+		}
+	}
+
+	// 旧版本保存的图片没有sidecar, 文件名是<id><type>
+	for _, entry := range entries {
+		path := filepath.Join(store.imageFolder, entry.Name())
+		if entry.IsDir() || store.refs[path] > 0 {
+			continue
+		}
+
+		info, err := store.adoptImage(path, entry)
+		if err != nil {
+			log.Printf("cannot adopt image file %s: %v", path, err)
+			continue
+		}
+		if info != nil {
+			log.Printf("adopt image file %s without info as image %s", path, info.ID)
+		}
+	}
+	return nil
+}
+
+// adoptImage writes the sidecar of an image file saved before the sidecars, and adds the image.
+// Its laptop is unknown, the file was named by the image id and its type. It returns nil if the file is not such an image.
+func (store *DiskImageStore) adoptImage(path string, entry os.FileInfo) (*ImageInfo, error) {
+	imageType := filepath.Ext(entry.Name())
+	id := strings.TrimSuffix(entry.Name(), imageType)
+	parsed, err := uuid.Parse(id)
+	if err != nil || parsed.String() != id || !isImageExtension(imageType) || store.images[id] != nil {
+		return nil, nil
+	}
+
+	// a sidecar that cannot be read is left to Reconcile
+	_, err = os.Stat(store.sidecarPath(id))
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ImageInfo{
+		ID:        id,
+		Type:      imageType,
+		Path:      path,
+		Size:      size,
+		Checksum:  hex.EncodeToString(hash.Sum(nil)),
+		CreatedAt: entry.ModTime(),
+	}
+	err = writeImageSidecar(store.sidecarPath(id), info)
+	if err != nil {
+		return nil, err
+	}
+
+	store.images[id] = info
+	store.refs[path]++
+	return info, nil
+}
+
+func (store *DiskImageStore) readSidecar(path string) (*ImageInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sidecar imageSidecar
+	err = json.Unmarshal(data, &sidecar)
+	if err != nil {
+		return nil, err
+	}
+
+	// the name of the sidecar must match its content
+	if sidecar.ID == "" || sidecar.ID+sidecarSuffix != filepath.Base(path) {
+		return nil, fmt.Errorf("invalid image info")
+	}
+	return sidecar.imageInfo(func(file string) (string, bool) {
+		return sidecarFilePath(store.imageFolder, file)
+	})
+}
+
+// Reconcile compares the images with the files of the folder and, when laptopExists is not nil, with the laptops.
+// If clean is true the orphan files, the broken images and the orphan images are removed.
+func (store *DiskImageStore) Reconcile(laptopExists func(laptopID string) (bool, error), clean bool) (*ImageReport, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	report := &ImageReport{Cleaned: clean}
+	known := make(map[string]bool)
+	var removed []string
+
+	for path := range store.refs {
+		known[path] = true
+	}
+	for id, info := range store.images {
+		known[store.sidecarPath(id)] = true
+
+		stat, err := os.Stat(info.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("cannot check image file: %w", err)
+		}
+		if err != nil || stat.Size() != info.Size {
+			report.BrokenImages = append(report.BrokenImages, id)
+			removed = append(removed, id)
+			continue
+		}
+
+		// the laptop of an adopted image is unknown
+		if laptopExists != nil && info.LaptopID != "" {
+			exists, err := laptopExists(info.LaptopID)
+			if err != nil {
+				return nil, fmt.Errorf("cannot find laptop of image: %w", err)
+			}
+			if !exists {
+				report.OrphanImages = append(report.OrphanImages, id)
+				removed = append(removed, id)
+			}
+		}
+	}
+
+	// 检查image目录和blob目录中的文件, 名称相对于image目录. upload目录只有上传中的文件
+	for _, folder := range []string{"", blobFolder} {
+		entries, err := ioutil.ReadDir(filepath.Join(store.imageFolder, folder))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read image folder: %w", err)
+		}
+		for _, entry := range entries {
+			path := filepath.Join(store.imageFolder, folder, entry.Name())
+			if entry.IsDir() || known[path] || store.isSaving(entry.Name()) {
+				continue
+			}
+			report.OrphanFiles = append(report.OrphanFiles, sidecarFile(store.imageFolder, path))
+		}
+	}
+
+	sort.Strings(report.OrphanFiles)
+	sort.Strings(report.BrokenImages)
+	sort.Strings(report.OrphanImages)
+
+	if !clean {
+		return report, nil
+	}
+
+	for _, name := range report.OrphanFiles {
+		err := os.Remove(filepath.Join(store.imageFolder, filepath.FromSlash(name)))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("cannot remove orphan file: %w", err)
+		}
+	}
+	for _, id := range removed {
+		err := os.Remove(store.sidecarPath(id))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("cannot remove image: %w", err)
+		}
+
+		// 其他image还在使用的文件不会被删除
+		info := store.images[id]
+		delete(store.images, id)
+		err = store.releaseImage(info)
+		if err != nil {
+			return nil, fmt.Errorf("cannot remove image: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// isSaving returns true if the file belongs to an image that is being saved, the mutex must be held
+func (store *DiskImageStore) isSaving(name string) bool {
+	for id := range store.saving {
+		if strings.HasPrefix(name, id) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// imageRenditionSizes are the renditions generated for each image, by the maximum of their width and height
+var imageRenditionSizes = []struct {
+	name string
+	size int
+}{
+	{"small", 160},
+	{"medium", 480},
+	{"large", 1024},
+}
+
+// maxRenditionPixels protects against decoding a huge image in memory, larger images have no rendition.
+// A decoded image of 16 MP takes up to 64 MB.
+const maxRenditionPixels = 16_000_000
+
+// renditionSlots limits the images decoded at the same time, the other uploads wait for a slot
+var renditionSlots = make(chan struct{}, 2)
+
+// errNoRendition is returned for an image that cannot be decoded by the standard library, like WebP
+var errNoRendition = errors.New("image format has no rendition")
+
+// addImageRenditions decodes an image and adds a resized rendition for each size of imageRenditionSizes.
+// JPEG images have JPEG renditions, the others have PNG renditions.
+func addImageRenditions(ctx context.Context, store ImageStore, info *ImageInfo) error {
+	if info.Type != ".png" && info.Type != ".jpg" && info.Type != ".gif" {
+		return errNoRendition
+	}
+
+	select {
+	case renditionSlots <- struct{}{}:
+		defer func() { <-renditionSlots }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	src, err := decodeImage(store, info.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, size := range imageRenditionSizes {
+		img := resizeImage(src, size.size)
+
+		data := &bytes.Buffer{}
+		rendition := ImageRendition{
+			Name:   size.name,
+			Width:  img.Bounds().Dx(),
+			Height: img.Bounds().Dy(),
+		}
+		if info.Type == ".jpg" {
+			rendition.Type = ".jpg"
+			err = jpeg.Encode(data, img, &jpeg.Options{Quality: 85})
+		} else {
+			rendition.Type = ".png"
+			err = png.Encode(data, img)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot encode %s rendition: %w", size.name, err)
+		}
+
+		err = store.AddRendition(info.ID, rendition, data)
+		if err != nil {
+			return fmt.Errorf("cannot save %s rendition: %w", size.name, err)
+		}
+	}
+	return nil
+}
+
+// decodeImage checks the size of an image before decoding it
+func decodeImage(store ImageStore, imageID string) (image.Image, error) {
+	file, err := store.Open(imageID, "")
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(file)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxRenditionPixels {
+		return nil, fmt.Errorf("image size %dx%d is not supported", config.Width, config.Height)
+	}
+
+	file, err = store.Open(imageID, "")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode image: %w", err)
+	}
+	return img, nil
+}
+
+// resizeImage scales an image down so that its width and height are at most size, keeping its aspect ratio.
+// Each pixel is the average of the pixels of the source it covers. Smaller images keep their size.
+func resizeImage(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	width, height := srcWidth, srcHeight
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, maxInt(1, srcHeight*size/srcWidth)
+		} else {
+			width, height = maxInt(1, srcWidth*size/srcHeight), size
+		}
+	}
+
+	// 只转换每行目标像素覆盖的源图像行, 不复制整个图像. RGBA图像直接读取像素
+	rgba, isRGBA := src.(*image.RGBA)
+	var strip *image.RGBA
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, maxInt((y+1)*srcHeight/height, y*srcHeight/height+1)
+
+		var rows *image.RGBA
+		if isRGBA {
+			rows = rgba.SubImage(image.Rect(bounds.Min.X, bounds.Min.Y+y0, bounds.Max.X, bounds.Min.Y+y1)).(*image.RGBA)
+		} else {
+			if strip == nil || strip.Rect.Dy() < y1-y0 {
+				strip = image.NewRGBA(image.Rect(0, 0, srcWidth, y1-y0))
+			}
+			rows = strip.SubImage(image.Rect(0, 0, srcWidth, y1-y0)).(*image.RGBA)
+			draw.Draw(rows, rows.Bounds(), src, image.Pt(bounds.Min.X, bounds.Min.Y+y0), draw.Src)
+		}
+
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, maxInt((x+1)*srcWidth/width, x*srcWidth/width+1)
+
+			var sum [4]int
+			for sy := 0; sy < y1-y0; sy++ {
+				row := rows.Pix[sy*rows.Stride+x0*4 : sy*rows.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+
+			count := (x1 - x0) * (y1 - y0)
+			offset := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[offset+i] = uint8(sum[i] / count)
+			}
+		}
+	}
+	return dst
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestResizeImage(t *testing.T) {
+	t.Parallel()
+
+	// left half black, right half white
+	src := image.NewGray(image.Rect(10, 10, 410, 110))
+	for y := 10; y < 110; y++ {
+		for x := 210; x < 410; x++ {
+			src.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+
+	img := resizeImage(src, 100)
+	require.Equal(t, image.Rect(0, 0, 100, 25), img.Bounds())
+	require.Equal(t, color.RGBA{0, 0, 0, 255}, img.RGBAAt(0, 0))
+	require.Equal(t, color.RGBA{255, 255, 255, 255}, img.RGBAAt(99, 24))
+
+	// a pixel covering both halves is their average
+	img = resizeImage(src, 1)
+	require.Equal(t, image.Rect(0, 0, 1, 1), img.Bounds())
+	require.Equal(t, color.RGBA{127, 127, 127, 255}, img.RGBAAt(0, 0))
+
+	// a small image keeps its size
+	img = resizeImage(src, 1000)
+	require.Equal(t, image.Rect(0, 0, 400, 100), img.Bounds())
+	require.Equal(t, color.RGBA{255, 255, 255, 255}, img.RGBAAt(399, 0))
+
+	tall := image.NewGray(image.Rect(0, 0, 50, 200))
+	require.Equal(t, image.Rect(0, 0, 25, 100), resizeImage(tall, 100).Bounds())
+}
+
+func TestResizeRGBAImage(t *testing.T) {
+	t.Parallel()
+
+	// the pixels of a RGBA image are read in place, also from a sub image
+	full := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			if x >= 200 {
+				full.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+			} else if x >= 100 {
+				full.SetRGBA(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	src := full.SubImage(image.Rect(100, 0, 300, 100))
+
+	img := resizeImage(src, 20)
+	require.Equal(t, image.Rect(0, 0, 20, 10), img.Bounds())
+	require.Equal(t, color.RGBA{0, 0, 255, 255}, img.RGBAAt(0, 0))
+	require.Equal(t, color.RGBA{255, 0, 0, 255}, img.RGBAAt(19, 9))
+	require.Equal(t, color.RGBA{0, 0, 0, 0}, full.RGBAAt(0, 0), "the source should not change")
+}
+
+func TestAddImageRenditionsWaitsForSlot(t *testing.T) {
+	// not parallel, every slot is taken
+	for i := 0; i < cap(renditionSlots); i++ {
+		renditionSlots <- struct{}{}
+	}
+	defer func() {
+		for i := 0; i < cap(renditionSlots); i++ {
+			<-renditionSlots
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := addImageRenditions(ctx, nil, &ImageInfo{ID: "image", Type: ".png"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
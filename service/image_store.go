@@ -1,75 +1,452 @@
 package service
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/google/uuid"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 )
 
 // ImageStore is a interface to store laptop image
 type ImageStore interface {
-	// Save saves a new laptop image to the store
-	Save(laptopID string, imageType string, imageData bytes.Buffer) (string, error)
+	// Save saves a new laptop image to the store, reading its data until io.EOF.
+	// Nothing is stored if reading the data fails.
+	Save(laptopID string, imageType string, imageData io.Reader) (*ImageInfo, error)
+	// Find returns the info of an image, or nil if it doesn't exist
+	Find(imageID string) (*ImageInfo, error)
+	// List returns the images of a laptop, from the oldest
+	List(laptopID string) ([]*ImageInfo, error)
+	// AddRendition saves a resized version of an image, replacing the rendition of the same name.
+	// The store sets the path and the size of the rendition.
+	AddRendition(imageID string, rendition ImageRendition, data io.Reader) error
+	// Open opens the data of an image, or of its rendition if rendition is not empty.
+	// It returns ErrNotFound if the image or the rendition doesn't exist.
+	Open(imageID string, rendition string) (io.ReadCloser, error)
+	// Delete removes an image and its data, it returns ErrNotFound if the image doesn't exist
+	Delete(imageID string) error
 }
 
-// DiskImageStore stores image on the disk and its info on memory
+// blobFolder is the sub folder of the image data, each file is named by the hex SHA-256 of its data
+const blobFolder = "blobs"
+
+// DiskImageStore stores image on the disk and its info on memory.
+// The info of each image is also written in a sidecar file, to load it again after a restart.
+// The data is content-addressed: images and renditions with the same data share one file of the blob folder.
 type DiskImageStore struct {
 	mutex sync.RWMutex
 	imageFolder string
 	images map[string]*ImageInfo
+	// refs 每个文件被多少个image和rendition引用, 由images计算
+	refs map[string]int
+	// uploads 可以继续的上传, 重启后不保留
+	uploads map[string]*diskUpload
+	// saving 正在保存的image id和次数, Reconcile不能删除它们的文件
+	saving map[string]int
 }
 
 // ImageInfo contains information of laptop image
 type ImageInfo struct {
+	ID string
 	LaptopID string
 	Type string
 	Path string
+	Size int64
+	// Checksum is the hex SHA-256 of the image data, it is also the name of its blob
+	Checksum string
+	CreatedAt time.Time
+	Renditions []*ImageRendition
+}
+
+// ImageRendition is a resized version of an image
+type ImageRendition struct {
+	Name string
+	Type string
+	Path string
+	Width int
+	Height int
+	Size int64
 }
 
-// NewDiskImageStore return a new DiskImageStore
+// clone returns a copy of the info that can be modified
+func (info *ImageInfo) clone() *ImageInfo {
+	other := *info
+	other.Renditions = make([]*ImageRendition, len(info.Renditions))
+	for i, rendition := range info.Renditions {
+		r := *rendition
+		other.Renditions[i] = &r
+	}
+	return &other
+}
+
+// files returns the paths of the image file and of the files of its renditions
+func (info *ImageInfo) files() []string {
+	paths := []string{info.Path}
+	for _, rendition := range info.Renditions {
+		paths = append(paths, rendition.Path)
+	}
+	return paths
+}
+
+// rendition returns the rendition with the name, or nil
+func (info *ImageInfo) rendition(name string) *ImageRendition {
+	for _, rendition := range info.Renditions {
+		if rendition.Name == name {
+			return rendition
+		}
+	}
+	return nil
+}
+
+// NewDiskImageStore return a new DiskImageStore with the images found in the folder
 // 生成一个硬盘存储图片的对象
-func NewDiskImageStore(imageFolder string) *DiskImageStore {
-	return &DiskImageStore{
+func NewDiskImageStore(imageFolder string) (*DiskImageStore, error) {
+	err := os.MkdirAll(filepath.Join(imageFolder, blobFolder), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create image folder: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Join(imageFolder, uploadFolder), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create upload folder: %w", err)
+	}
+
+	store := &DiskImageStore{
 		imageFolder: imageFolder,
 		images: make(map[string]*ImageInfo),
+		refs: make(map[string]int),
+		uploads: make(map[string]*diskUpload),
+		saving: make(map[string]int),
+	}
+
+	err = store.load()
+	if err != nil {
+		return nil, err
+	}
+
+	// the uploads are resumed after a restart, until ExpireUploads removes them
+	err = store.loadUploads()
+	if err != nil {
+		return nil, err
 	}
+	return store, nil
 }
 
-// Save save laptop image to disk and keep info to memory
-func (store *DiskImageStore) Save(laptopID string, imageType string, imageData bytes.Buffer) (string, error) {
+// Save save laptop image to disk and keep info to memory.
+// The data is written to a temporary file, which becomes the blob of the image once the whole data is written.
+func (store *DiskImageStore) Save(laptopID string, imageType string, imageData io.Reader) (*ImageInfo, error) {
+	// imageType是文件扩展名, 不能包含路径
+	if !isImageExtension(imageType) {
+		return nil, fmt.Errorf("invalid image type %q", imageType)
+	}
+
 	// 生成uuid，作为image的名称
 	imageId, err := uuid.NewUUID()
 	if err != nil {
-		return "", fmt.Errorf("cannot generate image id: %w", err)
+		return nil, fmt.Errorf("cannot generate image id: %w", err)
 	}
-	// 构造image存储路径
-	imagePath := fmt.Sprintf("%s/%s%s", store.imageFolder, imageId, imageType)
+	id := imageId.String()
+	store.startSaving(id)
+	defer store.endSaving(id)
 
-	// 创建image文件
-	file, err := os.Create(imagePath)
+	tmpPath, size, checksum, err := store.writeTempFile(id, imageData)
 	if err != nil {
-		return "", fmt.Errorf("cannot create image file: %w", err)
+		return nil, err
 	}
+	return store.addImage(id, laptopID, imageType, tmpPath, size, checksum)
+}
+
+// addImage adds an image whose data is in a temporary file, which becomes its blob
+func (store *DiskImageStore) addImage(id string, laptopID string, imageType string, tmpPath string, size int64, checksum string) (*ImageInfo, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
 
-	// 将上传过来的image保存到创建的文件中
-	_, err = imageData.WriteTo(file)
+	// 相同内容的image共用一个文件
+	imagePath, err := store.addBlob(tmpPath, checksum)
 	if err != nil {
-		return "", fmt.Errorf("cannot write image file: %w", err)
+		return nil, err
+	}
+
+	info := &ImageInfo{
+		ID: id,
+		LaptopID: laptopID,
+		Type: imageType,
+		Path: imagePath,
+		Size: size,
+		Checksum: checksum,
+		CreatedAt: time.Now(),
+	}
+
+	// 先写sidecar, 之后重启也能找到这个image
+	err = writeImageSidecar(store.sidecarPath(id), info)
+	if err != nil {
+		store.releaseFile(imagePath)
+		return nil, err
 	}
 
 	// image保存成功后，将info存到内存信息中 map
+	store.images[id] = info
+	return info.clone(), nil
+}
+
+// writeTempFile writes the data to a temporary file of the blob folder, it returns its path, size and hex SHA-256.
+// The temporary file starts with name, the id of the image being saved, so that Reconcile doesn't remove it.
+func (store *DiskImageStore) writeTempFile(name string, data io.Reader) (string, int64, string, error) {
+	tmpPath := filepath.Join(store.imageFolder, blobFolder, name+".tmp")
+
+	// 创建临时文件
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("cannot create image file: %w", err)
+	}
+
+	// 将上传过来的image保存到临时文件中, 同时计算checksum
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", 0, "", fmt.Errorf("cannot write image file: %w", err)
+	}
+	return tmpPath, size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// addBlob moves a temporary file to the blob of its digest and adds a reference to the blob.
+// If the blob is already used, the temporary file is removed instead. The mutex must be held.
+func (store *DiskImageStore) addBlob(tmpPath string, digest string) (string, error) {
+	path := filepath.Join(store.imageFolder, blobFolder, digest)
+	if store.refs[path] > 0 {
+		os.Remove(tmpPath)
+	} else {
+		// 替换没有引用的旧文件
+		err := os.Rename(tmpPath, path)
+		if err != nil {
+			os.Remove(tmpPath)
+			return "", fmt.Errorf("cannot write image file: %w", err)
+		}
+	}
+
+	store.refs[path]++
+	return path, nil
+}
+
+// releaseFile removes a reference to a file, the file is removed with its last reference. The mutex must be held.
+func (store *DiskImageStore) releaseFile(path string) error {
+	store.refs[path]--
+	if store.refs[path] > 0 {
+		return nil
+	}
+	delete(store.refs, path)
+
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove image file: %w", err)
+	}
+	return nil
+}
+
+// releaseImage removes the references of the files of an image, the mutex must be held
+func (store *DiskImageStore) releaseImage(info *ImageInfo) error {
+	var err error
+	for _, path := range info.files() {
+		// 继续释放其他文件, 返回第一个错误
+		if releaseErr := store.releaseFile(path); err == nil {
+			err = releaseErr
+		}
+	}
+	return err
+}
+
+// AddRendition writes the rendition to its blob, then records it in the sidecar of the image
+func (store *DiskImageStore) AddRendition(imageID string, rendition ImageRendition, data io.Reader) error {
+	if !isImageExtension(rendition.Type) || !isRenditionName(rendition.Name) {
+		return fmt.Errorf("invalid rendition %q of type %q", rendition.Name, rendition.Type)
+	}
+
+	info, err := store.Find(imageID)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return ErrNotFound
+	}
+
+	store.startSaving(imageID)
+	defer store.endSaving(imageID)
+
+	tmpPath, size, digest, err := store.writeTempFile(imageID+"_"+rendition.Name, data)
+	if err != nil {
+		return err
+	}
+	rendition.Size = size
+
 	store.mutex.Lock()
 	defer store.mutex.Unlock()
 
-	store.images[imageId.String()] = &ImageInfo{
-		LaptopID: laptopID,
-		Type: imageType,
-		Path: imagePath,
+	// image在写文件的时候被删除了
+	if store.images[imageID] == nil {
+		os.Remove(tmpPath)
+		return ErrNotFound
+	}
+
+	rendition.Path, err = store.addBlob(tmpPath, digest)
+	if err != nil {
+		return err
+	}
+
+	info = store.images[imageID].clone()
+	var replacedPath string
+	if replaced := info.rendition(rendition.Name); replaced != nil {
+		replacedPath = replaced.Path
+		*replaced = rendition
+	} else {
+		info.Renditions = append(info.Renditions, &rendition)
+	}
+
+	err = writeImageSidecar(store.sidecarPath(imageID), info)
+	if err != nil {
+		store.releaseFile(rendition.Path)
+		return err
+	}
+	store.images[imageID] = info
+
+	if replacedPath != "" {
+		return store.releaseFile(replacedPath)
 	}
-	// 返回imageID给上传image的用户
-	return imageId.String(), nil
+	return nil
+}
+
+// isRenditionName returns true if name is made of lower case letters
+func isRenditionName(name string) bool {
+	if name == "" || len(name) > 20 {
+		return false
+	}
+	for _, c := range name {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isImageExtension returns true if imageType is a dot followed by lower case letters and digits
+func isImageExtension(imageType string) bool {
+	if len(imageType) < 2 || len(imageType) > 10 || imageType[0] != '.' {
+		return false
+	}
+	for _, c := range imageType[1:] {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func (store *DiskImageStore) startSaving(imageID string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.saving[imageID]++
+}
+
+func (store *DiskImageStore) endSaving(imageID string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.saving[imageID]--
+	if store.saving[imageID] == 0 {
+		delete(store.saving, imageID)
+	}
+}
+
+// Find returns a copy of the info of an image, or nil if it doesn't exist
+func (store *DiskImageStore) Find(imageID string) (*ImageInfo, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	info := store.images[imageID]
+	if info == nil {
+		return nil, nil
+	}
+	return info.clone(), nil
+}
+
+// List returns the images of a laptop, from the oldest
+func (store *DiskImageStore) List(laptopID string) ([]*ImageInfo, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	var images []*ImageInfo
+	for _, info := range store.images {
+		if info.LaptopID == laptopID {
+			images = append(images, info.clone())
+		}
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		if !images[i].CreatedAt.Equal(images[j].CreatedAt) {
+			return images[i].CreatedAt.Before(images[j].CreatedAt)
+		}
+		return images[i].ID < images[j].ID
+	})
+	return images, nil
+}
+
+// Open opens the image file, or the file of its rendition, to read it
+func (store *DiskImageStore) Open(imageID string, rendition string) (io.ReadCloser, error) {
+	info, err := store.Find(imageID)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, ErrNotFound
+	}
+
+	path := info.Path
+	if rendition != "" {
+		r := info.rendition(rendition)
+		if r == nil {
+			return nil, ErrNotFound
+		}
+		path = r.Path
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open image file: %w", err)
+	}
+	return file, nil
+}
+
+// Delete removes the info of the image, and its files that no other image uses
+func (store *DiskImageStore) Delete(imageID string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	info := store.images[imageID]
+	if info == nil {
+		return ErrNotFound
+	}
+
+	// 先删除sidecar: 中途失败时只会留下一个没有info的文件, Reconcile可以清理它
+	err := os.Remove(store.sidecarPath(imageID))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove image info: %w", err)
+	}
+	delete(store.images, imageID)
+
+	// 其他image还在使用的文件不会被删除
+	return store.releaseImage(info)
 }
 
 
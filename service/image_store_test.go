@@ -0,0 +1,306 @@
+package service_test
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"pc_book/sample"
+	"pc_book/service"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDiskImageStoreReopen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	laptopID := sample.NewLaptop().Id
+	info, err := store.Save(laptopID, ".png", strings.NewReader("image data"))
+	require.NoError(t, err)
+	require.Equal(t, int64(10), info.Size)
+	require.Len(t, info.Checksum, 64)
+	imageID := info.ID
+
+	found, err := store.Find(imageID)
+	require.NoError(t, err)
+	require.Equal(t, info, found)
+
+	rendition := service.ImageRendition{Name: "small", Type: ".png", Width: 16, Height: 9}
+	require.NoError(t, store.AddRendition(imageID, rendition, strings.NewReader("small data")))
+	require.Error(t, store.AddRendition(imageID, service.ImageRendition{Name: "../x", Type: ".png"}, strings.NewReader("")))
+	require.ErrorIs(t, store.AddRendition("unknown", rendition, strings.NewReader("")), service.ErrNotFound)
+
+	info, err = store.Find(imageID)
+	require.NoError(t, err)
+	require.Len(t, info.Renditions, 1)
+	require.Equal(t, int64(10), info.Renditions[0].Size)
+
+	// the info is read again from the sidecar
+	store, err = service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	other, err := store.Find(imageID)
+	require.NoError(t, err)
+	require.NotNil(t, other)
+	require.Equal(t, info.LaptopID, other.LaptopID)
+	require.Equal(t, info.Type, other.Type)
+	require.Equal(t, info.Path, other.Path)
+	require.Equal(t, info.Size, other.Size)
+	require.Equal(t, info.Checksum, other.Checksum)
+	require.True(t, info.CreatedAt.Equal(other.CreatedAt))
+	require.Equal(t, info.Renditions, other.Renditions)
+
+	file, err := store.Open(imageID, "small")
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.Equal(t, "small data", string(data))
+
+	_, err = store.Open(imageID, "large")
+	require.ErrorIs(t, err, service.ErrNotFound)
+
+	// the file of the rendition is not an orphan
+	report, err := store.Reconcile(nil, false)
+	require.NoError(t, err)
+	require.True(t, report.Empty())
+
+	images, err := store.List(laptopID)
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+
+	require.NoError(t, store.Delete(imageID))
+	store, err = service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	other, err = store.Find(imageID)
+	require.NoError(t, err)
+	require.Nil(t, other)
+}
+
+func TestDiskImageStoreReconcile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	laptopID := sample.NewLaptop().Id
+	deletedLaptopID := sample.NewLaptop().Id
+
+	keptInfo, err := store.Save(laptopID, ".png", strings.NewReader("kept"))
+	require.NoError(t, err)
+	kept := keptInfo.ID
+	brokenInfo, err := store.Save(laptopID, ".png", strings.NewReader("broken"))
+	require.NoError(t, err)
+	broken := brokenInfo.ID
+	orphanInfo, err := store.Save(deletedLaptopID, ".jpg", strings.NewReader("orphan"))
+	require.NoError(t, err)
+	orphan := orphanInfo.ID
+
+	info, err := store.Find(broken)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(info.Path))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "lost.png"), []byte("lost"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "blobs", "stray"), []byte("stray"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bad"+".info.json"), []byte("{"), 0644))
+
+	// a restart keeps everything until the folder is cleaned
+	store, err = service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	laptopExists := func(id string) (bool, error) {
+		return id == laptopID, nil
+	}
+
+	report, err := store.Reconcile(laptopExists, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bad.info.json", "blobs/stray", "lost.png"}, report.OrphanFiles)
+	require.Equal(t, []string{broken}, report.BrokenImages)
+	require.Equal(t, []string{orphan}, report.OrphanImages)
+
+	report, err = store.Reconcile(laptopExists, true)
+	require.NoError(t, err)
+	require.True(t, report.Cleaned)
+	require.False(t, report.Empty())
+
+	report, err = store.Reconcile(laptopExists, false)
+	require.NoError(t, err)
+	require.True(t, report.Empty())
+
+	for _, id := range []string{broken, orphan} {
+		info, err := store.Find(id)
+		require.NoError(t, err)
+		require.Nil(t, info)
+	}
+
+	// only the kept image and its sidecar are left
+	info, err = store.Find(kept)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"blobs/" + info.Checksum, kept + ".info.json"}, listImageFiles(t, dir))
+}
+
+// listImageFiles returns the files of the image folder and of its sub folders, relative to it
+func listImageFiles(t *testing.T, dir string) []string {
+	files := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func TestDiskImageStoreDeduplication(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	first, err := store.Save(sample.NewLaptop().Id, ".png", strings.NewReader("same data"))
+	require.NoError(t, err)
+	second, err := store.Save(sample.NewLaptop().Id, ".png", strings.NewReader("same data"))
+	require.NoError(t, err)
+	other, err := store.Save(sample.NewLaptop().Id, ".png", strings.NewReader("other data"))
+	require.NoError(t, err)
+
+	require.NotEqual(t, first.ID, second.ID)
+	// sha256 of "same data"
+	require.Equal(t, "6d8596e17b628c605d4f4d885f7fced4f69c5caa7c4edd4ec215476c42992698", first.Checksum)
+	require.Equal(t, first.Checksum, second.Checksum)
+	require.Equal(t, first.Path, second.Path)
+	require.Equal(t, filepath.Join(dir, "blobs", first.Checksum), first.Path)
+	require.NotEqual(t, first.Path, other.Path)
+
+	rendition := service.ImageRendition{Name: "small", Type: ".png", Width: 1, Height: 1}
+	require.NoError(t, store.AddRendition(first.ID, rendition, strings.NewReader("same data")))
+	require.NoError(t, store.AddRendition(second.ID, rendition, strings.NewReader("small data")))
+	// 3 sidecars and 3 blobs, the rendition of the first image shares its blob
+	require.Len(t, listImageFiles(t, dir), 6)
+
+	// the blob is kept while an image or a rendition uses it
+	require.NoError(t, store.Delete(first.ID))
+	require.FileExists(t, second.Path)
+
+	// the references are computed again after a restart
+	store, err = service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	// replacing the rendition releases its previous blob
+	require.NoError(t, store.AddRendition(second.ID, rendition, strings.NewReader("other data")))
+	require.Len(t, listImageFiles(t, dir), 4)
+
+	require.NoError(t, store.Delete(second.ID))
+	require.NoFileExists(t, second.Path)
+	require.FileExists(t, other.Path)
+
+	require.NoError(t, store.Delete(other.ID))
+	require.Empty(t, listImageFiles(t, dir))
+}
+
+func TestDiskImageStoreLegacyFile(t *testing.T) {
+	t.Parallel()
+
+	// an image saved before the blobs, its file is named by its id
+	dir := t.TempDir()
+	imageID := "3f8e9c2a-1d4b-4c5e-8f7a-6b5c4d3e2f1a"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, imageID+".png"), []byte("old data"), 0644))
+	sidecar := `{"id": "` + imageID + `", "laptop_id": "laptop", "type": ".png", "file": "` + imageID + `.png", "size": 8}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, imageID+".info.json"), []byte(sidecar), 0644))
+
+	store, err := service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	info, err := store.Find(imageID)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, imageID+".png"), info.Path)
+
+	report, err := store.Reconcile(nil, false)
+	require.NoError(t, err)
+	require.True(t, report.Empty())
+
+	require.NoError(t, store.Delete(imageID))
+	require.Empty(t, listImageFiles(t, dir))
+}
+
+func TestDiskImageStoreAdoptsFileWithoutInfo(t *testing.T) {
+	t.Parallel()
+
+	// an image saved before the sidecars only has its file, named by its id
+	dir := t.TempDir()
+	imageID := "7c1d2e3f-4a5b-4c6d-8e9f-0a1b2c3d4e5f"
+	imagePath := filepath.Join(dir, imageID+".jpg")
+	require.NoError(t, ioutil.WriteFile(imagePath, []byte("same data"), 0644))
+
+	store, err := service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	info, err := store.Find(imageID)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	require.Equal(t, imagePath, info.Path)
+	require.Equal(t, ".jpg", info.Type)
+	require.Equal(t, int64(9), info.Size)
+	require.Equal(t, "6d8596e17b628c605d4f4d885f7fced4f69c5caa7c4edd4ec215476c42992698", info.Checksum)
+	require.FileExists(t, filepath.Join(dir, imageID+".info.json"))
+
+	// its laptop is unknown, cleaning the folder keeps it
+	noLaptop := func(id string) (bool, error) {
+		return false, nil
+	}
+	report, err := store.Reconcile(noLaptop, true)
+	require.NoError(t, err)
+	require.True(t, report.Empty())
+	require.FileExists(t, imagePath)
+
+	store, err = service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+	info, err = store.Find(imageID)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+
+	require.NoError(t, store.Delete(imageID))
+	require.Empty(t, listImageFiles(t, dir))
+}
+
+func TestDiskImageStoreSaveFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	laptopID := sample.NewLaptop().Id
+	data := io.MultiReader(strings.NewReader("partial data"), iotest.ErrReader(errors.New("connection lost")))
+	info, err := store.Save(laptopID, ".png", data)
+	require.Error(t, err)
+	require.Nil(t, info)
+
+	// the temporary file is removed
+	require.Empty(t, listImageFiles(t, dir))
+
+	// the image type must be an extension
+	for _, imageType := range []string{"/../../etc", ".PNG", ""} {
+		info, err = store.Save(laptopID, imageType, strings.NewReader("image data"))
+		require.Error(t, err)
+		require.Nil(t, info)
+	}
+
+	require.Empty(t, listImageFiles(t, dir))
+
+	images, err := store.List(laptopID)
+	require.NoError(t, err)
+	require.Empty(t, images)
+}
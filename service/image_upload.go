@@ -0,0 +1,455 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// uploadFolder is the sub folder of the resumable uploads: the data of each upload is in the file named by its id,
+// and its state in the file of uploadStateSuffix, so that the uploads can be resumed after a restart
+const uploadFolder = "uploads"
+
+// uploadStateSuffix ends the name of the file that holds the state of an upload, the ids have no dot
+const uploadStateSuffix = ".json"
+
+// ResumableImageStore is an ImageStore whose uploads can be resumed after the stream of the client is broken
+type ResumableImageStore interface {
+	ImageStore
+	// StartUpload returns the upload with the id, which is created if it doesn't exist.
+	// It returns ErrConflict if the upload exists for another laptop or image type.
+	StartUpload(uploadID string, laptopID string, imageType string) (*ImageUpload, error)
+	// FindUpload returns the upload with the id, or nil if it doesn't exist
+	FindUpload(uploadID string) (*ImageUpload, error)
+	// AppendUpload writes the data at offset and returns the new offset of the upload.
+	// It returns ErrConflict if offset is not the offset of the upload, or if the upload is complete.
+	AppendUpload(uploadID string, offset int64, data []byte) (int64, error)
+	// OpenUpload opens the data received by an upload that is not complete
+	OpenUpload(uploadID string) (io.ReadCloser, error)
+	// CompleteUpload saves the data of an upload as a new image with the image type.
+	// The upload keeps the id of the image until it expires, completing it again returns the same image.
+	CompleteUpload(uploadID string, imageType string) (*ImageInfo, error)
+	// CancelUpload removes an upload and its data
+	CancelUpload(uploadID string) error
+	// ExpireUploads removes the uploads that are not updated since before, it returns their number
+	ExpireUploads(before time.Time) (int, error)
+}
+
+// ImageUpload is the state of a resumable upload
+type ImageUpload struct {
+	ID       string
+	LaptopID string
+	// Type is the image type declared by the client
+	Type string
+	// Offset is the size of the data received
+	Offset int64
+	// ImageID is set once the upload is complete
+	ImageID   string
+	UpdatedAt time.Time
+}
+
+// diskUpload is an upload of DiskImageStore.
+// The fields of upload are changed with both mutexes held, they can be read with one of them.
+type diskUpload struct {
+	// mutex serializes the writes of the data
+	mutex  sync.Mutex
+	upload ImageUpload
+	// path is the file of the data, it is empty once the upload is complete
+	path string
+	hash hash.Hash
+}
+
+// uploadState is the content of the state file of an upload.
+// The data file can be longer than Offset if the server stopped while writing, it is truncated when it is loaded.
+type uploadState struct {
+	ID        string    `json:"id"`
+	LaptopID  string    `json:"laptop_id"`
+	Type      string    `json:"type"`
+	Offset    int64     `json:"offset"`
+	ImageID   string    `json:"image_id,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (store *DiskImageStore) uploadPath(uploadID string) string {
+	return filepath.Join(store.imageFolder, uploadFolder, uploadID)
+}
+
+// writeUploadState writes the state of an upload to a temporary file, then renames it to the state file
+func (store *DiskImageStore) writeUploadState(upload ImageUpload) error {
+	data, err := json.MarshalIndent(uploadState(upload), "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal upload state: %w", err)
+	}
+
+	path := store.uploadPath(upload.ID) + uploadStateSuffix
+	tmpPath := path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0644)
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("cannot write upload state: %w", err)
+	}
+	return nil
+}
+
+// loadUploads reads the state of the uploads of the upload folder.
+// The checksum of the data received is computed again, the files that belong to no upload are removed.
+func (store *DiskImageStore) loadUploads() error {
+	folder := filepath.Join(store.imageFolder, uploadFolder)
+	entries, err := ioutil.ReadDir(folder)
+	if err != nil {
+		return fmt.Errorf("cannot read upload folder: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, uploadStateSuffix) {
+			continue
+		}
+
+		u, err := store.loadUpload(strings.TrimSuffix(name, uploadStateSuffix))
+		if err != nil {
+			log.Printf("ignore upload %s: %v", name, err)
+			continue
+		}
+		store.uploads[u.upload.ID] = u
+	}
+
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), uploadStateSuffix)
+		if store.uploads[name] == nil {
+			os.RemoveAll(filepath.Join(folder, entry.Name()))
+		}
+	}
+	return nil
+}
+
+func (store *DiskImageStore) loadUpload(uploadID string) (*diskUpload, error) {
+	data, err := ioutil.ReadFile(store.uploadPath(uploadID) + uploadStateSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	var state uploadState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, err
+	}
+	if state.ID != uploadID || !isUploadID(uploadID) {
+		return nil, fmt.Errorf("invalid upload state")
+	}
+
+	u := &diskUpload{upload: ImageUpload(state)}
+	if state.ImageID != "" {
+		return u, nil
+	}
+
+	// 数据文件可能比状态中的offset长, 多出的部分没有被确认
+	u.path = store.uploadPath(uploadID)
+	file, err := os.OpenFile(u.path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() < state.Offset {
+		u.upload.Offset = stat.Size()
+	}
+	err = file.Truncate(u.upload.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	u.hash = sha256.New()
+	_, err = io.Copy(u.hash, file)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// isUploadID returns true if the id of an upload can be a file name: 1 to 64 letters, digits, - or _
+func isUploadID(uploadID string) bool {
+	if uploadID == "" || len(uploadID) > 64 {
+		return false
+	}
+	for _, c := range uploadID {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// StartUpload creates the upload and its empty file, or returns the existing upload
+func (store *DiskImageStore) StartUpload(uploadID string, laptopID string, imageType string) (*ImageUpload, error) {
+	if !isUploadID(uploadID) {
+		return nil, fmt.Errorf("invalid upload id %q", uploadID)
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if u := store.uploads[uploadID]; u != nil {
+		if u.upload.LaptopID != laptopID || u.upload.Type != imageType {
+			return nil, ErrConflict
+		}
+		upload := u.upload
+		return &upload, nil
+	}
+
+	path := store.uploadPath(uploadID)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create upload file: %w", err)
+	}
+	file.Close()
+
+	u := &diskUpload{
+		upload: ImageUpload{
+			ID:        uploadID,
+			LaptopID:  laptopID,
+			Type:      imageType,
+			UpdatedAt: time.Now(),
+		},
+		path: path,
+		hash: sha256.New(),
+	}
+	err = store.writeUploadState(u.upload)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	store.uploads[uploadID] = u
+
+	upload := u.upload
+	return &upload, nil
+}
+
+// FindUpload returns a copy of the upload, or nil if it doesn't exist
+func (store *DiskImageStore) FindUpload(uploadID string) (*ImageUpload, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	u := store.uploads[uploadID]
+	if u == nil {
+		return nil, nil
+	}
+	upload := u.upload
+	return &upload, nil
+}
+
+func (store *DiskImageStore) findUpload(uploadID string) (*diskUpload, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	u := store.uploads[uploadID]
+	if u == nil {
+		return nil, ErrNotFound
+	}
+	return u, nil
+}
+
+// AppendUpload writes the data at the end of the file of the upload.
+// The file is truncated to the previous offset if the write fails, so that the offset is always its size.
+func (store *DiskImageStore) AppendUpload(uploadID string, offset int64, data []byte) (int64, error) {
+	u, err := store.findUpload(uploadID)
+	if err != nil {
+		return 0, err
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.path == "" || offset != u.upload.Offset {
+		return 0, ErrConflict
+	}
+
+	file, err := os.OpenFile(u.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return 0, fmt.Errorf("cannot open upload file: %w", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Truncate(u.path, offset)
+		return 0, fmt.Errorf("cannot write upload file: %w", err)
+	}
+
+	// the data is only received once the new offset is written
+	upload := u.upload
+	upload.Offset += int64(len(data))
+	upload.UpdatedAt = time.Now()
+	err = store.writeUploadState(upload)
+	if err != nil {
+		os.Truncate(u.path, offset)
+		return 0, err
+	}
+	u.hash.Write(data)
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	u.upload = upload
+	return u.upload.Offset, nil
+}
+
+// OpenUpload opens the file of the upload to read it
+func (store *DiskImageStore) OpenUpload(uploadID string) (io.ReadCloser, error) {
+	u, err := store.findUpload(uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.path == "" {
+		return nil, ErrConflict
+	}
+	file, err := os.Open(u.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open upload file: %w", err)
+	}
+	return file, nil
+}
+
+// CompleteUpload moves the file of the upload to the blob of its data, the checksum was computed by AppendUpload
+func (store *DiskImageStore) CompleteUpload(uploadID string, imageType string) (*ImageInfo, error) {
+	if !isImageExtension(imageType) {
+		return nil, fmt.Errorf("invalid image type %q", imageType)
+	}
+
+	u, err := store.findUpload(uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	// 已经完成的上传返回同一个image
+	if u.path == "" {
+		info, err := store.Find(u.upload.ImageID)
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			return nil, ErrNotFound
+		}
+		return info, nil
+	}
+
+	imageID, err := uuid.NewUUID()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate image id: %w", err)
+	}
+
+	checksum := hex.EncodeToString(u.hash.Sum(nil))
+	info, err := store.addImage(imageID.String(), u.upload.LaptopID, imageType, u.path, u.upload.Offset, checksum)
+	if err != nil {
+		// the file has been moved or removed, the upload cannot be resumed
+		store.mutex.Lock()
+		delete(store.uploads, uploadID)
+		store.mutex.Unlock()
+		os.Remove(u.path)
+		os.Remove(u.path + uploadStateSuffix)
+		return nil, err
+	}
+
+	upload := u.upload
+	upload.ImageID = info.ID
+	upload.UpdatedAt = time.Now()
+	err = store.writeUploadState(upload)
+	if err != nil {
+		// the image is saved, only completing the upload again after a restart fails
+		log.Printf("cannot record image %s of upload %s: %v", info.ID, uploadID, err)
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	u.upload = upload
+	u.path = ""
+	u.hash = nil
+	return info, nil
+}
+
+// CancelUpload removes the upload and its file
+func (store *DiskImageStore) CancelUpload(uploadID string) error {
+	u, err := store.findUpload(uploadID)
+	if err != nil {
+		return err
+	}
+	_, err = store.removeUpload(u, time.Time{})
+	return err
+}
+
+// ExpireUploads removes the uploads not updated since before, and their files
+func (store *DiskImageStore) ExpireUploads(before time.Time) (int, error) {
+	store.mutex.RLock()
+	var expired []*diskUpload
+	for _, u := range store.uploads {
+		if u.upload.UpdatedAt.Before(before) {
+			expired = append(expired, u)
+		}
+	}
+	store.mutex.RUnlock()
+
+	count := 0
+	for _, u := range expired {
+		removed, err := store.removeUpload(u, before)
+		if err != nil {
+			return count, err
+		}
+		if removed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// removeUpload removes an upload, unless it has been updated since before if before is not zero
+func (store *DiskImageStore) removeUpload(u *diskUpload, before time.Time) (bool, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	store.mutex.Lock()
+	if store.uploads[u.upload.ID] != u || (!before.IsZero() && !u.upload.UpdatedAt.Before(before)) {
+		store.mutex.Unlock()
+		return false, nil
+	}
+	delete(store.uploads, u.upload.ID)
+	store.mutex.Unlock()
+
+	paths := []string{store.uploadPath(u.upload.ID) + uploadStateSuffix}
+	if u.path != "" {
+		paths = append(paths, u.path)
+	}
+	for _, path := range paths {
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return true, fmt.Errorf("cannot remove upload file: %w", err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,178 @@
+package service_test
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"pc_book/sample"
+	"pc_book/service"
+	"testing"
+	"time"
+)
+
+func TestDiskImageStoreUpload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	laptopID := sample.NewLaptop().Id
+	upload, err := store.StartUpload("upload-1", laptopID, ".png")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), upload.Offset)
+
+	_, err = store.StartUpload("upload-1", sample.NewLaptop().Id, ".png")
+	require.ErrorIs(t, err, service.ErrConflict)
+	_, err = store.StartUpload("../upload", laptopID, ".png")
+	require.Error(t, err)
+
+	offset, err := store.AppendUpload("upload-1", 0, []byte("image "))
+	require.NoError(t, err)
+	require.Equal(t, int64(6), offset)
+
+	// the offset must be the size of the received data
+	_, err = store.AppendUpload("upload-1", 0, []byte("image "))
+	require.ErrorIs(t, err, service.ErrConflict)
+	_, err = store.AppendUpload("unknown", 0, []byte("image "))
+	require.ErrorIs(t, err, service.ErrNotFound)
+
+	// the upload is resumed
+	upload, err = store.StartUpload("upload-1", laptopID, ".png")
+	require.NoError(t, err)
+	require.Equal(t, int64(6), upload.Offset)
+
+	offset, err = store.AppendUpload("upload-1", upload.Offset, []byte("data"))
+	require.NoError(t, err)
+	require.Equal(t, int64(10), offset)
+
+	file, err := store.OpenUpload("upload-1")
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.Equal(t, "image data", string(data))
+
+	info, err := store.CompleteUpload("upload-1", ".png")
+	require.NoError(t, err)
+	require.Equal(t, laptopID, info.LaptopID)
+	require.Equal(t, int64(10), info.Size)
+	// sha256 of "image data"
+	require.Equal(t, "b41b86dcfdc6219bc2fb987591ad9995bcf3a1e40c2bdd3fdbec622371e6e1af", info.Checksum)
+	require.ElementsMatch(t, []string{"blobs/" + info.Checksum, info.ID + ".info.json", "uploads/upload-1.json"}, listImageFiles(t, dir))
+
+	// the upload keeps the id of the image
+	upload, err = store.FindUpload("upload-1")
+	require.NoError(t, err)
+	require.Equal(t, info.ID, upload.ImageID)
+
+	other, err := store.CompleteUpload("upload-1", ".png")
+	require.NoError(t, err)
+	require.Equal(t, info.ID, other.ID)
+	_, err = store.AppendUpload("upload-1", 10, []byte("more"))
+	require.ErrorIs(t, err, service.ErrConflict)
+}
+
+func TestDiskImageStoreExpireUploads(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	laptopID := sample.NewLaptop().Id
+	_, err = store.StartUpload("old", laptopID, "")
+	require.NoError(t, err)
+	_, err = store.AppendUpload("old", 0, []byte("old data"))
+	require.NoError(t, err)
+	_, err = store.StartUpload("canceled", laptopID, "")
+	require.NoError(t, err)
+
+	before := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = store.StartUpload("recent", laptopID, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"uploads/canceled", "uploads/canceled.json",
+		"uploads/old", "uploads/old.json",
+		"uploads/recent", "uploads/recent.json",
+	}, listImageFiles(t, dir))
+
+	require.NoError(t, store.CancelUpload("canceled"))
+	require.ErrorIs(t, store.CancelUpload("canceled"), service.ErrNotFound)
+
+	count, err := store.ExpireUploads(before)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, []string{"uploads/recent", "uploads/recent.json"}, listImageFiles(t, dir))
+
+	upload, err := store.FindUpload("old")
+	require.NoError(t, err)
+	require.Nil(t, upload)
+	upload, err = store.FindUpload("recent")
+	require.NoError(t, err)
+	require.NotNil(t, upload)
+
+	count, err = store.ExpireUploads(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Empty(t, listImageFiles(t, dir))
+}
+
+func TestDiskImageStoreUploadsAfterRestart(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	laptopID := sample.NewLaptop().Id
+	_, err = store.StartUpload("upload", laptopID, ".png")
+	require.NoError(t, err)
+	_, err = store.AppendUpload("upload", 0, []byte("image "))
+	require.NoError(t, err)
+	_, err = store.StartUpload("done", laptopID, ".png")
+	require.NoError(t, err)
+	_, err = store.AppendUpload("done", 0, []byte("done"))
+	require.NoError(t, err)
+	done, err := store.CompleteUpload("done", ".png")
+	require.NoError(t, err)
+
+	// the data written after the last recorded offset is dropped
+	file, err := os.OpenFile(filepath.Join(dir, "uploads", "upload"), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = file.Write([]byte("torn"))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "uploads", "orphan"), []byte("orphan"), 0644))
+
+	store, err = service.NewDiskImageStore(dir)
+	require.NoError(t, err)
+
+	upload, err := store.FindUpload("upload")
+	require.NoError(t, err)
+	require.NotNil(t, upload)
+	require.Equal(t, laptopID, upload.LaptopID)
+	require.Equal(t, ".png", upload.Type)
+	require.Equal(t, int64(6), upload.Offset)
+
+	upload, err = store.FindUpload("done")
+	require.NoError(t, err)
+	require.Equal(t, done.ID, upload.ImageID)
+
+	offset, err := store.AppendUpload("upload", 6, []byte("data"))
+	require.NoError(t, err)
+	require.Equal(t, int64(10), offset)
+	info, err := store.CompleteUpload("upload", ".png")
+	require.NoError(t, err)
+	// sha256 of "image data"
+	require.Equal(t, "b41b86dcfdc6219bc2fb987591ad9995bcf3a1e40c2bdd3fdbec622371e6e1af", info.Checksum)
+
+	// the expired uploads are removed by the sweeper only
+	count, err := store.ExpireUploads(time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.NotContains(t, listImageFiles(t, dir), "uploads/orphan")
+}
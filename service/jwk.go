@@ -0,0 +1,43 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"pc_book/pd"
+)
+
+// toJWK converts the public part of a signing key to a JSON web key (RFC 7517)
+func toJWK(key *SigningKey) (*pd.PublicKey, error) {
+	jwk := &pd.PublicKey{
+		Kid: key.ID,
+		Alg: key.Method.Alg(),
+		Use: "sig",
+	}
+
+	switch publicKey := key.PublicKey().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64URL(publicKey.N.Bytes())
+		jwk.E = encodeBase64URL(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		params := publicKey.Curve.Params()
+		// 坐标需要补齐到曲线的字节长度
+		size := (params.BitSize + 7) / 8
+
+		jwk.Kty = "EC"
+		jwk.Crv = params.Name
+		jwk.X = encodeBase64URL(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeBase64URL(publicKey.Y.FillBytes(make([]byte, size)))
+	default:
+		return nil, fmt.Errorf("key %s has no public key", key.ID)
+	}
+
+	return jwk, nil
+}
+
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -3,56 +3,247 @@ package service
 import (
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"sort"
+	"sync"
 	"time"
 )
 
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+
+	// defaultKeyID is the key ID of the secret key given to NewJWTManager
+	defaultKeyID = "default"
+)
+
 // JWTManager is a JSON web token manager
 type JWTManager struct {
-	secretKey     string
-	tokenDuration time.Duration
+	tokenDuration        time.Duration
+	refreshTokenDuration time.Duration
+
+	// keys holds every key that can verify tokens, the one with signingKeyID signs new tokens
+	keysMutex    sync.RWMutex
+	keys         map[string]*SigningKey
+	signingKeyID string
+
+	// revoked keeps the ID of revoked tokens until they expire,
+	// issued keeps the ID of the tokens of each user until they expire, so that RevokeUser can revoke them
+	mutex   sync.RWMutex
+	revoked map[string]int64
+	issued  map[string]map[string]int64
 }
 
 // UserClaims is a custom JWT claims that contains some user's information
 type UserClaims struct {
 	jwt.StandardClaims
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	TokenType string `json:"token_type"`
+}
+
+// NewJWTManager returns a new JWTManager that signs tokens with an HMAC secret key
+func NewJWTManager(secretKey string, tokenDuration time.Duration, refreshTokenDuration time.Duration) *JWTManager {
+	manager, _ := NewJWTManagerWithKeys([]*SigningKey{NewHMACSigningKey(defaultKeyID, secretKey)}, tokenDuration, refreshTokenDuration)
+	return manager
+}
+
+// NewJWTManagerWithKeys returns a new JWTManager that accepts tokens signed by any of the keys,
+// new tokens are signed with the first key that has a private part
+func NewJWTManagerWithKeys(keys []*SigningKey, tokenDuration time.Duration, refreshTokenDuration time.Duration) (*JWTManager, error) {
+	manager := &JWTManager{
+		tokenDuration:        tokenDuration,
+		refreshTokenDuration: refreshTokenDuration,
+		keys:                 make(map[string]*SigningKey),
+		revoked:              make(map[string]int64),
+		issued:               make(map[string]map[string]int64),
+	}
+
+	for _, key := range keys {
+		if err := manager.AddKey(key); err != nil {
+			return nil, err
+		}
+		if manager.signingKeyID == "" && key.CanSign() {
+			manager.signingKeyID = key.ID
+		}
+	}
+
+	if manager.signingKeyID == "" {
+		return nil, fmt.Errorf("no key can sign tokens")
+	}
+	return manager, nil
+}
+
+// AddKey adds a key that is accepted to verify tokens
+func (manager *JWTManager) AddKey(key *SigningKey) error {
+	manager.keysMutex.Lock()
+	defer manager.keysMutex.Unlock()
+
+	if key.ID == "" {
+		return fmt.Errorf("key ID is required")
+	}
+	if manager.keys[key.ID] != nil {
+		return fmt.Errorf("key %s: %w", key.ID, ErrAlreadyExists)
+	}
+
+	manager.keys[key.ID] = key
+	return nil
+}
+
+// RemoveKey removes a key, tokens signed by it are no longer accepted
+func (manager *JWTManager) RemoveKey(id string) error {
+	manager.keysMutex.Lock()
+	defer manager.keysMutex.Unlock()
+
+	if manager.keys[id] == nil {
+		return fmt.Errorf("key %s: %w", id, ErrNotFound)
+	}
+	if id == manager.signingKeyID {
+		return fmt.Errorf("cannot remove key %s which is used for signing", id)
+	}
+
+	delete(manager.keys, id)
+	return nil
+}
+
+// SetSigningKey chooses the key which signs new tokens
+func (manager *JWTManager) SetSigningKey(id string) error {
+	manager.keysMutex.Lock()
+	defer manager.keysMutex.Unlock()
+
+	key := manager.keys[id]
+	if key == nil {
+		return fmt.Errorf("key %s: %w", id, ErrNotFound)
+	}
+	if !key.CanSign() {
+		return fmt.Errorf("key %s has no private key", id)
+	}
+
+	manager.signingKeyID = id
+	return nil
 }
 
-// NewJWTManager returns a new JWTManager
-func NewJWTManager(secretKey string, tokenDuration time.Duration) *JWTManager {
-	return &JWTManager{
-		secretKey:     secretKey,
-		tokenDuration: tokenDuration,
+// PublicKeys returns the asymmetric keys that are accepted, ordered by key ID
+func (manager *JWTManager) PublicKeys() []*SigningKey {
+	manager.keysMutex.RLock()
+	defer manager.keysMutex.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(manager.keys))
+	for _, key := range manager.keys {
+		if key.PublicKey() != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+func (manager *JWTManager) signingKey() *SigningKey {
+	manager.keysMutex.RLock()
+	defer manager.keysMutex.RUnlock()
+
+	return manager.keys[manager.signingKeyID]
+}
+
+// verifyingKey finds the key for a token, tokens without a key ID are checked with the signing key
+func (manager *JWTManager) verifyingKey(token *jwt.Token) (interface{}, error) {
+	manager.keysMutex.RLock()
+	defer manager.keysMutex.RUnlock()
+
+	keyID := manager.signingKeyID
+	if kid, ok := token.Header["kid"]; ok {
+		keyID, ok = kid.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid key id")
+		}
+	}
+
+	key := manager.keys[keyID]
+	if key == nil {
+		return nil, fmt.Errorf("unknown key id: %s", keyID)
 	}
+
+	// the algorithm must match the key, otherwise a public key could be used as an HMAC secret
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unspected token signing method: %s", token.Method.Alg())
+	}
+	return key.verifyKey, nil
 }
 
-// Generate generates and signs a new token for user
+// Generate generates and signs a new access token for user
 func (manager *JWTManager) Generate(user *User) (string, error) {
+	return manager.generate(user, accessTokenType, manager.tokenDuration)
+}
+
+// GenerateRefreshToken generates and signs a new refresh token for user
+func (manager *JWTManager) GenerateRefreshToken(user *User) (string, error) {
+	return manager.generate(user, refreshTokenType, manager.refreshTokenDuration)
+}
+
+func (manager *JWTManager) generate(user *User, tokenType string, duration time.Duration) (string, error) {
+	tokenID, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("cannot generate token id: %w", err)
+	}
+
+	now := time.Now()
 	claims := UserClaims{
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(manager.tokenDuration).Unix(),
+			Id:        tokenID.String(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(duration).Unix(),
 		},
-		Username: user.UserName,
-		Role:     user.Role,
+		Username:  user.UserName,
+		Role:      user.Role,
+		TokenType: tokenType,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
-	return token.SignedString([]byte(manager.secretKey))
+	key := manager.signingKey()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.signKey)
+	if err != nil {
+		return "", err
+	}
+
+	manager.recordIssued(user.UserName, &claims)
+	return signed, nil
+}
+
+// recordIssued keeps the ID of a new token of the user, and forgets the expired ones
+func (manager *JWTManager) recordIssued(username string, claims *UserClaims) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	tokens := manager.issued[username]
+	if tokens == nil {
+		tokens = make(map[string]int64)
+		manager.issued[username] = tokens
+	}
+
+	now := time.Now().Unix()
+	for id, expiresAt := range tokens {
+		if expiresAt < now {
+			delete(tokens, id)
+		}
+	}
+	tokens[claims.Id] = claims.ExpiresAt
 }
+
 // Verify verifies the access token string and return a userClaim if the token is valid
 func (manager *JWTManager) Verify(accessToken string) (*UserClaims, error) {
-	token, err := jwt.ParseWithClaims(
-		accessToken,
-		&UserClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			_, ok := token.Method.(*jwt.SigningMethodHMAC)
-			if !ok {
-				return nil, fmt.Errorf("unspected token signing method")
-			}
-			return []byte(manager.secretKey), nil
-		},
-	)
+	return manager.verify(accessToken, accessTokenType)
+}
+
+// VerifyRefreshToken verifies the refresh token string and return a userClaim if the token is valid
+func (manager *JWTManager) VerifyRefreshToken(refreshToken string) (*UserClaims, error) {
+	return manager.verify(refreshToken, refreshTokenType)
+}
+
+func (manager *JWTManager) verify(tokenString string, tokenType string) (*UserClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, manager.verifyingKey)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %v", err)
 	}
@@ -62,6 +253,64 @@ func (manager *JWTManager) Verify(accessToken string) (*UserClaims, error) {
 		return nil, fmt.Errorf("invalid token claim")
 	}
 
+	if claims.TokenType != tokenType {
+		return nil, fmt.Errorf("invalid token type: %q", claims.TokenType)
+	}
+
+	if manager.IsRevoked(claims.Id) {
+		return nil, fmt.Errorf("token has been revoked")
+	}
+
 	return claims, nil
 }
 
+// Revoke puts the token ID on the revocation list until the token expires,
+// it returns false if the token was already revoked
+func (manager *JWTManager) Revoke(claims *UserClaims) bool {
+	if claims.Id == "" {
+		return false
+	}
+
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	// 清理已经过期的token，它们不会再通过验证
+	now := time.Now().Unix()
+	for id, expiresAt := range manager.revoked {
+		if expiresAt < now {
+			delete(manager.revoked, id)
+		}
+	}
+
+	if _, ok := manager.revoked[claims.Id]; ok {
+		return false
+	}
+	manager.revoked[claims.Id] = claims.ExpiresAt
+	return true
+}
+
+// RevokeUser revokes every token issued to the user that has not expired yet, it returns how many were revoked
+func (manager *JWTManager) RevokeUser(username string) int {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	count := 0
+	now := time.Now().Unix()
+	for id, expiresAt := range manager.issued[username] {
+		if _, ok := manager.revoked[id]; expiresAt >= now && !ok {
+			manager.revoked[id] = expiresAt
+			count++
+		}
+	}
+	delete(manager.issued, username)
+	return count
+}
+
+// IsRevoked checks whether the token ID is on the revocation list
+func (manager *JWTManager) IsRevoked(tokenID string) bool {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	_, ok := manager.revoked[tokenID]
+	return ok
+}
@@ -0,0 +1,299 @@
+package service_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"pc_book/pd"
+	"pc_book/service"
+	"testing"
+)
+
+func TestJWTManagerTokenTypes(t *testing.T) {
+	t.Parallel()
+
+	jwtManager := service.NewJWTManager(testSecretKey, testTokenDuration, testRefreshTokenDuration)
+	user, err := service.NewUser("user1", "secret", "user")
+	require.NoError(t, err)
+
+	accessToken, err := jwtManager.Generate(user)
+	require.NoError(t, err)
+	refreshToken, err := jwtManager.GenerateRefreshToken(user)
+	require.NoError(t, err)
+
+	claims, err := jwtManager.Verify(accessToken)
+	require.NoError(t, err)
+	require.Equal(t, "user1", claims.Username)
+	require.Equal(t, "user", claims.Role)
+	require.NotEmpty(t, claims.Id)
+
+	claims, err = jwtManager.VerifyRefreshToken(refreshToken)
+	require.NoError(t, err)
+	require.Equal(t, "user1", claims.Username)
+
+	// a token can only be used for its own purpose
+	_, err = jwtManager.Verify(refreshToken)
+	require.Error(t, err)
+	_, err = jwtManager.VerifyRefreshToken(accessToken)
+	require.Error(t, err)
+}
+
+func TestJWTManagerRevoke(t *testing.T) {
+	t.Parallel()
+
+	jwtManager := service.NewJWTManager(testSecretKey, testTokenDuration, testRefreshTokenDuration)
+	user, err := service.NewUser("user1", "secret", "user")
+	require.NoError(t, err)
+
+	token1, err := jwtManager.Generate(user)
+	require.NoError(t, err)
+	token2, err := jwtManager.Generate(user)
+	require.NoError(t, err)
+
+	claims, err := jwtManager.Verify(token1)
+	require.NoError(t, err)
+
+	require.True(t, jwtManager.Revoke(claims))
+	require.False(t, jwtManager.Revoke(claims))
+	require.True(t, jwtManager.IsRevoked(claims.Id))
+
+	_, err = jwtManager.Verify(token1)
+	require.Error(t, err)
+
+	// other tokens of the same user are still valid
+	_, err = jwtManager.Verify(token2)
+	require.NoError(t, err)
+
+	// every token of the user is revoked, the one already revoked is not counted
+	refreshToken, err := jwtManager.GenerateRefreshToken(user)
+	require.NoError(t, err)
+	require.Equal(t, 2, jwtManager.RevokeUser(user.UserName))
+	require.Equal(t, 0, jwtManager.RevokeUser(user.UserName))
+
+	_, err = jwtManager.Verify(token2)
+	require.Error(t, err)
+	_, err = jwtManager.VerifyRefreshToken(refreshToken)
+	require.Error(t, err)
+}
+
+func TestJWTManagerAsymmetricKeys(t *testing.T) {
+	t.Parallel()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	user, err := service.NewUser("user1", "secret", "user")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		signer crypto.Signer
+		alg    string
+	}{
+		{name: "ecdsa", signer: ecKey, alg: "ES256"},
+		{name: "rsa", signer: rsaKey, alg: "RS256"},
+	} {
+		key, err := service.NewSigningKey(tc.name, tc.signer)
+		require.NoError(t, err)
+		require.Equal(t, tc.alg, key.Method.Alg())
+
+		jwtManager, err := service.NewJWTManagerWithKeys([]*service.SigningKey{key}, testTokenDuration, testRefreshTokenDuration)
+		require.NoError(t, err)
+
+		token, err := jwtManager.Generate(user)
+		require.NoError(t, err)
+
+		claims, err := jwtManager.Verify(token)
+		require.NoError(t, err)
+		require.Equal(t, "user1", claims.Username)
+	}
+}
+
+func TestJWTManagerKeyRotation(t *testing.T) {
+	t.Parallel()
+
+	oldKey := newTestECSigningKey(t, "key-1")
+	newKey := newTestECSigningKey(t, "key-2")
+
+	user, err := service.NewUser("user1", "secret", "user")
+	require.NoError(t, err)
+
+	jwtManager, err := service.NewJWTManagerWithKeys([]*service.SigningKey{oldKey}, testTokenDuration, testRefreshTokenDuration)
+	require.NoError(t, err)
+
+	oldToken, err := jwtManager.Generate(user)
+	require.NoError(t, err)
+
+	// publish the new key first, then switch to it
+	require.NoError(t, jwtManager.AddKey(newKey))
+	require.NoError(t, jwtManager.SetSigningKey("key-2"))
+
+	newToken, err := jwtManager.Generate(user)
+	require.NoError(t, err)
+	requireKeyID(t, "key-2", newToken)
+
+	_, err = jwtManager.Verify(oldToken)
+	require.NoError(t, err)
+	_, err = jwtManager.Verify(newToken)
+	require.NoError(t, err)
+
+	// the signing key cannot be removed
+	require.Error(t, jwtManager.RemoveKey("key-2"))
+
+	// once the old key is retired, its tokens are rejected
+	require.NoError(t, jwtManager.RemoveKey("key-1"))
+	_, err = jwtManager.Verify(oldToken)
+	require.Error(t, err)
+	_, err = jwtManager.Verify(newToken)
+	require.NoError(t, err)
+}
+
+func TestJWTManagerRejectsAlgorithmConfusion(t *testing.T) {
+	t.Parallel()
+
+	key := newTestECSigningKey(t, "key-1")
+	jwtManager, err := service.NewJWTManagerWithKeys([]*service.SigningKey{key}, testTokenDuration, testRefreshTokenDuration)
+	require.NoError(t, err)
+
+	// sign an HMAC token using the public key as secret
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(key.PublicKey())
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, service.UserClaims{Username: "admin1", Role: "admin", TokenType: "access"})
+	token.Header["kid"] = "key-1"
+	forged, err := token.SignedString(publicKeyDER)
+	require.NoError(t, err)
+
+	_, err = jwtManager.Verify(forged)
+	require.Error(t, err)
+}
+
+func TestLoadSigningKeyFromPEM(t *testing.T) {
+	t.Parallel()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	privateFile := filepath.Join(t.TempDir(), "private.pem")
+	require.NoError(t, ioutil.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	der, err = x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+	publicFile := filepath.Join(t.TempDir(), "public.pem")
+	require.NoError(t, ioutil.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+
+	signingKey, err := service.LoadSigningKeyFromPEM("key-1", privateFile)
+	require.NoError(t, err)
+	require.True(t, signingKey.CanSign())
+	require.Equal(t, "ES384", signingKey.Method.Alg())
+
+	verifyingKey, err := service.LoadSigningKeyFromPEM("key-1", publicFile)
+	require.NoError(t, err)
+	require.False(t, verifyingKey.CanSign())
+
+	// another service can verify our tokens with the public key only
+	user, err := service.NewUser("user1", "secret", "user")
+	require.NoError(t, err)
+
+	issuer, err := service.NewJWTManagerWithKeys([]*service.SigningKey{signingKey}, testTokenDuration, testRefreshTokenDuration)
+	require.NoError(t, err)
+	token, err := issuer.Generate(user)
+	require.NoError(t, err)
+
+	verifier := service.NewJWTManager("unused", testTokenDuration, testRefreshTokenDuration)
+	require.NoError(t, verifier.AddKey(verifyingKey))
+	_, err = verifier.Verify(token)
+	require.NoError(t, err)
+}
+
+func TestServerGetPublicKeys(t *testing.T) {
+	t.Parallel()
+
+	ecKey := newTestECSigningKey(t, "ec-key")
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaKey, err := service.NewSigningKey("rsa-key", rsaPrivateKey)
+	require.NoError(t, err)
+
+	keys := []*service.SigningKey{ecKey, rsaKey, service.NewHMACSigningKey("hmac-key", "secret")}
+	jwtManager, err := service.NewJWTManagerWithKeys(keys, testTokenDuration, testRefreshTokenDuration)
+	require.NoError(t, err)
+
+	server := service.NewAuthService(service.NewInMemoryUserStore(), jwtManager)
+	res, err := server.GetPublicKeys(context.Background(), &pd.GetPublicKeysRequest{})
+	require.NoError(t, err)
+
+	// the HMAC secret is never published
+	require.Len(t, res.GetKeys(), 2)
+
+	ecJWK := res.GetKeys()[0]
+	require.Equal(t, "ec-key", ecJWK.GetKid())
+	require.Equal(t, "EC", ecJWK.GetKty())
+	require.Equal(t, "ES256", ecJWK.GetAlg())
+	require.Equal(t, "P-256", ecJWK.GetCrv())
+
+	rsaJWK := res.GetKeys()[1]
+	require.Equal(t, "rsa-key", rsaJWK.GetKid())
+	require.Equal(t, "RSA", rsaJWK.GetKty())
+	require.Equal(t, "RS256", rsaJWK.GetAlg())
+
+	// rebuild the keys from the JWKs and verify tokens offline
+	user, err := service.NewUser("user1", "secret", "user")
+	require.NoError(t, err)
+
+	token, err := jwtManager.Generate(user)
+	require.NoError(t, err)
+	ecPublicKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     decodeBase64URLInt(t, ecJWK.GetX()),
+		Y:     decodeBase64URLInt(t, ecJWK.GetY()),
+	}
+	_, err = jwt.Parse(token, func(token *jwt.Token) (interface{}, error) { return ecPublicKey, nil })
+	require.NoError(t, err)
+
+	require.NoError(t, jwtManager.SetSigningKey("rsa-key"))
+	token, err = jwtManager.Generate(user)
+	require.NoError(t, err)
+	rsaPublicKey := &rsa.PublicKey{
+		N: decodeBase64URLInt(t, rsaJWK.GetN()),
+		E: int(decodeBase64URLInt(t, rsaJWK.GetE()).Int64()),
+	}
+	_, err = jwt.Parse(token, func(token *jwt.Token) (interface{}, error) { return rsaPublicKey, nil })
+	require.NoError(t, err)
+}
+
+func newTestECSigningKey(t *testing.T, id string) *service.SigningKey {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	key, err := service.NewSigningKey(id, privateKey)
+	require.NoError(t, err)
+	return key
+}
+
+func requireKeyID(t *testing.T, keyID string, tokenString string) {
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, &service.UserClaims{})
+	require.NoError(t, err)
+	require.Equal(t, keyID, token.Header["kid"])
+}
+
+func decodeBase64URLInt(t *testing.T, value string) *big.Int {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	require.NoError(t, err)
+	return new(big.Int).SetBytes(data)
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"pc_book/pd"
+	"sync"
+	"time"
+)
+
+// watchBufferSize is the number of events a watcher can fall behind before it is dropped
+const watchBufferSize = 64
+
+// laptopEvent is a change of a laptop in the store
+type laptopEvent struct {
+	// laptop is the new version of the laptop, nil if it was deleted
+	laptop *pd.Laptop
+	id     string
+}
+
+// laptopWatcher receives the events published after it subscribed
+type laptopWatcher struct {
+	// events is closed when the watcher is dropped or unsubscribed
+	events chan laptopEvent
+	// dropped is set with the mutex of the broker when the buffer is full
+	dropped bool
+}
+
+// laptopBroker sends the changes of the laptops to the watchers.
+// publish never waits for a watcher: a watcher whose buffer is full is dropped,
+// so that a slow client cannot block the RPCs that change the store.
+type laptopBroker struct {
+	mutex    sync.Mutex
+	watchers map[*laptopWatcher]bool
+}
+
+func newLaptopBroker() *laptopBroker {
+	return &laptopBroker{watchers: make(map[*laptopWatcher]bool)}
+}
+
+// subscribe returns a watcher that receives the next events
+func (broker *laptopBroker) subscribe(bufferSize int) *laptopWatcher {
+	watcher := &laptopWatcher{events: make(chan laptopEvent, bufferSize)}
+
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	broker.watchers[watcher] = true
+	return watcher
+}
+
+// unsubscribe stops sending events to the watcher, it can be called after the watcher is dropped
+func (broker *laptopBroker) unsubscribe(watcher *laptopWatcher) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	broker.remove(watcher)
+}
+
+// publish sends the event to every watcher
+func (broker *laptopBroker) publish(event laptopEvent) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	for watcher := range broker.watchers {
+		select {
+		case watcher.events <- event:
+		default:
+			// 缓冲区满了, 丢弃这个watcher而不是等待
+			watcher.dropped = true
+			broker.remove(watcher)
+		}
+	}
+}
+
+// dropped returns true if the watcher was dropped because its buffer was full
+func (broker *laptopBroker) dropped(watcher *laptopWatcher) bool {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	return watcher.dropped
+}
+
+// remove must be called with the mutex held
+func (broker *laptopBroker) remove(watcher *laptopWatcher) {
+	if broker.watchers[watcher] {
+		delete(broker.watchers, watcher)
+		close(watcher.events)
+	}
+}
+
+// watchedLaptops remembers the laptops a watcher has been sent, to turn the events into changes of its result set
+type watchedLaptops struct {
+	filter *pd.Filter
+	// sent maps the id of a visible laptop to the version that was sent
+	sent map[string]laptopVersion
+}
+
+// laptopVersion identifies a version of a laptop, updated_at is set by the server on every change
+type laptopVersion struct {
+	etag      string
+	updatedAt time.Time
+}
+
+func newLaptopVersion(laptop *pd.Laptop) laptopVersion {
+	return laptopVersion{etag: LaptopEtag(laptop), updatedAt: laptop.GetUpdatedAt().AsTime()}
+}
+
+func newWatchedLaptops(filter *pd.Filter) *watchedLaptops {
+	return &watchedLaptops{filter: filter, sent: make(map[string]laptopVersion)}
+}
+
+// existing records a laptop of the initial search
+func (watched *watchedLaptops) existing(laptop *pd.Laptop) *pd.WatchLaptopsResponse {
+	watched.sent[laptop.GetId()] = newLaptopVersion(laptop)
+	return &pd.WatchLaptopsResponse{Event: pd.WatchLaptopsResponse_EXISTING, Laptop: laptop}
+}
+
+// change returns the response for an event, or nil if the event doesn't change the result set
+func (watched *watchedLaptops) change(event laptopEvent) *pd.WatchLaptopsResponse {
+	sent, visible := watched.sent[event.id]
+
+	// 初始搜索可能已经发送了这个版本或者更新的版本, 缓冲区中较早的事件已经过时
+	var version laptopVersion
+	if event.laptop != nil {
+		version = newLaptopVersion(event.laptop)
+		if visible && (version.etag == sent.etag || version.updatedAt.Before(sent.updatedAt)) {
+			return nil
+		}
+	}
+
+	if event.laptop == nil || !isQualified(watched.filter, event.laptop) {
+		if !visible {
+			return nil
+		}
+		delete(watched.sent, event.id)
+		return &pd.WatchLaptopsResponse{
+			Event:  pd.WatchLaptopsResponse_REMOVED,
+			Laptop: &pd.Laptop{Id: event.id},
+		}
+	}
+
+	watched.sent[event.id] = version
+
+	res := &pd.WatchLaptopsResponse{Event: pd.WatchLaptopsResponse_ADDED, Laptop: event.laptop}
+	if visible {
+		res.Event = pd.WatchLaptopsResponse_UPDATED
+	}
+	return res
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"pc_book/pd"
+	"pc_book/sample"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLaptopBrokerDropsSlowWatcher(t *testing.T) {
+	t.Parallel()
+
+	broker := newLaptopBroker()
+	slow := broker.subscribe(1)
+	fast := broker.subscribe(3)
+
+	// publish must not wait for the slow watcher
+	for i := 0; i < 3; i++ {
+		broker.publish(laptopEvent{id: sample.NewLaptop().Id})
+	}
+
+	// the slow watcher gets the buffered event, then its channel is closed
+	_, ok := <-slow.events
+	require.True(t, ok)
+	_, ok = <-slow.events
+	require.False(t, ok)
+	require.Len(t, fast.events, 3)
+
+	broker.unsubscribe(slow)
+	broker.unsubscribe(fast)
+	require.Empty(t, broker.watchers)
+}
+
+func TestWatchedLaptopsChange(t *testing.T) {
+	t.Parallel()
+
+	watched := newWatchedLaptops(&pd.Filter{MaxPriceUsd: 2000})
+	laptop := sample.NewLaptop()
+	laptop.PriceUsd = 1000
+	watched.existing(laptop)
+
+	// the same version as the initial search
+	require.Nil(t, watched.change(laptopEvent{laptop: laptop, id: laptop.Id}))
+
+	updated, err := deepCopy(laptop)
+	require.NoError(t, err)
+	updated.PriceUsd = 3000
+	res := watched.change(laptopEvent{laptop: updated, id: laptop.Id})
+	require.Equal(t, pd.WatchLaptopsResponse_REMOVED, res.GetEvent())
+
+	// not visible anymore
+	require.Nil(t, watched.change(laptopEvent{id: laptop.Id}))
+}
+
+func TestWatchedLaptopsDropsOlderVersion(t *testing.T) {
+	t.Parallel()
+
+	watched := newWatchedLaptops(&pd.Filter{MaxPriceUsd: 2000})
+	older := sample.NewLaptop()
+	older.PriceUsd = 1000
+	older.UpdatedAt = timestamppb.New(time.Now().Add(-time.Minute))
+
+	// the initial search has sent a newer version than the buffered events
+	newer, err := deepCopy(older)
+	require.NoError(t, err)
+	newer.PriceUsd = 1500
+	newer.UpdatedAt = timestamppb.Now()
+	watched.existing(newer)
+
+	require.Nil(t, watched.change(laptopEvent{laptop: older, id: older.Id}))
+	older.PriceUsd = 3000
+	require.Nil(t, watched.change(laptopEvent{laptop: older, id: older.Id}), "an older version must not remove the laptop")
+	require.Nil(t, watched.change(laptopEvent{laptop: newer, id: newer.Id}))
+
+	latest, err := deepCopy(newer)
+	require.NoError(t, err)
+	latest.PriceUsd = 1800
+	latest.UpdatedAt = timestamppb.New(time.Now().Add(time.Minute))
+	res := watched.change(laptopEvent{laptop: latest, id: latest.Id})
+	require.Equal(t, pd.WatchLaptopsResponse_UPDATED, res.GetEvent())
+	require.Equal(t, 1800.0, res.GetLaptop().GetPriceUsd())
+}
+
+// fakeWatchStream is the server side of a WatchLaptops stream, onSend is called after each response
+type fakeWatchStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	responses []*pd.WatchLaptopsResponse
+	onSend    func()
+}
+
+func (stream *fakeWatchStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (stream *fakeWatchStream) Send(res *pd.WatchLaptopsResponse) error {
+	stream.responses = append(stream.responses, res)
+	if stream.onSend != nil {
+		stream.onSend()
+	}
+	return nil
+}
+
+func TestWatchLaptopsDroppedDuringSearch(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+	server := NewLaptopService(laptopStore, nil, NewInMemoryRatingStore())
+
+	// the buffer fills up while the existing laptop is sent
+	stream := &fakeWatchStream{ctx: context.Background()}
+	stream.onSend = func() {
+		for i := 0; i <= watchBufferSize; i++ {
+			server.broker.publish(laptopEvent{id: sample.NewLaptop().Id})
+		}
+	}
+
+	err := server.WatchLaptops(&pd.WatchLaptopsRequest{Filter: &pd.Filter{MaxPriceUsd: 1e9}}, stream)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Len(t, stream.responses, 1)
+	require.Equal(t, pd.WatchLaptopsResponse_EXISTING, stream.responses[0].GetEvent())
+}
+
+func TestLaptopServicePublishesInOrder(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := NewInMemoryLaptopStore()
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+	server := NewLaptopService(laptopStore, nil, NewInMemoryRatingStore())
+
+	watcher := server.broker.subscribe(1000)
+	defer server.broker.unsubscribe(watcher)
+
+	// concurrent updates, some of them fail because the laptop changed since they read it
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			server.UpdateLaptop(context.Background(), &pd.UpdateLaptopRequest{
+				Laptop:     &pd.Laptop{Id: laptop.Id, PriceUsd: float64(1000 + i)},
+				UpdateMask: &field_mask.FieldMask{Paths: []string{"price_usd"}},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	// the last event is the version in the store
+	require.NotEmpty(t, watcher.events)
+	var last laptopEvent
+	for len(watcher.events) > 0 {
+		last = <-watcher.events
+	}
+	stored, err := laptopStore.Find(laptop.Id)
+	require.NoError(t, err)
+	require.Equal(t, LaptopEtag(stored), LaptopEtag(last.laptop))
+}
@@ -2,11 +2,19 @@ package service_test
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/protobuf/field_mask"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"image/png"
 	"io"
+	"io/ioutil"
 	"net"
 	"os"
 	"path/filepath"
@@ -14,7 +22,9 @@ import (
 	"pc_book/sample"
 	"pc_book/serializer"
 	"pc_book/service"
+	"sort"
 	"testing"
+	"time"
 )
 
 func TestClientCreateLaptop(t *testing.T) {
@@ -41,6 +51,10 @@ func TestClientCreateLaptop(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, other)
 
+	// updated_at is set by the server
+	require.False(t, other.UpdatedAt.AsTime().Before(laptop.UpdatedAt.AsTime()))
+	laptop.UpdatedAt = other.UpdatedAt
+
 	// check that the saved laptop is the same as the one we send
 	// 将protobuf文件转换成json格式再进行比较
 	requireSameLaptop(t, laptop, other)
@@ -117,23 +131,385 @@ func TestClientSearchLaptop(t *testing.T) {
 	require.Equal(t, len(expectedIDs), found)
 }
 
+func TestClientSearchLaptopPages(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	var laptops []*pd.Laptop
+	for i := 0; i < 7; i++ {
+		laptop := sample.NewLaptop()
+		require.NoError(t, laptopStore.Save(laptop))
+		laptops = append(laptops, laptop)
+	}
+	sort.Slice(laptops, func(i, j int) bool { return laptops[i].PriceUsd > laptops[j].PriceUsd })
+
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	req := &pd.SearchLaptopRequest{
+		Filter:     &pd.Filter{MaxPriceUsd: 5000},
+		SortBy:     pd.SearchLaptopRequest_PRICE,
+		Descending: true,
+		PageSize:   3,
+	}
+
+	var ids []string
+	for pages := 1; ; pages++ {
+		stream, err := laptopClient.SearchLaptop(context.Background(), req)
+		require.NoError(t, err)
+
+		var last *pd.SearchLaptopResponse
+		count := 0
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			ids = append(ids, res.GetLaptop().GetId())
+			last = res
+			count++
+		}
+		require.LessOrEqual(t, count, 3)
+
+		if last.GetNextPageToken() == "" {
+			require.Equal(t, 3, pages)
+			break
+		}
+		req.PageToken = last.GetNextPageToken()
+	}
+
+	require.Len(t, ids, len(laptops))
+	for i, laptop := range laptops {
+		require.Equal(t, laptop.Id, ids[i])
+	}
+
+	// a token cannot be used with another sort order
+	req.Descending = false
+	stream, err := laptopClient.SearchLaptop(context.Background(), req)
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	requireCode(t, codes.InvalidArgument, err)
+}
+
+func TestClientSearchLaptopText(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	expectedIDs := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		laptop := sample.NewLaptop()
+		if i < 3 {
+			laptop.Name = fmt.Sprintf("Thinkpad T%d", i)
+			expectedIDs[laptop.Id] = true
+		} else {
+			laptop.Name = "Macbook Air"
+		}
+		require.NoError(t, laptopStore.Save(laptop))
+	}
+
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	req := &pd.SearchLaptopRequest{
+		Filter:     &pd.Filter{MaxPriceUsd: 5000},
+		Query:      "thinkpad",
+		SortBy:     pd.SearchLaptopRequest_RELEVANCE,
+		Descending: true,
+		PageSize:   2,
+	}
+
+	found := make(map[string]bool)
+	for {
+		stream, err := laptopClient.SearchLaptop(context.Background(), req)
+		require.NoError(t, err)
+
+		var last *pd.SearchLaptopResponse
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			require.Greater(t, res.GetScore(), 0.0)
+			found[res.GetLaptop().GetId()] = true
+			last = res
+		}
+
+		if last.GetNextPageToken() == "" {
+			break
+		}
+		req.PageToken = last.GetNextPageToken()
+	}
+	require.Equal(t, expectedIDs, found)
+}
+
+func TestClientImportLaptops(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	existing := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(existing))
+
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	importLaptops := func(options *pd.ImportOptions, laptops []*pd.Laptop) []*pd.ImportLaptopsResponse {
+		stream, err := laptopClient.ImportLaptops(context.Background())
+		require.NoError(t, err)
+
+		// 一边发送一边接收, 大量laptop时不会阻塞
+		var results []*pd.ImportLaptopsResponse
+		waitResponse := make(chan error)
+		go func() {
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					waitResponse <- nil
+					return
+				}
+				if err != nil {
+					waitResponse <- err
+					return
+				}
+				results = append(results, res)
+			}
+		}()
+
+		if options != nil {
+			err := stream.Send(&pd.ImportLaptopsRequest{Data: &pd.ImportLaptopsRequest_Options{Options: options}})
+			require.NoError(t, err)
+		}
+		for _, laptop := range laptops {
+			err := stream.Send(&pd.ImportLaptopsRequest{Data: &pd.ImportLaptopsRequest_Laptop{Laptop: laptop}})
+			require.NoError(t, err)
+		}
+		require.NoError(t, stream.CloseSend())
+
+		require.NoError(t, <-waitResponse)
+		require.Len(t, results, len(laptops))
+		return results
+	}
+
+	invalid := sample.NewLaptop()
+	invalid.Id = "invalid-uuid"
+
+	laptops := make([]*pd.Laptop, 1000)
+	for i := range laptops {
+		laptops[i] = sample.NewLaptop()
+	}
+	laptops[10] = existing
+	laptops[20] = invalid
+
+	// one failure doesn't stop the others
+	for i, res := range importLaptops(nil, laptops) {
+		require.Equal(t, uint32(i), res.GetIndex())
+
+		switch i {
+		case 10:
+			require.Equal(t, pd.ImportLaptopsResponse_ALREADY_EXISTS, res.GetResult())
+		case 20:
+			require.Equal(t, pd.ImportLaptopsResponse_INVALID_ARGUMENT, res.GetResult())
+			require.NotEmpty(t, res.GetError())
+		default:
+			require.Equal(t, pd.ImportLaptopsResponse_OK, res.GetResult())
+			require.Equal(t, laptops[i].Id, res.GetId())
+			require.NotEmpty(t, res.GetEtag())
+		}
+	}
+
+	other, err := laptopStore.Find(laptops[999].Id)
+	require.NoError(t, err)
+	require.NotNil(t, other)
+
+	// a transactional import saves nothing when one laptop fails
+	batch := []*pd.Laptop{sample.NewLaptop(), existing, sample.NewLaptop()}
+	results := importLaptops(&pd.ImportOptions{Transactional: true}, batch)
+	require.Equal(t, pd.ImportLaptopsResponse_ABORTED, results[0].GetResult())
+	require.Equal(t, pd.ImportLaptopsResponse_ALREADY_EXISTS, results[1].GetResult())
+	require.Equal(t, pd.ImportLaptopsResponse_ABORTED, results[2].GetResult())
+
+	other, err = laptopStore.Find(batch[0].Id)
+	require.NoError(t, err)
+	require.Nil(t, other)
+
+	batch[1] = sample.NewLaptop()
+	for i, res := range importLaptops(&pd.ImportOptions{Transactional: true}, batch) {
+		require.Equal(t, pd.ImportLaptopsResponse_OK, res.GetResult())
+
+		other, err := laptopStore.Find(batch[i].Id)
+		require.NoError(t, err)
+		require.NotNil(t, other)
+		require.Equal(t, res.GetEtag(), service.LaptopEtag(other))
+	}
+}
+
+func TestClientExportLaptops(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	laptops := make(map[string]*pd.Laptop)
+	for i := 0; i < 200; i++ {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = float64(1000 + i*10)
+		require.NoError(t, laptopStore.Save(laptop))
+		laptops[laptop.Id] = laptop
+	}
+
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	export := func(req *pd.ExportLaptopsRequest) []byte {
+		stream, err := laptopClient.ExportLaptops(context.Background(), req)
+		require.NoError(t, err)
+
+		var data []byte
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				return data
+			}
+			require.NoError(t, err)
+			data = append(data, res.GetChunkData()...)
+		}
+	}
+
+	formats := map[pd.ExportLaptopsRequest_Format]func(r io.Reader) serializer.MessageReader{
+		pd.ExportLaptopsRequest_JSON_LINES: func(r io.Reader) serializer.MessageReader { return serializer.NewJSONLinesReader(r) },
+		pd.ExportLaptopsRequest_PROTOBUF:   func(r io.Reader) serializer.MessageReader { return serializer.NewDelimitedReader(r) },
+		pd.ExportLaptopsRequest_CSV:        func(r io.Reader) serializer.MessageReader { return serializer.NewCSVReader(r, &pd.Laptop{}) },
+	}
+
+	for format, newReader := range formats {
+		data := export(&pd.ExportLaptopsRequest{Format: format})
+
+		// every laptop is exported with no loss
+		reader := newReader(bytes.NewReader(data))
+		found := 0
+		for {
+			laptop := &pd.Laptop{}
+			err := reader.Read(laptop)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			requireSameLaptop(t, laptops[laptop.Id], laptop)
+			found++
+		}
+		require.Equal(t, len(laptops), found, format.String())
+
+		// only the laptops matching the filter
+		data = export(&pd.ExportLaptopsRequest{Format: format, Filter: &pd.Filter{MaxPriceUsd: 1495}})
+		reader = newReader(bytes.NewReader(data))
+		found = 0
+		for {
+			laptop := &pd.Laptop{}
+			err := reader.Read(laptop)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			require.LessOrEqual(t, laptop.PriceUsd, 1495.0)
+			found++
+		}
+		require.Equal(t, 50, found, format.String())
+	}
+
+	stream, err := laptopClient.ExportLaptops(context.Background(), &pd.ExportLaptopsRequest{Format: 10})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestClientWatchLaptops(t *testing.T) {
+	t.Parallel()
+
+	newLaptop := func(price float64) *pd.Laptop {
+		laptop := sample.NewLaptop()
+		laptop.PriceUsd = price
+		return laptop
+	}
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	cheap1, cheap2, expensive := newLaptop(1000), newLaptop(1000), newLaptop(3000)
+	for _, laptop := range []*pd.Laptop{cheap1, cheap2, expensive} {
+		require.NoError(t, laptopStore.Save(laptop))
+	}
+
+	serverAddress := startTestLaptopServer(t, laptopStore, nil, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+	ctx := context.Background()
+
+	stream, err := laptopClient.WatchLaptops(ctx, &pd.WatchLaptopsRequest{Filter: &pd.Filter{MaxPriceUsd: 2000}})
+	require.NoError(t, err)
+
+	requireEvent := func(event pd.WatchLaptopsResponse_Event, id string) *pd.WatchLaptopsResponse {
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, event, res.GetEvent())
+		require.Equal(t, id, res.GetLaptop().GetId())
+		return res
+	}
+
+	var existing []string
+	for i := 0; i < 2; i++ {
+		res, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, pd.WatchLaptopsResponse_EXISTING, res.GetEvent())
+		existing = append(existing, res.GetLaptop().GetId())
+	}
+	require.ElementsMatch(t, []string{cheap1.Id, cheap2.Id}, existing)
+
+	setPrice := func(id string, price float64) {
+		_, err := laptopClient.UpdateLaptop(ctx, &pd.UpdateLaptopRequest{
+			Laptop:     &pd.Laptop{Id: id, PriceUsd: price},
+			UpdateMask: &field_mask.FieldMask{Paths: []string{"price_usd"}},
+		})
+		require.NoError(t, err)
+	}
+
+	// a laptop that doesn't match the filter sends no event
+	_, err = laptopClient.CreateLaptop(ctx, &pd.CreateLaptopRequest{Laptop: newLaptop(4000)})
+	require.NoError(t, err)
+
+	created := newLaptop(1500)
+	_, err = laptopClient.CreateLaptop(ctx, &pd.CreateLaptopRequest{Laptop: created})
+	require.NoError(t, err)
+	requireEvent(pd.WatchLaptopsResponse_ADDED, created.Id)
+
+	setPrice(cheap1.Id, 1100)
+	res := requireEvent(pd.WatchLaptopsResponse_UPDATED, cheap1.Id)
+	require.Equal(t, 1100.0, res.GetLaptop().GetPriceUsd())
+
+	setPrice(cheap2.Id, 2500)
+	requireEvent(pd.WatchLaptopsResponse_REMOVED, cheap2.Id)
+
+	setPrice(expensive.Id, 1200)
+	requireEvent(pd.WatchLaptopsResponse_ADDED, expensive.Id)
+
+	_, err = laptopClient.DeleteLaptop(ctx, &pd.DeleteLaptopRequest{Id: created.Id})
+	require.NoError(t, err)
+	requireEvent(pd.WatchLaptopsResponse_REMOVED, created.Id)
+}
+
 // TestClientUploadImage 上传图片的测试
 func TestClientUploadImage(t *testing.T) {
 	t.Parallel()
 
-	testImageFolder := "../tmp"
+	testImageFolder := t.TempDir()
 
 	laptopStore := service.NewInMemoryLaptopStore()
-	imageStore := service.NewDiskImageStore(testImageFolder)
+	imageStore, err := service.NewDiskImageStore(testImageFolder)
+	require.NoError(t, err)
 
 	laptop := sample.NewLaptop()
-	err := laptopStore.Save(laptop)
+	err = laptopStore.Save(laptop)
 	require.NoError(t, err)
 
 	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
 	laptopClient := newTestLaptopClient(t, serverAddress)
 
-	imagePath := fmt.Sprintf("%s/laptop.png", testImageFolder)
+	imagePath := "../tmp/laptop.png"
 	file, err := os.Open(imagePath)
 	require.NoError(t, err)
 	defer file.Close()
@@ -182,9 +558,442 @@ func TestClientUploadImage(t *testing.T) {
 	require.NotZero(t, res.GetId())
 	require.EqualValues(t, size, res.GetSize())
 
-	savedImagePath := fmt.Sprintf("%s/%s%s", testImageFolder, res.GetId(), imageType)
-	require.FileExists(t, savedImagePath)
-	require.NoError(t, os.Remove(savedImagePath))
+	// the data is stored in the blob of its digest
+	imageData, err := os.ReadFile(imagePath)
+	require.NoError(t, err)
+	digest := sha256.Sum256(imageData)
+	require.Equal(t, hex.EncodeToString(digest[:]), res.GetDigest())
+	require.FileExists(t, filepath.Join(testImageFolder, "blobs", res.GetDigest()))
+}
+
+func TestClientDownloadListDeleteImage(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore, err := service.NewDiskImageStore(t.TempDir())
+	require.NoError(t, err)
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+	ctx := context.Background()
+
+	imageData, err := os.ReadFile("../tmp/laptop.png")
+	require.NoError(t, err)
+
+	first := uploadTestImage(t, laptopClient, laptop.GetId(), ".png", imageData)
+	second := uploadTestImage(t, laptopClient, laptop.GetId(), ".png", imageData[:100])
+
+	list, err := laptopClient.ListImages(ctx, &pd.ListImagesRequest{LaptopId: laptop.GetId()})
+	require.NoError(t, err)
+	require.Len(t, list.GetImages(), 2)
+	require.Equal(t, first.GetId(), list.GetImages()[0].GetId())
+	require.Equal(t, uint64(len(imageData)), list.GetImages()[0].GetSize())
+	require.Equal(t, second.GetId(), list.GetImages()[1].GetId())
+
+	list, err = laptopClient.ListImages(ctx, &pd.ListImagesRequest{LaptopId: sample.NewLaptop().GetId()})
+	require.NoError(t, err)
+	require.Empty(t, list.GetImages())
+
+	info, downloaded := downloadTestImage(t, laptopClient, first.GetId(), "")
+	require.Equal(t, laptop.GetId(), info.GetLaptopId())
+	require.Equal(t, ".png", info.GetImageType())
+	require.Equal(t, imageData, downloaded)
+
+	_, err = laptopClient.DeleteImage(ctx, &pd.DeleteImageRequest{ImageId: first.GetId()})
+	require.NoError(t, err)
+
+	_, err = laptopClient.DeleteImage(ctx, &pd.DeleteImageRequest{ImageId: first.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	stream, err := laptopClient.DownloadImage(ctx, &pd.DownloadImageRequest{ImageId: first.GetId()})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	list, err = laptopClient.ListImages(ctx, &pd.ListImagesRequest{LaptopId: laptop.GetId()})
+	require.NoError(t, err)
+	require.Len(t, list.GetImages(), 1)
+	require.Equal(t, second.GetId(), list.GetImages()[0].GetId())
+}
+
+// downloadTestImage returns the info sent by DownloadImage and the data of the image or of its rendition
+func downloadTestImage(t *testing.T, laptopClient pd.LaptopServiceClient, imageID string, rendition string) (*pd.ImageInfo, []byte) {
+	stream, err := laptopClient.DownloadImage(context.Background(), &pd.DownloadImageRequest{ImageId: imageID, Rendition: rendition})
+	require.NoError(t, err)
+
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	info := res.GetInfo()
+	require.NotNil(t, info)
+
+	var data []byte
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data = append(data, res.GetChunkData()...)
+	}
+	return info, data
+}
+
+func TestClientImageRenditions(t *testing.T) {
+	t.Parallel()
+
+	testImageFolder := t.TempDir()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore, err := service.NewDiskImageStore(testImageFolder)
+	require.NoError(t, err)
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+	ctx := context.Background()
+
+	// laptop.png is 716x128
+	imageData, err := os.ReadFile("../tmp/laptop.png")
+	require.NoError(t, err)
+	uploaded := uploadTestImage(t, laptopClient, laptop.GetId(), ".png", imageData)
+
+	list, err := laptopClient.ListImages(ctx, &pd.ListImagesRequest{LaptopId: laptop.GetId()})
+	require.NoError(t, err)
+	require.Len(t, list.GetImages(), 1)
+
+	expected := map[string][2]int{
+		"small":  {160, 28},
+		"medium": {480, 85},
+		"large":  {716, 128},
+	}
+	renditions := list.GetImages()[0].GetRenditions()
+	require.Len(t, renditions, len(expected))
+
+	for _, rendition := range renditions {
+		size, ok := expected[rendition.GetName()]
+		require.True(t, ok, rendition.GetName())
+		require.Equal(t, ".png", rendition.GetImageType())
+		require.Equal(t, uint32(size[0]), rendition.GetWidth())
+		require.Equal(t, uint32(size[1]), rendition.GetHeight())
+
+		info, data := downloadTestImage(t, laptopClient, uploaded.GetId(), rendition.GetName())
+		require.Len(t, info.GetRenditions(), len(expected))
+		require.Equal(t, rendition.GetSize(), uint64(len(data)))
+
+		img, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		require.Equal(t, size[0], img.Bounds().Dx())
+		require.Equal(t, size[1], img.Bounds().Dy())
+	}
+
+	stream, err := laptopClient.DownloadImage(ctx, &pd.DownloadImageRequest{ImageId: uploaded.GetId(), Rendition: "huge"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// the renditions are deleted with the image
+	_, err = laptopClient.DeleteImage(ctx, &pd.DeleteImageRequest{ImageId: uploaded.GetId()})
+	require.NoError(t, err)
+
+	files, err := ioutil.ReadDir(filepath.Join(testImageFolder, "blobs"))
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestClientUploadImageCanceled(t *testing.T) {
+	t.Parallel()
+
+	testImageFolder := t.TempDir()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore, err := service.NewDiskImageStore(testImageFolder)
+	require.NoError(t, err)
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := laptopClient.UploadImage(ctx)
+	require.NoError(t, err)
+
+	err = stream.Send(&pd.UploadImageRequest{
+		Data: &pd.UploadImageRequest_Info{
+			Info: &pd.ImageInfo{LaptopId: laptop.GetId(), ImageType: ".png"},
+		},
+	})
+	require.NoError(t, err)
+	imageData, err := os.ReadFile("../tmp/laptop.png")
+	require.NoError(t, err)
+	err = stream.Send(&pd.UploadImageRequest{
+		Data: &pd.UploadImageRequest_ChunkData{ChunkData: imageData[:1024]},
+	})
+	require.NoError(t, err)
+
+	// wait for the server to write the first chunk to its temporary file
+	require.Eventually(t, func() bool {
+		files, err := ioutil.ReadDir(filepath.Join(testImageFolder, "blobs"))
+		return err == nil && len(files) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	// the temporary file is removed and no image is saved
+	require.Eventually(t, func() bool {
+		files, err := ioutil.ReadDir(filepath.Join(testImageFolder, "blobs"))
+		return err == nil && len(files) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	images, err := imageStore.List(laptop.GetId())
+	require.NoError(t, err)
+	require.Empty(t, images)
+}
+
+func TestClientResumeUploadImage(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore, err := service.NewDiskImageStore(t.TempDir())
+	require.NoError(t, err)
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+	ctx := context.Background()
+
+	imageData, err := os.ReadFile("../tmp/laptop.png")
+	require.NoError(t, err)
+	half := len(imageData) / 2
+
+	// the first stream fails after half of the image
+	stream := startResumableUpload(t, laptopClient, "resume-1", laptop.GetId())
+	sendUploadChunks(t, stream, imageData[:half], 0)
+	sendUploadChunks(t, stream, imageData[half:half+10], half+10)
+	_, err = stream.CloseAndRecv()
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	query, err := laptopClient.QueryUpload(ctx, &pd.QueryUploadRequest{UploadId: "resume-1"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(half), query.GetOffset())
+	require.Equal(t, laptop.GetId(), query.GetLaptopId())
+	require.Empty(t, query.GetImageId())
+
+	// the data already received is skipped
+	stream = startResumableUpload(t, laptopClient, "resume-1", laptop.GetId())
+	sendUploadChunks(t, stream, imageData[half-100:], half-100)
+	res, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.Equal(t, uint32(len(imageData)), res.GetSize())
+
+	digest := sha256.Sum256(imageData)
+	require.Equal(t, hex.EncodeToString(digest[:]), res.GetDigest())
+	_, downloaded := downloadTestImage(t, laptopClient, res.GetId(), "")
+	require.Equal(t, imageData, downloaded)
+
+	query, err = laptopClient.QueryUpload(ctx, &pd.QueryUploadRequest{UploadId: "resume-1"})
+	require.NoError(t, err)
+	require.Equal(t, res.GetId(), query.GetImageId())
+
+	// a client that didn't receive the response gets the same image
+	stream = startResumableUpload(t, laptopClient, "resume-1", laptop.GetId())
+	again, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.Equal(t, res.GetId(), again.GetId())
+
+	stream = startResumableUpload(t, laptopClient, "resume-1", sample.NewLaptop().GetId())
+	_, err = stream.CloseAndRecv()
+	require.Error(t, err)
+
+	_, err = laptopClient.QueryUpload(ctx, &pd.QueryUploadRequest{UploadId: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// an upload whose data is not an image is removed
+	stream = startResumableUpload(t, laptopClient, "resume-2", laptop.GetId())
+	sendUploadChunks(t, stream, []byte("#!/bin/sh\necho hello\n"), 0)
+	_, err = stream.CloseAndRecv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = laptopClient.QueryUpload(ctx, &pd.QueryUploadRequest{UploadId: "resume-2"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	images, err := imageStore.List(laptop.GetId())
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+}
+
+// plainImageStore hides the resumable uploads of the store it wraps
+type plainImageStore struct {
+	service.ImageStore
+}
+
+func TestClientUploadImageWithoutResume(t *testing.T) {
+	t.Parallel()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	diskStore, err := service.NewDiskImageStore(t.TempDir())
+	require.NoError(t, err)
+	imageStore := plainImageStore{diskStore}
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	imageData, err := os.ReadFile("../tmp/laptop.png")
+	require.NoError(t, err)
+
+	// the image is received at once, the upload cannot be queried
+	stream := startResumableUpload(t, laptopClient, "upload", laptop.GetId())
+	sendUploadChunks(t, stream, imageData, 0)
+	res, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.Equal(t, uint32(len(imageData)), res.GetSize())
+
+	_, err = laptopClient.QueryUpload(context.Background(), &pd.QueryUploadRequest{UploadId: "upload"})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func startResumableUpload(t *testing.T, laptopClient pd.LaptopServiceClient, uploadID string, laptopID string) pd.LaptopService_UploadImageClient {
+	stream, err := laptopClient.UploadImage(context.Background())
+	require.NoError(t, err)
+
+	err = stream.Send(&pd.UploadImageRequest{
+		Data: &pd.UploadImageRequest_Info{
+			Info: &pd.ImageInfo{LaptopId: laptopID, ImageType: ".png", UploadId: uploadID},
+		},
+	})
+	require.NoError(t, err)
+	return stream
+}
+
+// sendUploadChunks sends the data by chunks of 1KB with their offsets, starting from offset
+func sendUploadChunks(t *testing.T, stream pd.LaptopService_UploadImageClient, data []byte, offset int) {
+	for len(data) > 0 {
+		n := 1024
+		if n > len(data) {
+			n = len(data)
+		}
+		err := stream.Send(&pd.UploadImageRequest{
+			Data: &pd.UploadImageRequest_Chunk{
+				Chunk: &pd.ImageChunk{Data: data[:n], Offset: uint64(offset)},
+			},
+		})
+		require.NoError(t, err)
+		data = data[n:]
+		offset += n
+	}
+}
+
+// uploadTestImage uploads the image data by chunks of 1KB
+func uploadTestImage(t *testing.T, laptopClient pd.LaptopServiceClient, laptopID string, imageType string, imageData []byte) *pd.UploadImageResponse {
+	res, err := sendTestImage(t, laptopClient, laptopID, imageType, imageData)
+	require.NoError(t, err)
+	return res
+}
+
+// sendTestImage uploads an image by chunks of 1KB, it returns the error of the server
+func sendTestImage(t *testing.T, laptopClient pd.LaptopServiceClient, laptopID string, imageType string, imageData []byte) (*pd.UploadImageResponse, error) {
+	stream, err := laptopClient.UploadImage(context.Background())
+	require.NoError(t, err)
+
+	err = stream.Send(&pd.UploadImageRequest{
+		Data: &pd.UploadImageRequest_Info{
+			Info: &pd.ImageInfo{LaptopId: laptopID, ImageType: imageType},
+		},
+	})
+	require.NoError(t, err)
+
+	for len(imageData) > 0 {
+		n := 1024
+		if n > len(imageData) {
+			n = len(imageData)
+		}
+		err := stream.Send(&pd.UploadImageRequest{
+			Data: &pd.UploadImageRequest_ChunkData{ChunkData: imageData[:n]},
+		})
+		if err == io.EOF {
+			// the server has already returned its error
+			break
+		}
+		require.NoError(t, err)
+		imageData = imageData[n:]
+	}
+
+	return stream.CloseAndRecv()
+}
+
+func TestClientUploadImageFormat(t *testing.T) {
+	t.Parallel()
+
+	testImageFolder := t.TempDir()
+
+	laptopStore := service.NewInMemoryLaptopStore()
+	imageStore, err := service.NewDiskImageStore(testImageFolder)
+	require.NoError(t, err)
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, laptopStore.Save(laptop))
+
+	serverAddress := startTestLaptopServer(t, laptopStore, imageStore, nil)
+	laptopClient := newTestLaptopClient(t, serverAddress)
+
+	png, err := os.ReadFile("../tmp/laptop.png")
+	require.NoError(t, err)
+	jpeg := []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	gif := []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;")
+	webp := []byte("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00")
+
+	testCases := []struct {
+		name      string
+		imageType string
+		data      []byte
+		code      codes.Code
+		extension string
+	}{
+		{name: "png", imageType: ".png", data: png, code: codes.OK, extension: ".png"},
+		{name: "jpeg", imageType: "jpeg", data: jpeg, code: codes.OK, extension: ".jpg"},
+		{name: "gif mime type", imageType: "image/gif", data: gif, code: codes.OK, extension: ".gif"},
+		{name: "webp", imageType: ".WEBP", data: webp, code: codes.OK, extension: ".webp"},
+		{name: "detected type", imageType: "", data: gif, code: codes.OK, extension: ".gif"},
+		{name: "path", imageType: "/../../etc", data: png, code: codes.InvalidArgument},
+		{name: "executable", imageType: ".exe", data: png, code: codes.InvalidArgument},
+		{name: "mismatch", imageType: ".png", data: jpeg, code: codes.InvalidArgument},
+		{name: "unknown format", imageType: ".png", data: []byte("#!/bin/sh\necho hello\n"), code: codes.InvalidArgument},
+		{name: "empty", imageType: ".png", data: nil, code: codes.InvalidArgument},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := sendTestImage(t, laptopClient, laptop.GetId(), tc.imageType, tc.data)
+			if tc.code != codes.OK {
+				require.Error(t, err)
+				require.Equal(t, tc.code, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+
+			// the file name is built from the image id and the detected format
+			info, err := imageStore.Find(res.GetId())
+			require.NoError(t, err)
+			require.Equal(t, tc.extension, info.Type)
+			require.Equal(t, filepath.Join(testImageFolder, "blobs", res.GetDigest()), info.Path)
+		})
+	}
+
+	images, err := imageStore.List(laptop.GetId())
+	require.NoError(t, err)
+	require.Len(t, images, 5)
 }
 
 func TestClientRateLaptop(t *testing.T) {
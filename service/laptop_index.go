@@ -0,0 +1,166 @@
+package service
+
+import (
+	"pc_book/pd"
+	"sort"
+)
+
+// indexEntry is the position of a laptop in a laptopIndex
+type indexEntry struct {
+	key float64
+	id  string
+}
+
+// laptopIndex keeps the laptops sorted by one field, so that a filter condition on it is a range
+type laptopIndex struct {
+	name    string
+	key     func(laptop *pd.Laptop) float64
+	entries []indexEntry
+	// atMost is true if the filter gives a maximum of the key, false if it gives a minimum
+	atMost bool
+	// bound returns the maximum or minimum key of the filter, ok is false if the filter doesn't limit the key
+	bound func(filter *pd.Filter) (value float64, ok bool)
+	// sortBy is the order of the search that the key gives, ID if the key is not a sort value
+	sortBy pd.SearchLaptopRequest_SortBy
+}
+
+// newLaptopIndexes returns the indexes of InMemoryLaptopStore, one for each numeric condition of pd.Filter
+func newLaptopIndexes() []*laptopIndex {
+	return []*laptopIndex{
+		{
+			name:   "price_usd",
+			key:    func(laptop *pd.Laptop) float64 { return laptop.GetPriceUsd() },
+			atMost: true,
+			bound: func(filter *pd.Filter) (float64, bool) {
+				return filter.GetMaxPriceUsd(), true
+			},
+			sortBy: pd.SearchLaptopRequest_PRICE,
+		},
+		{
+			name: "cpu_cores",
+			key:  func(laptop *pd.Laptop) float64 { return float64(laptop.GetCpu().GetNumberCores()) },
+			bound: func(filter *pd.Filter) (float64, bool) {
+				return float64(filter.GetMinCpuCores()), filter.GetMinCpuCores() > 0
+			},
+		},
+		{
+			name: "cpu_min_ghz",
+			key:  func(laptop *pd.Laptop) float64 { return laptop.GetCpu().GetMinGhz() },
+			bound: func(filter *pd.Filter) (float64, bool) {
+				return filter.GetMinCpuGhz(), filter.GetMinCpuGhz() > 0
+			},
+			sortBy: pd.SearchLaptopRequest_CPU_GHZ,
+		},
+		{
+			// float64 loses precision for huge values but keeps the order, so the range can only be too large
+			name: "ram_bits",
+			key:  func(laptop *pd.Laptop) float64 { return float64(toBit(laptop.GetRam())) },
+			bound: func(filter *pd.Filter) (float64, bool) {
+				bits := toBit(filter.GetMinRam())
+				return float64(bits), bits > 0
+			},
+			sortBy: pd.SearchLaptopRequest_RAM,
+		},
+	}
+}
+
+// search returns the position of the first entry that is not before (key, id)
+func (index *laptopIndex) search(key float64, id string) int {
+	return sort.Search(len(index.entries), func(i int) bool {
+		entry := index.entries[i]
+		return entry.key > key || (entry.key == key && entry.id >= id)
+	})
+}
+
+func (index *laptopIndex) insert(laptop *pd.Laptop) {
+	entry := indexEntry{key: index.key(laptop), id: laptop.GetId()}
+	i := index.search(entry.key, entry.id)
+
+	index.entries = append(index.entries, indexEntry{})
+	copy(index.entries[i+1:], index.entries[i:])
+	index.entries[i] = entry
+}
+
+func (index *laptopIndex) remove(laptop *pd.Laptop) {
+	key := index.key(laptop)
+	i := index.search(key, laptop.GetId())
+	if i < len(index.entries) && index.entries[i].id == laptop.GetId() {
+		index.entries = append(index.entries[:i], index.entries[i+1:]...)
+	}
+}
+
+// candidates returns the entries that satisfy the condition of the filter on the key,
+// ok is false if the filter has no condition on it
+func (index *laptopIndex) candidates(filter *pd.Filter) (entries []indexEntry, ok bool) {
+	bound, ok := index.bound(filter)
+	if !ok {
+		return nil, false
+	}
+
+	// 二分查找条件的边界
+	i := sort.Search(len(index.entries), func(i int) bool {
+		if index.atMost {
+			return index.entries[i].key > bound
+		}
+		return index.entries[i].key >= bound
+	})
+
+	if index.atMost {
+		return index.entries[:i], true
+	}
+	return index.entries[i:], true
+}
+
+// planSearch returns the entries of the most selective index for the filter,
+// ok is false if no index can be used and every laptop must be checked
+func planSearch(indexes []*laptopIndex, filter *pd.Filter) (entries []indexEntry, ok bool) {
+	for _, index := range indexes {
+		candidates, usable := index.candidates(filter)
+		if usable && (!ok || len(candidates) < len(entries)) {
+			entries, ok = candidates, true
+		}
+	}
+	return entries, ok
+}
+
+// sortIndex returns the index whose key is the sort value of the query, nil if the order has no index
+func sortIndex(indexes []*laptopIndex, query *SearchQuery) *laptopIndex {
+	if query.SortBy == pd.SearchLaptopRequest_ID {
+		return nil
+	}
+	for _, index := range indexes {
+		if index.sortBy == query.SortBy {
+			return index
+		}
+	}
+	return nil
+}
+
+// page returns the entries that satisfy the condition of the filter on the key and come after the cursor
+// of the query, in the order of the key: a descending query reads them from the end
+func (index *laptopIndex) page(query *SearchQuery) []indexEntry {
+	entries := index.entries
+	if candidates, ok := index.candidates(query.Filter); ok {
+		entries = candidates
+	}
+	if query.After == nil {
+		return entries
+	}
+
+	after := query.After
+	if query.Descending {
+		// the entries before the cursor
+		i := sort.Search(len(entries), func(i int) bool {
+			entry := entries[i]
+			return entry.key > after.Value || (entry.key == after.Value && entry.id >= after.ID)
+		})
+		return entries[:i]
+	}
+
+	// the entries after the cursor
+	i := sort.Search(len(entries), func(i int) bool {
+		entry := entries[i]
+		return entry.key > after.Value || (entry.key == after.Value && entry.id > after.ID)
+	})
+	return entries[i:]
+}
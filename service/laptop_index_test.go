@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"pc_book/pd"
+	"pc_book/sample"
+	"testing"
+)
+
+// fullScan is the search without indexes, it checks every laptop of the store
+func fullScan(store *InMemoryLaptopStore, query *SearchQuery) []string {
+	top := newTopResults(query)
+	store.forEach(func(laptop *pd.Laptop) error {
+		if isQualified(query.Filter, laptop) {
+			top.add(laptop, 0)
+		}
+		return nil
+	})
+
+	var ids []string
+	for _, result := range top.sorted() {
+		other, _ := deepCopy(result.laptop)
+		ids = append(ids, other.Id)
+	}
+	return ids
+}
+
+func newIndexTestStore(t testing.TB, n int) *InMemoryLaptopStore {
+	store := NewInMemoryLaptopStore()
+	for i := 0; i < n; i++ {
+		require.NoError(t, store.Save(sample.NewLaptop()))
+	}
+	return store
+}
+
+func randomFilter() *pd.Filter {
+	return &pd.Filter{
+		MaxPriceUsd: 1500 + rand.Float64()*2000,
+		MinCpuCores: uint32(rand.Intn(8)),
+		MinCpuGhz:   rand.Float64() * 3.5,
+		MinRam:      &pd.Memory{Value: uint64(rand.Intn(64)), Unit: pd.Memory_GIGABYTE},
+	}
+}
+
+func TestInMemoryLaptopStoreIndexes(t *testing.T) {
+	t.Parallel()
+
+	store := newIndexTestStore(t, 500)
+
+	// the indexes must follow updates and deletes
+	var laptops []*pd.Laptop
+	store.forEach(func(laptop *pd.Laptop) error {
+		laptops = append(laptops, laptop)
+		return nil
+	})
+	for i, laptop := range laptops[:100] {
+		if i%2 == 0 {
+			require.NoError(t, store.Delete(laptop.Id, ""))
+			continue
+		}
+		updated := sample.NewLaptop()
+		updated.Id = laptop.Id
+		require.NoError(t, store.Update(updated, ""))
+	}
+
+	for _, index := range store.indexes {
+		require.Len(t, index.entries, len(store.data), index.name)
+	}
+
+	for i := 0; i < 100; i++ {
+		query := &SearchQuery{Filter: randomFilter(), SortBy: pd.SearchLaptopRequest_PRICE, Limit: 20}
+
+		var ids []string
+		err := store.Search(context.Background(), query, func(laptop *pd.Laptop, score float64) error {
+			ids = append(ids, laptop.Id)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, fullScan(store, query), ids)
+	}
+}
+
+func TestInMemoryLaptopStoreSortedPages(t *testing.T) {
+	t.Parallel()
+
+	store := newIndexTestStore(t, 300)
+	sorts := []pd.SearchLaptopRequest_SortBy{
+		pd.SearchLaptopRequest_PRICE,
+		pd.SearchLaptopRequest_CPU_GHZ,
+		pd.SearchLaptopRequest_RAM,
+	}
+
+	for i := 0; i < 30; i++ {
+		for _, sortBy := range sorts {
+			for _, descending := range []bool{false, true} {
+				query := &SearchQuery{Filter: randomFilter(), SortBy: sortBy, Descending: descending}
+				expected := fullScan(store, query)
+
+				// the pages are read by walking the sort index from the cursor
+				query.Limit = 7
+				var ids []string
+				for {
+					var last *pd.Laptop
+					count := 0
+					err := store.Search(context.Background(), query, func(laptop *pd.Laptop, score float64) error {
+						ids = append(ids, laptop.Id)
+						last = laptop
+						count++
+						return nil
+					})
+					require.NoError(t, err)
+					if count < query.Limit {
+						break
+					}
+					query.After = query.cursor(last, 0)
+				}
+				require.Equal(t, expected, ids, "sort by %v, descending: %v", sortBy, descending)
+			}
+		}
+	}
+}
+
+func TestLaptopIndexPage(t *testing.T) {
+	t.Parallel()
+
+	store := newIndexTestStore(t, 100)
+	query := &SearchQuery{Filter: &pd.Filter{MaxPriceUsd: 5000}, SortBy: pd.SearchLaptopRequest_PRICE}
+	index := sortIndex(store.indexes, query)
+	require.Equal(t, "price_usd", index.name)
+	require.Len(t, index.page(query), 100)
+
+	// the page starts right after the cursor, in both directions
+	middle := index.entries[40]
+	query.After = &SearchCursor{Value: middle.key, ID: middle.id}
+	require.Equal(t, index.entries[41:], index.page(query))
+	query.Descending = true
+	require.Equal(t, index.entries[:40], index.page(query))
+
+	// the orders without an index are sorted with the heap
+	query.SortBy = pd.SearchLaptopRequest_RELEASE_YEAR
+	require.Nil(t, sortIndex(store.indexes, query))
+	query.SortBy = pd.SearchLaptopRequest_ID
+	require.Nil(t, sortIndex(store.indexes, query))
+}
+
+func TestPlanSearch(t *testing.T) {
+	t.Parallel()
+
+	store := newIndexTestStore(t, 100)
+
+	// no laptop has that many cores, so the cpu_cores index is the most selective one
+	filter := &pd.Filter{MaxPriceUsd: 5000, MinCpuCores: 100}
+	entries, ok := planSearch(store.indexes, filter)
+	require.True(t, ok)
+	require.Empty(t, entries)
+
+	// every laptop is cheaper, so only the price index is used and returns all of them
+	filter = &pd.Filter{MaxPriceUsd: 5000}
+	entries, ok = planSearch(store.indexes, filter)
+	require.True(t, ok)
+	require.Len(t, entries, 100)
+}
+
+func benchmarkSearch(b *testing.B, search func(store *InMemoryLaptopStore, query *SearchQuery)) {
+	store := newIndexTestStore(b, 10000)
+	query := &SearchQuery{
+		Filter: &pd.Filter{
+			MaxPriceUsd: 1600,
+			MinCpuCores: 4,
+			MinCpuGhz:   2.5,
+			MinRam:      &pd.Memory{Value: 16, Unit: pd.Memory_GIGABYTE},
+		},
+		Limit: 10,
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		search(store, query)
+	}
+}
+
+func BenchmarkInMemoryLaptopStoreIndexedSearch(b *testing.B) {
+	benchmarkSearch(b, func(store *InMemoryLaptopStore, query *SearchQuery) {
+		store.Search(context.Background(), query, func(laptop *pd.Laptop, score float64) error { return nil })
+	})
+}
+
+func BenchmarkInMemoryLaptopStoreFullScan(b *testing.B) {
+	benchmarkSearch(b, func(store *InMemoryLaptopStore, query *SearchQuery) {
+		fullScan(store, query)
+	})
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"container/heap"
+	"pc_book/pd"
+)
+
+// SearchQuery describes which laptops LaptopStore.Search returns and in which order.
+// Laptops are ordered by the sort value and then by id, so that the order is always the same.
+type SearchQuery struct {
+	Filter *pd.Filter
+	// Text is a free-text query, only the laptops that match all of its words are returned
+	Text       string
+	SortBy     pd.SearchLaptopRequest_SortBy
+	Descending bool
+	// Limit is the maximum number of laptops to return, 0 means no limit
+	Limit int
+	// After is the position of the last laptop of the previous page, nil to start from the beginning
+	After *SearchCursor
+	// Rating returns the average rating of a laptop, it is required to sort by rating
+	Rating func(laptopID string) float64
+}
+
+// SearchCursor is the position of a laptop in the order of a SearchQuery
+type SearchCursor struct {
+	Value float64
+	ID    string
+}
+
+// tokens returns the words of the text query, nil if there is no text query
+func (query *SearchQuery) tokens() []string {
+	return queryTokens(query.Text)
+}
+
+// sortValue returns the value of the laptop that the query sorts by, score is its text relevance
+func (query *SearchQuery) sortValue(laptop *pd.Laptop, score float64) float64 {
+	switch query.SortBy {
+	case pd.SearchLaptopRequest_PRICE:
+		return laptop.GetPriceUsd()
+	case pd.SearchLaptopRequest_CPU_GHZ:
+		return laptop.GetCpu().GetMinGhz()
+	case pd.SearchLaptopRequest_RAM:
+		return float64(toBit(laptop.GetRam()))
+	case pd.SearchLaptopRequest_RELEASE_YEAR:
+		return float64(laptop.GetReleaseYear())
+	case pd.SearchLaptopRequest_RATING:
+		if query.Rating == nil {
+			return 0
+		}
+		return query.Rating(laptop.GetId())
+	case pd.SearchLaptopRequest_RELEVANCE:
+		return score
+	default:
+		return 0
+	}
+}
+
+// cursor returns the position of the laptop
+func (query *SearchQuery) cursor(laptop *pd.Laptop, score float64) *SearchCursor {
+	return &SearchCursor{Value: query.sortValue(laptop, score), ID: laptop.GetId()}
+}
+
+// before reports whether position a comes before position b
+func (query *SearchQuery) before(a *SearchCursor, b *SearchCursor) bool {
+	if query.Descending {
+		a, b = b, a
+	}
+	if a.Value != b.Value {
+		return a.Value < b.Value
+	}
+	return a.ID < b.ID
+}
+
+// isAfterCursor reports whether a laptop at position comes after query.After
+func (query *SearchQuery) isAfterCursor(position *SearchCursor) bool {
+	return query.After == nil || query.before(query.After, position)
+}
+
+// searchResult is a laptop that matches a query, with its position
+type searchResult struct {
+	laptop   *pd.Laptop
+	score    float64
+	position *SearchCursor
+}
+
+// topResults keeps the first query.Limit results in order, using a heap with the last one on top
+type topResults struct {
+	query   *SearchQuery
+	results []searchResult
+}
+
+func newTopResults(query *SearchQuery) *topResults {
+	return &topResults{query: query}
+}
+
+// add adds a laptop that matches the filter and returns false if it is not in the page
+func (top *topResults) add(laptop *pd.Laptop, score float64) bool {
+	position := top.query.cursor(laptop, score)
+	if !top.query.isAfterCursor(position) {
+		return false
+	}
+
+	limit := top.query.Limit
+	if limit > 0 && len(top.results) == limit {
+		if !top.query.before(position, top.results[0].position) {
+			return false
+		}
+		top.results[0] = searchResult{laptop: laptop, score: score, position: position}
+		heap.Fix(top, 0)
+		return true
+	}
+
+	heap.Push(top, searchResult{laptop: laptop, score: score, position: position})
+	return true
+}
+
+// sorted returns the results in order and empties the heap
+func (top *topResults) sorted() []searchResult {
+	sorted := make([]searchResult, len(top.results))
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(top).(searchResult)
+	}
+	return sorted
+}
+
+func (top *topResults) Len() int { return len(top.results) }
+
+func (top *topResults) Less(i, j int) bool {
+	// the last result is on top of the heap, so that it is the first one to be replaced
+	return top.query.before(top.results[j].position, top.results[i].position)
+}
+
+func (top *topResults) Swap(i, j int) {
+	top.results[i], top.results[j] = top.results[j], top.results[i]
+}
+
+func (top *topResults) Push(x interface{}) { top.results = append(top.results, x.(searchResult)) }
+
+func (top *topResults) Pop() interface{} {
+	last := top.results[len(top.results)-1]
+	top.results = top.results[:len(top.results)-1]
+	return last
+}
@@ -1,20 +1,34 @@
 package service
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"io"
 	"log"
+	"math"
 	"pc_book/pd"
+	"pc_book/serializer"
+	"sync"
 )
 
-// 设定上传图片的最大大小
-const maxImageSize = 1 << 20
+// 设定上传图片的最大大小, 上传的数据直接写到硬盘, 不在内存中
+const maxImageSize = 512 << 20
+
+// exportChunkSize is the size of the chunks sent by ExportLaptops
+const exportChunkSize = 64 << 10
+
+// imageChunkSize is the size of the chunks sent by DownloadImage
+const imageChunkSize = 64 << 10
+
+// maxImportBatchSize is the maximum number of laptops of a transactional import, which are all kept in memory
+const maxImportBatchSize = 10000
 
 // LaptopService is the server that provides laptop service
 type LaptopService struct {
@@ -22,6 +36,10 @@ type LaptopService struct {
 	laptopStore LaptopStore
 	imageStore ImageStore
 	ratingStore RatingStore
+	broker *laptopBroker
+	// writeMutex is held while the laptop store is changed and the change is published,
+	// so that the watchers receive the events in the order of the changes
+	writeMutex sync.Mutex
 }
 
 func NewLaptopService(laptopStore LaptopStore, imageStore ImageStore, ratingStore RatingStore) *LaptopService {
@@ -29,26 +47,18 @@ func NewLaptopService(laptopStore LaptopStore, imageStore ImageStore, ratingStor
 		laptopStore: laptopStore,
 		imageStore: imageStore,
 		ratingStore: ratingStore,
+		broker: newLaptopBroker(),
 	}
 }
 
 // CreateLaptop is a unary RPC to create a new laptop.
 func (server *LaptopService) CreateLaptop(ctx context.Context, req *pd.CreateLaptopRequest) (*pd.CreateLaptopResponse, error) {
 	laptop := req.GetLaptop()
-	log.Printf("receive a create-laptop request with id: %s", laptop.Id)
+	log.Printf("receive a create-laptop request with id: %s", laptop.GetId())
 
-	if len(laptop.Id) > 0 {
-		// check if it's valid uuid
-		_, err := uuid.Parse(laptop.Id)
-		if err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "laptop ID is not a valid UUID: %v", err)
-		}
-	} else {
-		id, err := uuid.NewRandom()
-		if err != nil {
-			return nil, status.Errorf(codes.Internal, "can not generate a new laptop ID: %v", err)
-		}
-		laptop.Id = id.String()
+	err := prepareNewLaptop(laptop)
+	if err != nil {
+		return nil, err
 	}
 
 	// some heavy process
@@ -58,7 +68,9 @@ func (server *LaptopService) CreateLaptop(ctx context.Context, req *pd.CreateLap
 	}
 
 	// save the laptop to in-memory store
-	err := server.laptopStore.Save(laptop)
+	err = server.commit(func() error {
+		return server.laptopStore.Save(laptop)
+	}, laptopEvent{laptop: laptop, id: laptop.Id})
 	if err != nil {
 		code := codes.Internal
 		if errors.Is(err, ErrAlreadyExists) {
@@ -68,33 +80,478 @@ func (server *LaptopService) CreateLaptop(ctx context.Context, req *pd.CreateLap
 	}
 
 	log.Printf("save laptop with ID: %v", laptop.Id)
+
 	res := &pd.CreateLaptopResponse{
 		Id: laptop.Id,
+		Etag: LaptopEtag(laptop),
 	}
 	return res, nil
 }
 
+// prepareNewLaptop validates a laptop to create, generates its id if it has none and sets updated_at
+func prepareNewLaptop(laptop *pd.Laptop) error {
+	if laptop == nil {
+		return status.Errorf(codes.InvalidArgument, "laptop is missing")
+	}
+
+	err := validateLaptop(laptop)
+	if err != nil {
+		return err
+	}
+
+	if len(laptop.Id) > 0 {
+		// check if it's valid uuid
+		_, err := uuid.Parse(laptop.Id)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "laptop ID is not a valid UUID: %v", err)
+		}
+	} else {
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return status.Errorf(codes.Internal, "can not generate a new laptop ID: %v", err)
+		}
+		laptop.Id = id.String()
+	}
+
+	// updated_at 由服务端设置, 不信任客户端的值
+	laptop.UpdatedAt = ptypes.TimestampNow()
+	return nil
+}
+
+// ImportLaptops is a bidirectional-streaming RPC to create many laptops, with a result for each of them.
+// A laptop that fails doesn't stop the import, unless the options ask for a transactional import.
+func (server *LaptopService) ImportLaptops(stream pd.LaptopService_ImportLaptopsServer) error {
+	transactional := false
+	var laptops []*pd.Laptop
+	var results []*pd.ImportLaptopsResponse
+
+	for index := 0; ; {
+		if err := contextError(stream.Context()); err != nil {
+			return err
+		}
+
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return logErr(status.Errorf(codes.Unknown, "cannot receive stream request: %v", err))
+		}
+
+		if options := req.GetOptions(); options != nil {
+			if index > 0 {
+				return logErr(status.Errorf(codes.InvalidArgument, "import options must be sent before the laptops"))
+			}
+			transactional = options.GetTransactional()
+			continue
+		}
+
+		laptop := req.GetLaptop()
+		res := &pd.ImportLaptopsResponse{Index: uint32(index)}
+		index++
+
+		err = prepareNewLaptop(laptop)
+		if transactional {
+			// 事务模式: 先收集, 收到全部laptop后一起保存
+			if len(laptops) == maxImportBatchSize {
+				return logErr(status.Errorf(codes.ResourceExhausted, "a transactional import cannot have more than %d laptops", maxImportBatchSize))
+			}
+			setImportResult(res, laptop, err)
+			laptops = append(laptops, laptop)
+			results = append(results, res)
+			continue
+		}
+
+		if err == nil {
+			err = server.commit(func() error {
+				return server.laptopStore.Save(laptop)
+			}, laptopEvent{laptop: laptop, id: laptop.Id})
+			if err != nil {
+				err = status.Errorf(storeErrorCode(err), "cannot save laptop: %v", err)
+			}
+		}
+
+		setImportResult(res, laptop, err)
+		err = stream.Send(res)
+		if err != nil {
+			return logErr(status.Errorf(codes.Unknown, "cannot send stream response: %v", err))
+		}
+	}
+
+	if !transactional {
+		return nil
+	}
+
+	err := server.saveImportBatch(laptops, results)
+	if err != nil {
+		return logErr(err)
+	}
+
+	for _, res := range results {
+		err := stream.Send(res)
+		if err != nil {
+			return logErr(status.Errorf(codes.Unknown, "cannot send stream response: %v", err))
+		}
+	}
+	log.Printf("imported %d laptops", len(results))
+	return nil
+}
+
+// commit runs a change of the laptop store and publishes its event if it succeeds, with writeMutex held
+func (server *LaptopService) commit(change func() error, event laptopEvent) error {
+	server.writeMutex.Lock()
+	defer server.writeMutex.Unlock()
+
+	err := change()
+	if err == nil {
+		server.broker.publish(event)
+	}
+	return err
+}
+
+// saveImportBatch saves the laptops of a transactional import if they are all valid, and updates their results
+func (server *LaptopService) saveImportBatch(laptops []*pd.Laptop, results []*pd.ImportLaptopsResponse) error {
+	failed := false
+	for _, res := range results {
+		if res.Result != pd.ImportLaptopsResponse_OK {
+			failed = true
+		}
+	}
+
+	server.writeMutex.Lock()
+	defer server.writeMutex.Unlock()
+
+	if !failed && len(laptops) > 0 {
+		err := server.laptopStore.SaveBatch(laptops)
+		var batchErr *BatchError
+		if errors.As(err, &batchErr) {
+			failed = true
+			setImportResult(results[batchErr.Index], laptops[batchErr.Index],
+				status.Errorf(storeErrorCode(batchErr.Err), "cannot save laptop: %v", batchErr.Err))
+		} else if err != nil {
+			return status.Errorf(codes.Internal, "cannot save laptops: %v", err)
+		}
+	}
+
+	for i, res := range results {
+		if failed {
+			if res.Result == pd.ImportLaptopsResponse_OK {
+				res.Result = pd.ImportLaptopsResponse_ABORTED
+				res.Error = "another laptop of the import failed"
+				res.Etag = ""
+			}
+			continue
+		}
+		server.broker.publish(laptopEvent{laptop: laptops[i], id: laptops[i].Id})
+	}
+	return nil
+}
+
+// GetLaptop is a unary RPC to get a laptop by id
+func (server *LaptopService) GetLaptop(ctx context.Context, req *pd.GetLaptopRequest) (*pd.GetLaptopResponse, error) {
+	laptop, err := server.laptopStore.Find(req.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot find laptop: %v", err)
+	}
+	if laptop == nil {
+		return nil, status.Errorf(codes.NotFound, "laptop %s is not found", req.GetId())
+	}
+
+	res := &pd.GetLaptopResponse{
+		Laptop: laptop,
+		Etag: LaptopEtag(laptop),
+	}
+	return res, nil
+}
+
+// UpdateLaptop is a unary RPC to update the fields of a laptop listed in the field mask
+func (server *LaptopService) UpdateLaptop(ctx context.Context, req *pd.UpdateLaptopRequest) (*pd.UpdateLaptopResponse, error) {
+	update := req.GetLaptop()
+	log.Printf("receive an update-laptop request with id: %s, mask: %v", update.GetId(), req.GetUpdateMask().GetPaths())
+
+	current, err := server.laptopStore.Find(update.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot find laptop: %v", err)
+	}
+	if current == nil {
+		return nil, status.Errorf(codes.NotFound, "laptop %s is not found", update.GetId())
+	}
+
+	etag := LaptopEtag(current)
+	if req.GetEtag() != "" && req.GetEtag() != etag {
+		return nil, status.Errorf(codes.Aborted, "laptop %s has been modified, get it again", update.GetId())
+	}
+
+	laptop, err := deepCopy(current)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot copy laptop: %v", err)
+	}
+
+	err = applyLaptopMask(laptop, update, req.GetUpdateMask().GetPaths())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot apply update mask: %v", err)
+	}
+
+	err = validateLaptop(laptop)
+	if err != nil {
+		return nil, err
+	}
+	laptop.UpdatedAt = ptypes.TimestampNow()
+
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+
+	// 用读取到的版本做检查, 防止读取之后被其他请求修改
+	err = server.commit(func() error {
+		return server.laptopStore.Update(laptop, etag)
+	}, laptopEvent{laptop: laptop, id: laptop.Id})
+	if err != nil {
+		return nil, status.Errorf(storeErrorCode(err), "cannot update laptop: %v", err)
+	}
+
+	log.Printf("updated laptop with ID: %v", laptop.Id)
+
+	res := &pd.UpdateLaptopResponse{
+		Laptop: laptop,
+		Etag: LaptopEtag(laptop),
+	}
+	return res, nil
+}
+
+// DeleteLaptop is a unary RPC to delete a laptop
+func (server *LaptopService) DeleteLaptop(ctx context.Context, req *pd.DeleteLaptopRequest) (*pd.DeleteLaptopResponse, error) {
+	log.Printf("receive a delete-laptop request with id: %s", req.GetId())
+
+	err := server.commit(func() error {
+		return server.laptopStore.Delete(req.GetId(), req.GetEtag())
+	}, laptopEvent{id: req.GetId()})
+	if err != nil {
+		return nil, status.Errorf(storeErrorCode(err), "cannot delete laptop: %v", err)
+	}
+
+	log.Printf("deleted laptop with ID: %v", req.GetId())
+
+	return &pd.DeleteLaptopResponse{}, nil
+}
+
 // SearchLaptop is a server-streaming RPC to search for laptop
 func (server *LaptopService) SearchLaptop(req *pd.SearchLaptopRequest, stream pd.LaptopService_SearchLaptopServer) error {
 	filter := req.GetFilter()
 	log.Printf("recieve a search-laptop request with filter: %v", filter)
 
-	err := server.laptopStore.Search(stream.Context(), filter, func(laptop *pd.Laptop) error {
-		res := &pd.SearchLaptopResponse{Laptop: laptop}
+	if _, ok := pd.SearchLaptopRequest_SortBy_name[int32(req.GetSortBy())]; !ok {
+		return status.Errorf(codes.InvalidArgument, "unknown sort key: %d", req.GetSortBy())
+	}
+
+	query := &SearchQuery{
+		Filter:     filter,
+		Text:       req.GetQuery(),
+		SortBy:     req.GetSortBy(),
+		Descending: req.GetDescending(),
+		Rating:     server.averageRating,
+	}
+
+	if req.GetPageToken() != "" {
+		after, err := decodePageToken(query, req.GetPageToken())
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid page token: %v", err)
+		}
+		query.After = after
+	}
+
+	pageSize := int(req.GetPageSize())
+	if pageSize > 0 {
+		// 多查一个, 用来判断是否还有下一页
+		query.Limit = pageSize + 1
+	}
+
+	sent := 0
+	send := func(result *searchResult, hasMore bool) error {
+		laptop := result.laptop
+		res := &pd.SearchLaptopResponse{Laptop: laptop, Score: result.score}
+		if hasMore {
+			token, err := encodePageToken(query, query.cursor(laptop, result.score))
+			if err != nil {
+				return status.Errorf(codes.Internal, "cannot create page token: %v", err)
+			}
+			res.NextPageToken = token
+		}
+
 		err := stream.Send(res)
 		if err != nil {
 			return err
 		}
 
+		sent++
 		log.Printf("sent laptop with id: %s", laptop.GetId())
 		return nil
+	}
+
+	// a laptop is only sent once the next one is found, to know whether its token is needed
+	var pending *searchResult
+	err := server.laptopStore.Search(stream.Context(), query, func(laptop *pd.Laptop, score float64) error {
+		if pending != nil {
+			err := send(pending, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		pending = &searchResult{laptop: laptop, score: score}
+		if pageSize > 0 && sent == pageSize {
+			// the extra laptop belongs to the next page
+			pending = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if pending != nil {
+		return send(pending, false)
+	}
+	return nil
+}
+
+// GetSearchFacets is a unary RPC to count the laptops of a search for each value of the facet fields
+func (server *LaptopService) GetSearchFacets(ctx context.Context, req *pd.GetSearchFacetsRequest) (*pd.GetSearchFacetsResponse, error) {
+	log.Printf("recieve a get-search-facets request with filter: %v", req.GetFilter())
+
+	query := &SearchQuery{
+		Filter: req.GetFilter(),
+		Text:   req.GetQuery(),
+	}
+
+	facets, err := server.laptopStore.Facets(ctx, query)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot count search facets: %v", err)
+	}
+
+	return facets.toProto(), nil
+}
+
+// WatchLaptops is a server-streaming RPC that sends the laptops matching the filter, then every change of them
+func (server *LaptopService) WatchLaptops(req *pd.WatchLaptopsRequest, stream pd.LaptopService_WatchLaptopsServer) error {
+	filter := req.GetFilter()
+	log.Printf("recieve a watch-laptops request with filter: %v", filter)
+
+	// subscribe before the search, so that no change is missed between them
+	watcher := server.broker.subscribe(watchBufferSize)
+	defer server.broker.unsubscribe(watcher)
+
+	watched := newWatchedLaptops(filter)
+	err := server.laptopStore.Search(stream.Context(), &SearchQuery{Filter: filter}, func(laptop *pd.Laptop, score float64) error {
+		return stream.Send(watched.existing(laptop))
+	})
+	if err != nil {
+		return err
+	}
+	// 初始搜索期间缓冲区满了, 已经错过了一些变化
+	if server.broker.dropped(watcher) {
+		return logErr(status.Errorf(codes.ResourceExhausted, "too many changes during the initial search, watch again"))
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return contextError(stream.Context())
+		case event, ok := <-watcher.events:
+			if !ok {
+				return logErr(status.Errorf(codes.ResourceExhausted, "client is too slow to receive the changes, watch again"))
+			}
+
+			res := watched.change(event)
+			if res == nil {
+				continue
+			}
+			err := stream.Send(res)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ExportLaptops is a server-streaming RPC that sends the laptops matching the filter as a file, by chunks
+func (server *LaptopService) ExportLaptops(req *pd.ExportLaptopsRequest, stream pd.LaptopService_ExportLaptopsServer) error {
+	log.Printf("recieve an export-laptops request with format %v and filter: %v", req.GetFormat(), req.GetFilter())
+
+	output := bufio.NewWriterSize(chunkSender(func(chunk []byte) error {
+		return stream.Send(&pd.ExportLaptopsResponse{ChunkData: chunk})
+	}), exportChunkSize)
+
+	writer, err := newExportWriter(req.GetFormat(), output)
+	if err != nil {
+		return logErr(status.Errorf(codes.InvalidArgument, "cannot export laptops: %v", err))
+	}
+
+	filter := req.GetFilter()
+	if filter == nil {
+		// 没有filter时导出全部laptop
+		filter = &pd.Filter{MaxPriceUsd: math.MaxFloat64}
+	}
+
+	count := 0
+	err = server.laptopStore.Search(stream.Context(), &SearchQuery{Filter: filter}, func(laptop *pd.Laptop, score float64) error {
+		count++
+		return writer.Write(laptop)
 	})
 	if err != nil {
 		return err
 	}
+
+	err = writer.Flush()
+	if err == nil {
+		err = output.Flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("exported %d laptops", count)
 	return nil
 }
 
+// newExportWriter returns the writer of an export format
+func newExportWriter(format pd.ExportLaptopsRequest_Format, output io.Writer) (serializer.MessageWriter, error) {
+	switch format {
+	case pd.ExportLaptopsRequest_JSON_LINES:
+		return serializer.NewJSONLinesWriter(output), nil
+	case pd.ExportLaptopsRequest_PROTOBUF:
+		return serializer.NewDelimitedWriter(output), nil
+	case pd.ExportLaptopsRequest_CSV:
+		return serializer.NewCSVWriter(output, &pd.Laptop{}), nil
+	default:
+		return nil, fmt.Errorf("unknown format: %d", format)
+	}
+}
+
+// chunkSender is an io.Writer that sends each write as a chunk of a stream
+type chunkSender func(chunk []byte) error
+
+func (send chunkSender) Write(p []byte) (int, error) {
+	err := send(p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// averageRating returns the average score of a laptop, or 0 if it has not been rated
+func (server *LaptopService) averageRating(laptopID string) float64 {
+	if server.ratingStore == nil {
+		return 0
+	}
+
+	rating, err := server.ratingStore.Get(laptopID)
+	if err != nil || rating == nil || rating.Count == 0 {
+		return 0
+	}
+	return rating.Sum / float64(rating.Count)
+}
+
 // UploadImage is a server-streaming RPC to search for image uploading
 func (server *LaptopService) UploadImage(stream pd.LaptopService_UploadImageServer) error {
 	req, err := stream.Recv()
@@ -115,62 +572,380 @@ func (server *LaptopService) UploadImage(stream pd.LaptopService_UploadImageServ
 		return logErr(status.Errorf(codes.InvalidArgument, "laptop %s doesnt exist", laptopID))
 	}
 
-	imageData := bytes.Buffer{}
-	imageSize := 0
+	// 只接受允许的格式, 空的image type由内容决定
+	if imageType != "" && declaredImageFormat(imageType) == nil {
+		return logErr(status.Errorf(codes.InvalidArgument, "image type %q is not allowed", imageType))
+	}
+
+	// 不能继续上传的store一次接收整个image, 客户端只能从头重新上传
+	var info *ImageInfo
+	store, resumable := server.imageStore.(ResumableImageStore)
+	if uploadID := req.GetInfo().GetUploadId(); uploadID != "" && resumable {
+		info, err = server.receiveResumableImage(stream, store, uploadID, laptopID, imageType)
+	} else {
+		info, err = server.receiveImage(stream, laptopID, imageType)
+	}
+	if err != nil {
+		return err
+	}
+	imageID := info.ID
+	imageSize := info.Size
+
+	// 生成缩略图, 失败时图片仍然保存. 重新完成的上传已经有缩略图
+	if len(info.Renditions) == 0 {
+		err = addImageRenditions(stream.Context(), server.imageStore, info)
+		if errors.Is(err, errNoRendition) {
+			log.Printf("image %s of type %s has no rendition", imageID, info.Type)
+		} else if err != nil {
+			log.Printf("cannot add renditions of image %s: %v", imageID, err)
+		}
+	}
+
+	res := &pd.UploadImageResponse{
+		Id: imageID,
+		Size: uint32(imageSize),
+		Digest: info.Checksum,
+	}
+
+	err = stream.SendAndClose(res)
+	if err != nil {
+		return logErr(status.Errorf(codes.Unknown, "cannot send response: %v", err))
+	}
+
+	log.Printf("saved image with id: %s, size: %d", imageID, imageSize)
+	return nil
+}
+
+// receiveImage saves the chunks of the stream as they are received, so only one chunk is kept in memory
+func (server *LaptopService) receiveImage(stream pd.LaptopService_UploadImageServer, laptopID string, imageType string) (*ImageInfo, error) {
+	data := &imageChunkReader{stream: stream}
+
+	header := make([]byte, imageHeaderSize)
+	n, err := io.ReadFull(data, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	header = header[:n]
+
+	format, err := checkImageFormat(header, imageType)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := server.imageStore.Save(laptopID, format.extension, io.MultiReader(bytes.NewReader(header), data))
+	if data.err != nil {
+		// the upload failed, the store has removed what it has written
+		return nil, data.err
+	}
+	if err != nil {
+		return nil, logErr(status.Errorf(codes.Internal, "cannot save image to store: %v", err))
+	}
+	return info, nil
+}
+
+// checkImageFormat detects the format of an image from its first bytes, it must be the declared one if there is one
+func checkImageFormat(header []byte, imageType string) (*imageFormat, error) {
+	// 根据文件内容判断格式, 文件扩展名只来自服务器
+	format := detectImageFormat(header)
+	if format == nil {
+		return nil, logErr(status.Errorf(codes.InvalidArgument, "image format is not allowed, it must be PNG, JPEG, GIF or WebP"))
+	}
+	if imageType != "" && declaredImageFormat(imageType) != format {
+		return nil, logErr(status.Errorf(codes.InvalidArgument, "image type %s doesn't match the %s data", imageType, format.name))
+	}
+	return format, nil
+}
 
+// receiveResumableImage appends the chunks of the stream to an upload, which is completed when the client closes the stream.
+// If the stream is broken the upload is kept, the client can resume it from the offset returned by QueryUpload.
+func (server *LaptopService) receiveResumableImage(stream pd.LaptopService_UploadImageServer, store ResumableImageStore, uploadID string, laptopID string, imageType string) (*ImageInfo, error) {
+	if !isUploadID(uploadID) {
+		return nil, logErr(status.Errorf(codes.InvalidArgument, "invalid upload id %q", uploadID))
+	}
+
+	upload, err := store.StartUpload(uploadID, laptopID, imageType)
+	if errors.Is(err, ErrConflict) {
+		return nil, logErr(status.Errorf(codes.FailedPrecondition, "upload %s is for another laptop or image type", uploadID))
+	}
+	if err != nil {
+		return nil, logErr(status.Errorf(codes.Internal, "cannot start upload: %v", err))
+	}
+
+	// 客户端没有收到已经完成的上传的response
+	if upload.ImageID != "" {
+		info, err := server.imageStore.Find(upload.ImageID)
+		if err != nil {
+			return nil, logErr(status.Errorf(codes.Internal, "cannot find image: %v", err))
+		}
+		if info == nil {
+			return nil, logErr(status.Errorf(codes.NotFound, "image %s of upload %s is deleted", upload.ImageID, uploadID))
+		}
+		return info, nil
+	}
+	log.Printf("resume upload %s at offset %d", uploadID, upload.Offset)
+
+	offset := upload.Offset
 	for {
-		// check context error  用于上传图片超时
 		if err := contextError(stream.Context()); err != nil {
-			return err
+			return nil, err
 		}
 
-		log.Print("waiting to receive more data")
 		req, err := stream.Recv()
 		if err == io.EOF {
-			log.Print("no more data")
 			break
 		}
+		if err != nil {
+			return nil, logErr(status.Errorf(codes.Unknown, "cannot receive chunk data: %v", err))
+		}
 
+		data, chunkOffset := req.GetChunkData(), offset
+		if chunk := req.GetChunk(); chunk != nil {
+			data, chunkOffset = chunk.GetData(), int64(chunk.GetOffset())
+		}
+		if chunkOffset > offset {
+			return nil, logErr(status.Errorf(codes.FailedPrecondition, "chunk offset %d is after the %d bytes received", chunkOffset, offset))
+		}
+
+		// a client resuming from an older offset sends again data that is already received
+		skip := offset - chunkOffset
+		if skip >= int64(len(data)) {
+			continue
+		}
+		data = data[skip:]
+
+		if offset+int64(len(data)) > maxImageSize {
+			store.CancelUpload(uploadID)
+			return nil, logErr(status.Errorf(codes.InvalidArgument, "image is too large: %d > %d", offset+int64(len(data)), maxImageSize))
+		}
+
+		offset, err = store.AppendUpload(uploadID, offset, data)
 		if err != nil {
-			return logErr(status.Errorf(codes.Unknown, "cannot receive chunk data: %v", err))
+			return nil, logErr(status.Errorf(storeErrorCode(err), "cannot write chunk data: %v", err))
 		}
+	}
+
+	return server.completeUpload(store, upload)
+}
+
+// completeUpload checks the format of the data of an upload and saves it as an image, an invalid upload is removed
+func (server *LaptopService) completeUpload(store ResumableImageStore, upload *ImageUpload) (*ImageInfo, error) {
+	file, err := store.OpenUpload(upload.ID)
+	if err != nil {
+		return nil, logErr(status.Errorf(storeErrorCode(err), "cannot open upload: %v", err))
+	}
+
+	header := make([]byte, imageHeaderSize)
+	n, err := io.ReadFull(file, header)
+	file.Close()
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, logErr(status.Errorf(codes.Internal, "cannot read upload: %v", err))
+	}
+
+	format, err := checkImageFormat(header[:n], upload.Type)
+	if err != nil {
+		store.CancelUpload(upload.ID)
+		return nil, err
+	}
+
+	info, err := store.CompleteUpload(upload.ID, format.extension)
+	if err != nil {
+		return nil, logErr(status.Errorf(storeErrorCode(err), "cannot save image to store: %v", err))
+	}
+	return info, nil
+}
+
+// QueryUpload returns the state of a resumable upload, a client resumes a broken upload from its offset
+func (server *LaptopService) QueryUpload(ctx context.Context, req *pd.QueryUploadRequest) (*pd.QueryUploadResponse, error) {
+	uploadID := req.GetUploadId()
 
-		chunk := req.GetChunkData()
-		size := len(chunk)
+	store, ok := server.imageStore.(ResumableImageStore)
+	if !ok {
+		return nil, logErr(status.Errorf(codes.Unimplemented, "image store cannot resume uploads"))
+	}
+
+	upload, err := store.FindUpload(uploadID)
+	if err != nil {
+		return nil, logErr(status.Errorf(codes.Internal, "cannot find upload: %v", err))
+	}
+	if upload == nil {
+		return nil, logErr(status.Errorf(codes.NotFound, "upload %s is not found", uploadID))
+	}
 
-		log.Printf("receive a chunk with size: %d", size)
+	return &pd.QueryUploadResponse{
+		UploadId:  upload.ID,
+		LaptopId:  upload.LaptopID,
+		ImageType: upload.Type,
+		Offset:    uint64(upload.Offset),
+		ImageId:   upload.ImageID,
+	}, nil
+}
 
-		imageSize += size
-		if imageSize > maxImageSize {
-			return logErr(status.Errorf(codes.InvalidArgument, "image is too large: %d > %d", imageSize, maxImageSize))
+// imageChunkReader reads the data of the chunks of an UploadImage stream
+type imageChunkReader struct {
+	stream pd.LaptopService_UploadImageServer
+	chunk  []byte
+	size   int
+	// err is the status error that stopped the upload, it is returned by every following Read
+	err error
+	eof bool
+}
+
+func (reader *imageChunkReader) Read(p []byte) (int, error) {
+	for len(reader.chunk) == 0 {
+		if reader.err != nil {
+			return 0, reader.err
+		}
+		if reader.eof {
+			return 0, io.EOF
+		}
+
+		// check context error  用于上传图片超时
+		if err := contextError(reader.stream.Context()); err != nil {
+			reader.err = err
+			continue
 		}
 
-		_, err = imageData.Write(chunk)
+		log.Print("waiting to receive more data")
+		req, err := reader.stream.Recv()
+		if err == io.EOF {
+			log.Print("no more data")
+			reader.eof = true
+			continue
+		}
 		if err != nil {
-			return logErr(status.Errorf(codes.Unknown, "cannot write image to store: %v", err))
+			reader.err = logErr(status.Errorf(codes.Unknown, "cannot receive chunk data: %v", err))
+			continue
+		}
+
+		reader.chunk = req.GetChunkData()
+		if chunk := req.GetChunk(); chunk != nil {
+			// 不能继续的上传, offset必须是已经收到的大小
+			if chunk.GetOffset() != uint64(reader.size) {
+				reader.err = logErr(status.Errorf(codes.InvalidArgument, "chunk offset %d is not the %d bytes received", chunk.GetOffset(), reader.size))
+				continue
+			}
+			reader.chunk = chunk.GetData()
+		}
+		reader.size += len(reader.chunk)
+		log.Printf("receive a chunk with size: %d", len(reader.chunk))
+
+		if reader.size > maxImageSize {
+			reader.err = logErr(status.Errorf(codes.InvalidArgument, "image is too large: %d > %d", reader.size, maxImageSize))
+			reader.chunk = nil
 		}
 	}
 
-	fmt.Println(laptopID, imageType)
-	imageID, err := server.imageStore.Save(laptopID, imageType, imageData)
+	n := copy(p, reader.chunk)
+	reader.chunk = reader.chunk[n:]
+	return n, nil
+}
+
+// DownloadImage is a server-streaming RPC that sends the info of an image, then its data by chunks
+func (server *LaptopService) DownloadImage(req *pd.DownloadImageRequest, stream pd.LaptopService_DownloadImageServer) error {
+	imageID := req.GetImageId()
+	rendition := req.GetRendition()
+	log.Printf("receive a download-image request for image %s, rendition %q", imageID, rendition)
+
+	info, err := server.imageStore.Find(imageID)
 	if err != nil {
-		return logErr(status.Errorf(codes.Internal, "cannot save image to store: %v", err))
+		return logErr(status.Errorf(codes.Internal, "cannot find image: %v", err))
+	}
+	if info == nil {
+		return logErr(status.Errorf(codes.NotFound, "image %s is not found", imageID))
 	}
 
-	res := &pd.UploadImageResponse{
-		Id: imageID,
-		Size: uint32(imageSize),
+	file, err := server.imageStore.Open(imageID, rendition)
+	if errors.Is(err, ErrNotFound) && rendition != "" {
+		return logErr(status.Errorf(codes.NotFound, "rendition %s of image %s is not found", rendition, imageID))
+	}
+	if err != nil {
+		return logErr(status.Errorf(storeErrorCode(err), "cannot open image: %v", err))
 	}
+	defer file.Close()
 
-	err = stream.SendAndClose(res)
+	err = stream.Send(&pd.DownloadImageResponse{
+		Data: &pd.DownloadImageResponse_Info{Info: imageInfoToProto(info)},
+	})
 	if err != nil {
-		return logErr(status.Errorf(codes.Unknown, "cannot send response: %v", err))
+		return logErr(status.Errorf(codes.Unknown, "cannot send image info: %v", err))
 	}
 
-	log.Printf("saved image with id: %s, size: %d", imageID, imageSize)
+	buffer := make([]byte, imageChunkSize)
+	for {
+		if err := contextError(stream.Context()); err != nil {
+			return err
+		}
+
+		n, err := file.Read(buffer)
+		if n > 0 {
+			err := stream.Send(&pd.DownloadImageResponse{
+				Data: &pd.DownloadImageResponse_ChunkData{ChunkData: buffer[:n]},
+			})
+			if err != nil {
+				return logErr(status.Errorf(codes.Unknown, "cannot send chunk data: %v", err))
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return logErr(status.Errorf(codes.Internal, "cannot read image: %v", err))
+		}
+	}
+
+	log.Printf("sent image with id: %s, size: %d", imageID, info.Size)
 	return nil
 }
 
+// ListImages is a unary RPC to list the images of a laptop
+func (server *LaptopService) ListImages(ctx context.Context, req *pd.ListImagesRequest) (*pd.ListImagesResponse, error) {
+	images, err := server.imageStore.List(req.GetLaptopId())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot list images: %v", err)
+	}
+
+	res := &pd.ListImagesResponse{}
+	for _, info := range images {
+		res.Images = append(res.Images, imageInfoToProto(info))
+	}
+	return res, nil
+}
+
+// DeleteImage is a unary RPC to delete an image
+func (server *LaptopService) DeleteImage(ctx context.Context, req *pd.DeleteImageRequest) (*pd.DeleteImageResponse, error) {
+	log.Printf("receive a delete-image request for image %s", req.GetImageId())
+
+	err := server.imageStore.Delete(req.GetImageId())
+	if err != nil {
+		return nil, status.Errorf(storeErrorCode(err), "cannot delete image: %v", err)
+	}
+
+	log.Printf("deleted image with id: %s", req.GetImageId())
+	return &pd.DeleteImageResponse{}, nil
+}
+
+func imageInfoToProto(info *ImageInfo) *pd.ImageInfo {
+	renditions := make([]*pd.ImageRendition, len(info.Renditions))
+	for i, rendition := range info.Renditions {
+		renditions[i] = &pd.ImageRendition{
+			Name:      rendition.Name,
+			ImageType: rendition.Type,
+			Width:     uint32(rendition.Width),
+			Height:    uint32(rendition.Height),
+			Size:      uint64(rendition.Size),
+		}
+	}
+
+	return &pd.ImageInfo{
+		Id:         info.ID,
+		LaptopId:   info.LaptopID,
+		ImageType:  info.Type,
+		Size:       uint64(info.Size),
+		Renditions: renditions,
+		Digest:     info.Checksum,
+	}
+}
+
 // RateLaptop is a bidirectional-streaming RPC that allows client to rate a stream of laptops
 // with a score, and returns a stream of average score for each of them
 func (server *LaptopService) RateLaptop(stream pd.LaptopService_RateLaptopServer) error  {
@@ -218,6 +993,41 @@ func (server *LaptopService) RateLaptop(stream pd.LaptopService_RateLaptopServer
 	return nil
 }
 
+// setImportResult fills the result of an imported laptop from the error of its validation or its saving
+func setImportResult(res *pd.ImportLaptopsResponse, laptop *pd.Laptop, err error) {
+	res.Id = laptop.GetId()
+	if err == nil {
+		res.Result = pd.ImportLaptopsResponse_OK
+		res.Etag = LaptopEtag(laptop)
+		return
+	}
+
+	res.Error = status.Convert(err).Message()
+	res.Etag = ""
+	switch status.Code(err) {
+	case codes.AlreadyExists:
+		res.Result = pd.ImportLaptopsResponse_ALREADY_EXISTS
+	case codes.InvalidArgument:
+		res.Result = pd.ImportLaptopsResponse_INVALID_ARGUMENT
+	default:
+		res.Result = pd.ImportLaptopsResponse_INTERNAL
+	}
+}
+
+// storeErrorCode returns the status code for an error of LaptopStore
+func storeErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, ErrAlreadyExists):
+		return codes.AlreadyExists
+	case errors.Is(err, ErrConflict):
+		return codes.Aborted
+	default:
+		return codes.Internal
+	}
+}
+
 func logErr(err error) error {
 	if err != nil {
 		log.Print(err)
@@ -3,6 +3,8 @@ package service_test
 import (
 	"context"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/protobuf/field_mask"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"pc_book/pd"
@@ -94,3 +96,205 @@ func TestServerCreateLaptop(t *testing.T) {
 
 
 
+
+func TestServerCreateLaptopValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		change func(laptop *pd.Laptop)
+		// fields are the field paths of the expected violations
+		fields []string
+	}{
+		{
+			name:   "negative_price",
+			change: func(laptop *pd.Laptop) { laptop.PriceUsd = -1 },
+			fields: []string{"price_usd"},
+		},
+		{
+			name:   "zero_cores",
+			change: func(laptop *pd.Laptop) { laptop.Cpu.NumberCores = 0 },
+			fields: []string{"cpu.number_cores"},
+		},
+		{
+			name: "max_ghz_below_min_ghz",
+			change: func(laptop *pd.Laptop) {
+				laptop.Cpu.MinGhz = 3.5
+				laptop.Cpu.MaxGhz = 2.5
+			},
+			fields: []string{"cpu.max_ghz"},
+		},
+		{
+			name: "threads_below_cores",
+			change: func(laptop *pd.Laptop) {
+				laptop.Cpu.NumberCores = 8
+				laptop.Cpu.NumberThreads = 4
+			},
+			fields: []string{"cpu.number_threads"},
+		},
+		{
+			name: "nested_messages",
+			change: func(laptop *pd.Laptop) {
+				laptop.Gpus[0].Memory.Unit = pd.Memory_UNKNOWN
+				laptop.Storages[1].Driver = pd.Storage_Driver(9)
+				laptop.Screen.Resolution = nil
+				laptop.Keyboard.Layout = pd.Keyboard_UNKNOWN
+			},
+			fields: []string{"gpus[0].memory.unit", "storages[1].driver", "screen.resolution", "keyboard.layout"},
+		},
+		{
+			name: "missing_fields",
+			change: func(laptop *pd.Laptop) {
+				laptop.Brand = " "
+				laptop.Cpu = nil
+				laptop.Ram = nil
+				laptop.Weight = &pd.Laptop_WeightKg{WeightKg: 0}
+			},
+			fields: []string{"brand", "cpu", "ram", "weight_kg"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			laptop := sample.NewLaptop()
+			tc.change(laptop)
+
+			server := service.NewLaptopService(service.NewInMemoryLaptopStore(), nil, nil)
+			_, err := server.CreateLaptop(context.Background(), &pd.CreateLaptopRequest{Laptop: laptop})
+			require.ElementsMatch(t, tc.fields, violationFields(t, err))
+		})
+	}
+}
+
+// violationFields returns the field paths of the BadRequest details of an InvalidArgument error
+func violationFields(t *testing.T, err error) []string {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				require.NotEmpty(t, violation.GetDescription())
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+	return fields
+}
+
+func TestServerUpdateLaptop(t *testing.T) {
+	t.Parallel()
+
+	server := service.NewLaptopService(service.NewInMemoryLaptopStore(), nil, nil)
+	ctx := context.Background()
+
+	laptop := sample.NewLaptop()
+	created, err := server.CreateLaptop(ctx, &pd.CreateLaptopRequest{Laptop: laptop})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.Etag)
+
+	got, err := server.GetLaptop(ctx, &pd.GetLaptopRequest{Id: laptop.Id})
+	require.NoError(t, err)
+	require.Equal(t, created.Etag, got.Etag)
+
+	update := &pd.Laptop{
+		Id:       laptop.Id,
+		Brand:    "changed",
+		PriceUsd: 999,
+		Cpu:      &pd.CPU{MinGhz: 1.5},
+	}
+	mask := &field_mask.FieldMask{Paths: []string{"price_usd", "cpu.min_ghz"}}
+
+	res, err := server.UpdateLaptop(ctx, &pd.UpdateLaptopRequest{Laptop: update, UpdateMask: mask, Etag: got.Etag})
+	require.NoError(t, err)
+	require.Equal(t, 999.0, res.Laptop.PriceUsd)
+	require.Equal(t, 1.5, res.Laptop.Cpu.MinGhz)
+	// fields outside of the mask are kept
+	require.Equal(t, got.Laptop.Brand, res.Laptop.Brand)
+	require.Equal(t, got.Laptop.Cpu.MaxGhz, res.Laptop.Cpu.MaxGhz)
+	require.NotEqual(t, got.Etag, res.Etag)
+	require.False(t, res.Laptop.UpdatedAt.AsTime().Before(got.Laptop.UpdatedAt.AsTime()))
+
+	// a second editor with the old etag must not overwrite the change
+	_, err = server.UpdateLaptop(ctx, &pd.UpdateLaptopRequest{Laptop: update, UpdateMask: mask, Etag: got.Etag})
+	requireCode(t, codes.Aborted, err)
+
+	_, err = server.UpdateLaptop(ctx, &pd.UpdateLaptopRequest{
+		Laptop:     update,
+		UpdateMask: &field_mask.FieldMask{Paths: []string{"updated_at"}},
+	})
+	requireCode(t, codes.InvalidArgument, err)
+
+	_, err = server.UpdateLaptop(ctx, &pd.UpdateLaptopRequest{
+		Laptop:     update,
+		UpdateMask: &field_mask.FieldMask{Paths: []string{"cpu.unknown"}},
+	})
+	requireCode(t, codes.InvalidArgument, err)
+
+	// the laptop is validated after the mask is applied
+	_, err = server.UpdateLaptop(ctx, &pd.UpdateLaptopRequest{
+		Laptop:     &pd.Laptop{Id: laptop.Id, Cpu: &pd.CPU{MaxGhz: 1}},
+		UpdateMask: &field_mask.FieldMask{Paths: []string{"cpu.max_ghz"}},
+	})
+	require.Equal(t, []string{"cpu.max_ghz"}, violationFields(t, err))
+
+	update.Id = sample.NewLaptop().Id
+	_, err = server.UpdateLaptop(ctx, &pd.UpdateLaptopRequest{Laptop: update, UpdateMask: mask})
+	requireCode(t, codes.NotFound, err)
+}
+
+func TestServerDeleteLaptop(t *testing.T) {
+	t.Parallel()
+
+	server := service.NewLaptopService(service.NewInMemoryLaptopStore(), nil, nil)
+	ctx := context.Background()
+
+	laptop := sample.NewLaptop()
+	created, err := server.CreateLaptop(ctx, &pd.CreateLaptopRequest{Laptop: laptop})
+	require.NoError(t, err)
+
+	_, err = server.DeleteLaptop(ctx, &pd.DeleteLaptopRequest{Id: laptop.Id, Etag: "stale"})
+	requireCode(t, codes.Aborted, err)
+
+	_, err = server.DeleteLaptop(ctx, &pd.DeleteLaptopRequest{Id: laptop.Id, Etag: created.Etag})
+	require.NoError(t, err)
+
+	_, err = server.GetLaptop(ctx, &pd.GetLaptopRequest{Id: laptop.Id})
+	requireCode(t, codes.NotFound, err)
+
+	_, err = server.DeleteLaptop(ctx, &pd.DeleteLaptopRequest{Id: laptop.Id})
+	requireCode(t, codes.NotFound, err)
+}
+
+func TestServerGetSearchFacets(t *testing.T) {
+	t.Parallel()
+
+	server := service.NewLaptopService(service.NewInMemoryLaptopStore(), nil, nil)
+	ctx := context.Background()
+
+	for _, brand := range []string{"Apple", "Dell", "Apple"} {
+		laptop := sample.NewLaptop()
+		laptop.Brand = brand
+		_, err := server.CreateLaptop(ctx, &pd.CreateLaptopRequest{Laptop: laptop})
+		require.NoError(t, err)
+	}
+
+	res, err := server.GetSearchFacets(ctx, &pd.GetSearchFacetsRequest{Filter: &pd.Filter{MaxPriceUsd: 5000}})
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), res.Total)
+	require.Len(t, res.Facets, 5)
+
+	brand := res.Facets[0]
+	require.Equal(t, "brand", brand.Name)
+	require.Len(t, brand.Values, 2)
+	// the most frequent value comes first
+	require.Equal(t, "Apple", brand.Values[0].Value)
+	require.Equal(t, uint32(2), brand.Values[0].Count)
+	require.Equal(t, "Dell", brand.Values[1].Value)
+	require.Equal(t, uint32(1), brand.Values[1].Count)
+}
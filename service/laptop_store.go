@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/jinzhu/copier"
+	"github.com/golang/protobuf/proto"
 	"log"
 	"pc_book/pd"
 	"sync"
@@ -13,14 +13,44 @@ import (
 // ErrAlreadyExists is returned when a record with the same ID already exists in store
 var ErrAlreadyExists = errors.New("record already exists")
 
+// ErrNotFound is returned when a record doesn't exist in store
+var ErrNotFound = errors.New("record not found")
+
+// ErrConflict is returned when a record has changed since the given etag was read
+var ErrConflict = errors.New("record has been modified")
+
+// BatchError is returned by SaveBatch when a laptop of the batch cannot be saved, then none of them is saved
+type BatchError struct {
+	// Index is the position of the laptop in the batch
+	Index int
+	Err   error
+}
+
+func (err *BatchError) Error() string {
+	return fmt.Sprintf("laptop %d of the batch: %v", err.Index, err.Err)
+}
+
+func (err *BatchError) Unwrap() error {
+	return err.Err
+}
+
 // LaptopStore is an interface to store laptop
 type LaptopStore interface {
 	// Save saves the laptop to the store
 	Save(laptop *pd.Laptop) error
+	// SaveBatch saves all the laptops or none of them, it returns a *BatchError if one cannot be saved
+	SaveBatch(laptops []*pd.Laptop) error
 	// Find finds a laptop by id
 	Find(id string) (*pd.Laptop, error)
-	// Search search a laptop by filter, return one by one via the found function
-	Search(ctx context.Context, filter *pd.Filter, found func(laptop *pd.Laptop) error) error
+	// Update replaces the laptop with the same id, if etag is not empty it must match the stored laptop
+	Update(laptop *pd.Laptop, etag string) error
+	// Delete deletes a laptop by id, if etag is not empty it must match the stored laptop
+	Delete(id string, etag string) error
+	// Search search laptops by query, return one by one in the query order via the found function,
+	// with their relevance for the text query
+	Search(ctx context.Context, query *SearchQuery, found func(laptop *pd.Laptop, score float64) error) error
+	// Facets counts the laptops that match the filter and the text of the query for each facet value
+	Facets(ctx context.Context, query *SearchQuery) (*FacetCounts, error)
 }
 
 // InMemoryLaptopStore store laptop inmemory
@@ -28,12 +58,18 @@ type LaptopStore interface {
 type InMemoryLaptopStore struct {
 	mutex 	sync.RWMutex
 	data 	map[string]*pd.Laptop
+	// indexes 按字段排序的索引, 用于缩小查找范围
+	indexes []*laptopIndex
+	// text 全文搜索的倒排索引
+	text *textIndex
 }
 
 // NewInMemoryLaptopStore return a new InMemoryLaptopStore.
 func NewInMemoryLaptopStore() *InMemoryLaptopStore {
 	return &InMemoryLaptopStore{
 		data: make(map[string]*pd.Laptop),
+		indexes: newLaptopIndexes(),
+		text: newTextIndex(),
 	}
 }
 
@@ -51,9 +87,57 @@ func (store *InMemoryLaptopStore) Save(laptop *pd.Laptop) error {
 		return fmt.Errorf("can not copy laptop data: %v", err)
 	}
 	store.data[other.Id] = other
+	store.index(other)
+	return nil
+}
+
+// SaveBatch saves all the laptops or none of them
+func (store *InMemoryLaptopStore) SaveBatch(laptops []*pd.Laptop) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.checkBatch(laptops)
+	if err != nil {
+		return err
+	}
+
+	// 先全部复制, 出错时store没有任何改动
+	others := make([]*pd.Laptop, len(laptops))
+	for i, laptop := range laptops {
+		other, err := deepCopy(laptop)
+		if err != nil {
+			return &BatchError{Index: i, Err: fmt.Errorf("can not copy laptop data: %v", err)}
+		}
+		others[i] = other
+	}
+
+	for _, other := range others {
+		store.data[other.Id] = other
+		store.index(other)
+	}
+	return nil
+}
+
+// checkBatch checks that no laptop of the batch exists in the store or appears twice, the mutex must be held
+func (store *InMemoryLaptopStore) checkBatch(laptops []*pd.Laptop) error {
+	seen := make(map[string]bool, len(laptops))
+	for i, laptop := range laptops {
+		if store.data[laptop.Id] != nil || seen[laptop.Id] {
+			return &BatchError{Index: i, Err: ErrAlreadyExists}
+		}
+		seen[laptop.Id] = true
+	}
 	return nil
 }
 
+// verifyBatch is checkBatch for the stores built on top of this one
+func (store *InMemoryLaptopStore) verifyBatch(laptops []*pd.Laptop) error {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.checkBatch(laptops)
+}
+
 func (store *InMemoryLaptopStore) Find(id string) (*pd.Laptop, error) {
 	store.mutex.RLock()
 	defer store.mutex.RUnlock()
@@ -65,32 +149,257 @@ func (store *InMemoryLaptopStore) Find(id string) (*pd.Laptop, error) {
 	return deepCopy(laptop)
 }
 
-func (store *InMemoryLaptopStore) Search(ctx context.Context, filter *pd.Filter, found func(laptop *pd.Laptop) error) error {
+// Update replaces the laptop with the same id
+func (store *InMemoryLaptopStore) Update(laptop *pd.Laptop, etag string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.checkEtag(laptop.Id, etag)
+	if err != nil {
+		return err
+	}
+
+	other, err := deepCopy(laptop)
+	if err != nil {
+		return fmt.Errorf("can not copy laptop data: %v", err)
+	}
+	store.unindex(store.data[other.Id])
+	store.data[other.Id] = other
+	store.index(other)
+	return nil
+}
+
+// Delete deletes a laptop by id
+func (store *InMemoryLaptopStore) Delete(id string, etag string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	err := store.checkEtag(id, etag)
+	if err != nil {
+		return err
+	}
+
+	store.unindex(store.data[id])
+	delete(store.data, id)
+	return nil
+}
+
+// index adds the laptop to every index, the mutex must be held
+func (store *InMemoryLaptopStore) index(laptop *pd.Laptop) {
+	for _, index := range store.indexes {
+		index.insert(laptop)
+	}
+	store.text.add(laptop)
+}
+
+// unindex removes the laptop from every index, the mutex must be held
+func (store *InMemoryLaptopStore) unindex(laptop *pd.Laptop) {
+	for _, index := range store.indexes {
+		index.remove(laptop)
+	}
+	store.text.remove(laptop)
+}
+
+// verifyEtag checks that the laptop exists and matches etag, it is used by stores built on top of this one
+func (store *InMemoryLaptopStore) verifyEtag(id string, etag string) error {
 	store.mutex.RLock()
 	defer store.mutex.RUnlock()
 
-	for _, laptop := range store.data {
-		// heavy process
-		//time.Sleep(time.Second)
-		//fmt.Println("check laptop id: ", laptop.GetId())
+	return store.checkEtag(id, etag)
+}
+
+// checkEtag must be called with the mutex held
+func (store *InMemoryLaptopStore) checkEtag(id string, etag string) error {
+	laptop := store.data[id]
+	if laptop == nil {
+		return ErrNotFound
+	}
+	if etag != "" && LaptopEtag(laptop) != etag {
+		return ErrConflict
+	}
+	return nil
+}
 
-		// 检查上下文
-		if ctx.Err() == context.Canceled || ctx.Err()==context.DeadlineExceeded {
-			log.Print("context is canceled")
-			return errors.New("context is canceled")
+func (store *InMemoryLaptopStore) Search(ctx context.Context, query *SearchQuery, found func(laptop *pd.Laptop, score float64) error) error {
+	results, err := store.search(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	// found is called without holding the lock, so a slow client doesn't block the writers
+	for _, result := range results {
+		err := found(result.laptop, result.score)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Facets counts the laptops that match the filter and the text of the query for each facet value.
+// The limit, the order and the cursor of the query are ignored.
+func (store *InMemoryLaptopStore) Facets(ctx context.Context, query *SearchQuery) (*FacetCounts, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	facets := newFacetCounts()
+	err := store.match(ctx, query, func(laptop *pd.Laptop, score float64) {
+		facets.add(laptop)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
+
+// search returns copies of the laptops in the page of the query
+func (store *InMemoryLaptopStore) search(ctx context.Context, query *SearchQuery) ([]searchResult, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	scores := store.textScores(query)
+
+	// 排序字段有索引时, 从游标开始按索引读取, 找到一页就停止;
+	// 文本查询匹配的laptop更少时, 仍然排序所有匹配的laptop
+	var results []searchResult
+	var entries []indexEntry
+	index := sortIndex(store.indexes, query)
+	if index != nil {
+		entries = index.page(query)
+	}
+	if index != nil && query.Limit > 0 && (scores == nil || len(scores) >= len(entries)) {
+		var err error
+		results, err = store.walk(ctx, query, scores, entries)
+		if err != nil {
+			return nil, err
 		}
+	} else {
+		// the orders without an index sort every match with the heap
+		top := newTopResults(query)
+		err := store.matchScores(ctx, query, scores, func(laptop *pd.Laptop, score float64) {
+			top.add(laptop, score)
+		})
+		if err != nil {
+			return nil, err
+		}
+		results = top.sorted()
+	}
 
-		if isQualified(filter, laptop) {
-			// deep copy
-			other, err := deepCopy(laptop)
-			if err != nil {
-				return nil
+	// 只复制结果中的laptop
+	for i := range results {
+		other, err := deepCopy(results[i].laptop)
+		if err != nil {
+			return nil, err
+		}
+		results[i].laptop = other
+	}
+	return results, nil
+}
+
+// walk reads the entries of the sort index of the query in its order and stops once the page is full,
+// the mutex must be held
+func (store *InMemoryLaptopStore) walk(ctx context.Context, query *SearchQuery, scores map[string]float64, entries []indexEntry) ([]searchResult, error) {
+	var results []searchResult
+	for i := range entries {
+		entry := entries[i]
+		if query.Descending {
+			entry = entries[len(entries)-1-i]
+		}
+
+		laptop := store.data[entry.id]
+		score, ok, err := store.matches(ctx, query, scores, laptop)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		results = append(results, searchResult{laptop: laptop, score: score, position: query.cursor(laptop, score)})
+		if len(results) == query.Limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// match calls fn with every laptop that matches the filter and the text of the query,
+// the laptops must not be modified and the mutex must be held
+func (store *InMemoryLaptopStore) match(ctx context.Context, query *SearchQuery, fn func(laptop *pd.Laptop, score float64)) error {
+	return store.matchScores(ctx, query, store.textScores(query), fn)
+}
+
+// textScores returns the relevance of the laptops that match the text query, nil if there is no text query.
+// The mutex must be held.
+func (store *InMemoryLaptopStore) textScores(query *SearchQuery) map[string]float64 {
+	if tokens := query.tokens(); len(tokens) > 0 {
+		return store.text.search(tokens)
+	}
+	return nil
+}
+
+// matches reports whether the laptop matches the filter and the text scores, with its score
+func (store *InMemoryLaptopStore) matches(ctx context.Context, query *SearchQuery, scores map[string]float64, laptop *pd.Laptop) (float64, bool, error) {
+	// 检查上下文
+	if ctx.Err() == context.Canceled || ctx.Err()==context.DeadlineExceeded {
+		log.Print("context is canceled")
+		return 0, false, errors.New("context is canceled")
+	}
+
+	score, matched := scores[laptop.Id]
+	if scores != nil && !matched {
+		return 0, false, nil
+	}
+	return score, isQualified(query.Filter, laptop), nil
+}
+
+// matchScores is match with the scores of the text query, nil if there is no text query
+func (store *InMemoryLaptopStore) matchScores(ctx context.Context, query *SearchQuery, scores map[string]float64, fn func(laptop *pd.Laptop, score float64)) error {
+	check := func(laptop *pd.Laptop) error {
+		score, ok, err := store.matches(ctx, query, scores, laptop)
+		if err != nil {
+			return err
+		}
+		if ok {
+			fn(laptop, score)
+		}
+		return nil
+	}
+
+	// 只检查最小的索引范围内的laptop, 其他条件仍由isQualified检查
+	entries, ok := planSearch(store.indexes, query.Filter)
+	switch {
+	case scores != nil && (!ok || len(scores) < len(entries)):
+		for id := range scores {
+			if err := check(store.data[id]); err != nil {
+				return err
 			}
-			err = found(other)
-			if err != nil {
+		}
+	case ok:
+		for _, entry := range entries {
+			if err := check(store.data[entry.id]); err != nil {
+				return err
+			}
+		}
+	default:
+		for _, laptop := range store.data {
+			if err := check(laptop); err != nil {
 				return err
 			}
+		}
+	}
+	return nil
+}
 
+// forEach calls fn with every laptop in the store, the laptops must not be modified
+func (store *InMemoryLaptopStore) forEach(fn func(laptop *pd.Laptop) error) error {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for _, laptop := range store.data {
+		err := fn(laptop)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
@@ -102,9 +411,123 @@ func isQualified(filter *pd.Filter, laptop *pd.Laptop) bool {
 	if laptop.GetCpu().GetMinGhz() < filter.GetMinCpuGhz() { return false }
 	// 将内存统一转成bit大小，再进行比较
 	if toBit(laptop.Ram) < toBit(filter.MinRam) { return false }
+
+	if len(filter.GetBrands()) > 0 && !containsString(filter.GetBrands(), laptop.GetBrand()) {
+		return false
+	}
+	if filter.GetMinGpuMemory() != nil && maxGpuMemory(laptop) < toBit(filter.GetMinGpuMemory()) {
+		return false
+	}
+	if filter.GetSsdOnly() && !isSsdOnly(laptop) {
+		return false
+	}
+	if totalStorage(laptop) < toBit(filter.GetMinStorage()) {
+		return false
+	}
+	if !isScreenQualified(filter, laptop.GetScreen()) {
+		return false
+	}
+	if filter.GetBacklitKeyboard() && !laptop.GetKeyboard().GetBacklit() {
+		return false
+	}
+	// 重量统一转成kg再比较, 没有重量的laptop不满足重量条件
+	if filter.GetMaxWeight() != nil {
+		weight := toKg(laptop)
+		if weight == 0 || weight > maxWeightKg(filter) {
+			return false
+		}
+	}
+	if laptop.GetReleaseYear() < filter.GetMinReleaseYear() {
+		return false
+	}
+	if filter.GetMaxReleaseYear() > 0 && laptop.GetReleaseYear() > filter.GetMaxReleaseYear() {
+		return false
+	}
 	return true
 }
 
+func isScreenQualified(filter *pd.Filter, screen *pd.Screen) bool {
+	if screen.GetSizeInch() < filter.GetMinScreenSizeInch() {
+		return false
+	}
+	if filter.GetMaxScreenSizeInch() > 0 && screen.GetSizeInch() > filter.GetMaxScreenSizeInch() {
+		return false
+	}
+	if filter.GetScreenPanel() != pd.Screen_UNKNOWN && screen.GetPanel() != filter.GetScreenPanel() {
+		return false
+	}
+	if screen.GetResolution().GetWidth() < filter.GetMinResolution().GetWidth() ||
+		screen.GetResolution().GetHeight() < filter.GetMinResolution().GetHeight() {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// maxGpuMemory returns the memory in bits of the largest GPU
+func maxGpuMemory(laptop *pd.Laptop) uint64 {
+	var max uint64
+	for _, gpu := range laptop.GetGpus() {
+		if memory := toBit(gpu.GetMemory()); memory > max {
+			max = memory
+		}
+	}
+	return max
+}
+
+// totalStorage returns the size in bits of all the storages
+func totalStorage(laptop *pd.Laptop) uint64 {
+	var total uint64
+	for _, storage := range laptop.GetStorages() {
+		total += toBit(storage.GetMemory())
+	}
+	return total
+}
+
+func isSsdOnly(laptop *pd.Laptop) bool {
+	for _, storage := range laptop.GetStorages() {
+		if storage.GetDriver() != pd.Storage_SSD {
+			return false
+		}
+	}
+	return len(laptop.GetStorages()) > 0
+}
+
+// kgPerLb 1磅等于0.45359237千克
+const kgPerLb = 0.45359237
+
+// toKg returns the weight of the laptop in kilograms, or 0 if it is unknown
+func toKg(laptop *pd.Laptop) float64 {
+	switch weight := laptop.GetWeight().(type) {
+	case *pd.Laptop_WeightKg:
+		return weight.WeightKg
+	case *pd.Laptop_WeightLb:
+		return weight.WeightLb * kgPerLb
+	default:
+		return 0
+	}
+}
+
+// maxWeightKg returns the maximum weight of the filter in kilograms
+func maxWeightKg(filter *pd.Filter) float64 {
+	switch weight := filter.GetMaxWeight().(type) {
+	case *pd.Filter_MaxWeightKg:
+		return weight.MaxWeightKg
+	case *pd.Filter_MaxWeightLb:
+		return weight.MaxWeightLb * kgPerLb
+	default:
+		return 0
+	}
+}
+
 func toBit(memory *pd.Memory) uint64 {
 	value := memory.GetValue()
 
@@ -127,18 +550,11 @@ func toBit(memory *pd.Memory) uint64 {
 }
 
 func deepCopy(laptop *pd.Laptop) (*pd.Laptop, error) {
-	other := &pd.Laptop{}
-
-	err := copier.Copy(other, laptop)
-	if err != nil {
-		return nil, fmt.Errorf("cannot copy laptop data: %w", err)
+	other, ok := proto.Clone(laptop).(*pd.Laptop)
+	if !ok {
+		return nil, fmt.Errorf("cannot copy laptop data")
 	}
 
 	return other, nil
 }
 
-// DBLaptopStore store laptop DB
-// 存储在数据库中
-type DBLaptopStore struct {
-
-}
@@ -0,0 +1,844 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"os"
+	"path/filepath"
+	"pc_book/pd"
+	"pc_book/sample"
+	"pc_book/service"
+	"sort"
+	"testing"
+)
+
+// laptopStoreFactories returns a constructor for each LaptopStore implementation,
+// so that all of them are checked by the same tests
+func laptopStoreFactories() map[string]func(t *testing.T) service.LaptopStore {
+	return map[string]func(t *testing.T) service.LaptopStore{
+		"in_memory": func(t *testing.T) service.LaptopStore {
+			return service.NewInMemoryLaptopStore()
+		},
+		"file": func(t *testing.T) service.LaptopStore {
+			store, err := service.NewFileLaptopStore(t.TempDir())
+			require.NoError(t, err)
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+		"sqlite": func(t *testing.T) service.LaptopStore {
+			store, err := service.OpenSQLiteLaptopStore(filepath.Join(t.TempDir(), "laptops.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+	}
+}
+
+func TestLaptopStoreSaveFind(t *testing.T) {
+	t.Parallel()
+
+	for name, newStore := range laptopStoreFactories() {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := newStore(t)
+			laptop := sample.NewLaptop()
+			require.NoError(t, store.Save(laptop))
+
+			other, err := store.Find(laptop.Id)
+			require.NoError(t, err)
+			require.NotNil(t, other)
+			requireSameLaptop(t, laptop, other)
+
+			// the store keeps its own copy
+			other.Brand = "changed"
+			again, err := store.Find(laptop.Id)
+			require.NoError(t, err)
+			require.Equal(t, laptop.Brand, again.Brand)
+
+			missing, err := store.Find(sample.NewLaptop().Id)
+			require.NoError(t, err)
+			require.Nil(t, missing)
+
+			err = store.Save(laptop)
+			require.True(t, errors.Is(err, service.ErrAlreadyExists))
+		})
+	}
+}
+
+func TestLaptopStoreSaveBatch(t *testing.T) {
+	t.Parallel()
+
+	for name, newStore := range laptopStoreFactories() {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := newStore(t)
+			existing := sample.NewLaptop()
+			require.NoError(t, store.Save(existing))
+
+			// nothing is saved when one laptop already exists
+			fresh := sample.NewLaptop()
+			err := store.SaveBatch([]*pd.Laptop{fresh, existing})
+			var batchErr *service.BatchError
+			require.True(t, errors.As(err, &batchErr))
+			require.Equal(t, 1, batchErr.Index)
+			require.True(t, errors.Is(err, service.ErrAlreadyExists))
+
+			found, err := store.Find(fresh.Id)
+			require.NoError(t, err)
+			require.Nil(t, found)
+
+			// nor when the batch has the same laptop twice
+			err = store.SaveBatch([]*pd.Laptop{fresh, sample.NewLaptop(), fresh})
+			require.True(t, errors.As(err, &batchErr))
+			require.Equal(t, 2, batchErr.Index)
+
+			laptops := []*pd.Laptop{fresh, sample.NewLaptop(), sample.NewLaptop()}
+			require.NoError(t, store.SaveBatch(laptops))
+			for _, laptop := range laptops {
+				found, err := store.Find(laptop.Id)
+				require.NoError(t, err)
+				require.NotNil(t, found)
+				requireSameLaptop(t, laptop, found)
+			}
+		})
+	}
+}
+
+func TestLaptopStoreSearch(t *testing.T) {
+	t.Parallel()
+
+	filter := &pd.Filter{
+		MaxPriceUsd: 2000,
+		MinCpuCores: 4,
+		MinCpuGhz:   2.2,
+		MinRam:      &pd.Memory{Value: 8, Unit: pd.Memory_GIGABYTE},
+	}
+
+	for name, newStore := range laptopStoreFactories() {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := newStore(t)
+			expectedIDs := saveSearchLaptops(t, store)
+
+			found := make(map[string]bool)
+			err := store.Search(context.Background(), &service.SearchQuery{Filter: filter}, func(laptop *pd.Laptop, score float64) error {
+				found[laptop.Id] = true
+				return nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, expectedIDs, found)
+		})
+	}
+}
+
+func TestLaptopStoreSearchCriteria(t *testing.T) {
+	t.Parallel()
+
+	newLaptop := func() *pd.Laptop {
+		laptop := sample.NewLaptop()
+		laptop.Brand = "Dell"
+		laptop.PriceUsd = 2000
+		laptop.Gpus[0].Memory = &pd.Memory{Value: 8, Unit: pd.Memory_GIGABYTE}
+		laptop.Storages = []*pd.Storage{
+			{Driver: pd.Storage_SSD, Memory: &pd.Memory{Value: 512, Unit: pd.Memory_GIGABYTE}},
+			{Driver: pd.Storage_SSD, Memory: &pd.Memory{Value: 512, Unit: pd.Memory_GIGABYTE}},
+		}
+		laptop.Screen = &pd.Screen{
+			SizeInch:   15.6,
+			Resolution: &pd.Screen_Resolution{Width: 1920, Height: 1080},
+			Panel:      pd.Screen_IPS,
+		}
+		laptop.Keyboard = &pd.Keyboard{Layout: pd.Keyboard_QWERTY, Backlit: true}
+		laptop.Weight = &pd.Laptop_WeightLb{WeightLb: 4.4}
+		laptop.ReleaseYear = 2018
+		return laptop
+	}
+
+	testCases := []struct {
+		name   string
+		filter *pd.Filter
+		// mismatch changes the laptop so that it doesn't match the filter anymore
+		mismatch func(laptop *pd.Laptop)
+	}{
+		{
+			name:     "brands",
+			filter:   &pd.Filter{Brands: []string{"Apple", "Dell"}},
+			mismatch: func(laptop *pd.Laptop) { laptop.Brand = "Lenovo" },
+		},
+		{
+			name:     "min_gpu_memory",
+			filter:   &pd.Filter{MinGpuMemory: &pd.Memory{Value: 8192, Unit: pd.Memory_MEGABYTE}},
+			mismatch: func(laptop *pd.Laptop) { laptop.Gpus[0].Memory.Value = 4 },
+		},
+		{
+			name:     "ssd_only",
+			filter:   &pd.Filter{SsdOnly: true},
+			mismatch: func(laptop *pd.Laptop) { laptop.Storages[1].Driver = pd.Storage_HDD },
+		},
+		{
+			name:     "min_storage",
+			filter:   &pd.Filter{MinStorage: &pd.Memory{Value: 1, Unit: pd.Memory_TERABYTE}},
+			mismatch: func(laptop *pd.Laptop) { laptop.Storages = laptop.Storages[:1] },
+		},
+		{
+			name:     "screen_size",
+			filter:   &pd.Filter{MinScreenSizeInch: 14, MaxScreenSizeInch: 16},
+			mismatch: func(laptop *pd.Laptop) { laptop.Screen.SizeInch = 17.3 },
+		},
+		{
+			name:     "screen_panel",
+			filter:   &pd.Filter{ScreenPanel: pd.Screen_IPS},
+			mismatch: func(laptop *pd.Laptop) { laptop.Screen.Panel = pd.Screen_OLED },
+		},
+		{
+			name:     "min_resolution",
+			filter:   &pd.Filter{MinResolution: &pd.Screen_Resolution{Width: 1920, Height: 1080}},
+			mismatch: func(laptop *pd.Laptop) { laptop.Screen.Resolution.Height = 1200; laptop.Screen.Resolution.Width = 1600 },
+		},
+		{
+			name:     "backlit_keyboard",
+			filter:   &pd.Filter{BacklitKeyboard: true},
+			mismatch: func(laptop *pd.Laptop) { laptop.Keyboard.Backlit = false },
+		},
+		{
+			name:     "max_weight_kg",
+			filter:   &pd.Filter{MaxWeight: &pd.Filter_MaxWeightKg{MaxWeightKg: 2.1}},
+			mismatch: func(laptop *pd.Laptop) { laptop.Weight = &pd.Laptop_WeightKg{WeightKg: 2.2} },
+		},
+		{
+			name:     "max_weight_lb",
+			filter:   &pd.Filter{MaxWeight: &pd.Filter_MaxWeightLb{MaxWeightLb: 4.5}},
+			mismatch: func(laptop *pd.Laptop) { laptop.Weight = nil },
+		},
+		{
+			name:     "release_year",
+			filter:   &pd.Filter{MinReleaseYear: 2017, MaxReleaseYear: 2018},
+			mismatch: func(laptop *pd.Laptop) { laptop.ReleaseYear = 2019 },
+		},
+	}
+
+	for name, newStore := range laptopStoreFactories() {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, tc := range testCases {
+				tc := tc
+				t.Run(tc.name, func(t *testing.T) {
+					store := newStore(t)
+
+					match := newLaptop()
+					require.NoError(t, store.Save(match))
+					other := newLaptop()
+					tc.mismatch(other)
+					require.NoError(t, store.Save(other))
+
+					tc.filter.MaxPriceUsd = 3000
+					found := make(map[string]bool)
+					err := store.Search(context.Background(), &service.SearchQuery{Filter: tc.filter}, func(laptop *pd.Laptop, score float64) error {
+						found[laptop.Id] = true
+						return nil
+					})
+					require.NoError(t, err)
+					require.Equal(t, map[string]bool{match.Id: true}, found)
+				})
+			}
+		})
+	}
+}
+
+func TestLaptopStoreSearchSorted(t *testing.T) {
+	t.Parallel()
+
+	ratings := make(map[string]float64)
+	laptops := make([]*pd.Laptop, 10)
+	for i := range laptops {
+		laptops[i] = sample.NewLaptop()
+		// ties are ordered by id
+		laptops[i].PriceUsd = float64(1000 + i%4*100)
+		ratings[laptops[i].Id] = float64(i % 3)
+	}
+
+	testCases := []struct {
+		sortBy pd.SearchLaptopRequest_SortBy
+		value  func(laptop *pd.Laptop) float64
+	}{
+		{pd.SearchLaptopRequest_ID, func(laptop *pd.Laptop) float64 { return 0 }},
+		{pd.SearchLaptopRequest_PRICE, func(laptop *pd.Laptop) float64 { return laptop.PriceUsd }},
+		{pd.SearchLaptopRequest_CPU_GHZ, func(laptop *pd.Laptop) float64 { return laptop.Cpu.MinGhz }},
+		{pd.SearchLaptopRequest_RELEASE_YEAR, func(laptop *pd.Laptop) float64 { return float64(laptop.ReleaseYear) }},
+		{pd.SearchLaptopRequest_RATING, func(laptop *pd.Laptop) float64 { return ratings[laptop.Id] }},
+	}
+
+	for name, newStore := range laptopStoreFactories() {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := newStore(t)
+			for _, laptop := range laptops {
+				require.NoError(t, store.Save(laptop))
+			}
+
+			for _, tc := range testCases {
+				for _, descending := range []bool{false, true} {
+					expected := make([]string, len(laptops))
+					sorted := append([]*pd.Laptop(nil), laptops...)
+					sort.Slice(sorted, func(i, j int) bool {
+						if descending {
+							i, j = j, i
+						}
+						a, b := tc.value(sorted[i]), tc.value(sorted[j])
+						if a != b {
+							return a < b
+						}
+						return sorted[i].Id < sorted[j].Id
+					})
+					for i, laptop := range sorted {
+						expected[i] = laptop.Id
+					}
+
+					// read the laptops 3 by 3
+					query := &service.SearchQuery{
+						Filter:     &pd.Filter{MaxPriceUsd: 5000},
+						SortBy:     tc.sortBy,
+						Descending: descending,
+						Limit:      3,
+						Rating:     func(laptopID string) float64 { return ratings[laptopID] },
+					}
+
+					var ids []string
+					for {
+						var last *pd.Laptop
+						count := 0
+						err := store.Search(context.Background(), query, func(laptop *pd.Laptop, score float64) error {
+							ids = append(ids, laptop.Id)
+							last = laptop
+							count++
+							return nil
+						})
+						require.NoError(t, err)
+						require.LessOrEqual(t, count, query.Limit)
+
+						if count < query.Limit {
+							break
+						}
+						query.After = &service.SearchCursor{Value: tc.value(last), ID: last.Id}
+					}
+					require.Equal(t, expected, ids, "sort by %v, descending: %v", tc.sortBy, descending)
+				}
+			}
+		})
+	}
+}
+
+func TestLaptopStoreTextSearch(t *testing.T) {
+	t.Parallel()
+
+	newLaptop := func(brand string, name string, cpuName string) *pd.Laptop {
+		laptop := sample.NewLaptop()
+		laptop.Brand = brand
+		laptop.Name = name
+		laptop.Cpu.Name = cpuName
+		laptop.Gpus[0].Name = "RTX 2070"
+		return laptop
+	}
+
+	x1 := newLaptop("Lenovo", "Thinkpad X1", "Core i7-8550U")
+	p1 := newLaptop("Lenovo", "Thinkpad P1", "Core i9-9880H")
+	mac := newLaptop("Apple", "Macbook Pro", "Core i7-8850H")
+	// "thinkpad" is only the beginning of a word of the CPU name
+	other := newLaptop("Dell", "XPS 13", "Thinkpadish")
+
+	search := func(t *testing.T, store service.LaptopStore, text string) ([]string, map[string]float64) {
+		query := &service.SearchQuery{
+			Filter:     &pd.Filter{MaxPriceUsd: 5000},
+			Text:       text,
+			SortBy:     pd.SearchLaptopRequest_RELEVANCE,
+			Descending: true,
+		}
+
+		var ids []string
+		scores := make(map[string]float64)
+		err := store.Search(context.Background(), query, func(laptop *pd.Laptop, score float64) error {
+			ids = append(ids, laptop.Id)
+			scores[laptop.Id] = score
+			return nil
+		})
+		require.NoError(t, err)
+		return ids, scores
+	}
+
+	for name, newStore := range laptopStoreFactories() {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := newStore(t)
+			for _, laptop := range []*pd.Laptop{x1, p1, mac, other} {
+				require.NoError(t, store.Save(laptop))
+			}
+
+			ids, _ := search(t, store, "ThinkPad X1")
+			require.Equal(t, []string{x1.Id}, ids)
+
+			// every word must match
+			ids, _ = search(t, store, "thinkpad macbook")
+			require.Empty(t, ids)
+
+			ids, scores := search(t, store, "think")
+			require.Len(t, ids, 3)
+			require.Equal(t, other.Id, ids[2])
+			require.Greater(t, scores[x1.Id], scores[other.Id])
+			require.Equal(t, scores[x1.Id], scores[p1.Id])
+
+			ids, _ = search(t, store, "i7 rtx")
+			require.ElementsMatch(t, []string{x1.Id, mac.Id}, ids)
+
+			// the index follows updates and deletes
+			updated := newLaptop("Lenovo", "Yoga Slim", "Core i7-8550U")
+			updated.Id = x1.Id
+			require.NoError(t, store.Update(updated, ""))
+			require.NoError(t, store.Delete(p1.Id, ""))
+
+			ids, _ = search(t, store, "thinkpad")
+			require.Equal(t, []string{other.Id}, ids)
+			ids, _ = search(t, store, "yoga")
+			require.Equal(t, []string{x1.Id}, ids)
+		})
+	}
+}
+
+func TestLaptopStoreFacets(t *testing.T) {
+	t.Parallel()
+
+	newLaptop := func(brand string, ram uint64, storages ...*pd.Storage) *pd.Laptop {
+		laptop := sample.NewLaptop()
+		laptop.Brand = brand
+		laptop.Name = brand + " Book"
+		laptop.PriceUsd = 1000
+		laptop.Cpu.Brand = "Intel"
+		laptop.Ram = &pd.Memory{Value: ram, Unit: pd.Memory_GIGABYTE}
+		laptop.Storages = storages
+		laptop.Screen.Panel = pd.Screen_IPS
+		return laptop
+	}
+
+	laptops := []*pd.Laptop{
+		newLaptop("Apple", 16, sample.NewSSD()),
+		newLaptop("Apple", 32, sample.NewSSD(), sample.NewSSD()),
+		newLaptop("Dell", 64, sample.NewSSD(), sample.NewHDD()),
+		newLaptop("Lenovo", 8, sample.NewHDD()),
+	}
+	// too expensive for the filter
+	expensive := newLaptop("Dell", 4)
+	expensive.PriceUsd = 4000
+	laptops = append(laptops, expensive)
+
+	for name, newStore := range laptopStoreFactories() {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := newStore(t)
+			for _, laptop := range laptops {
+				require.NoError(t, store.Save(laptop))
+			}
+
+			query := &service.SearchQuery{Filter: &pd.Filter{MaxPriceUsd: 2000}}
+			facets, err := store.Facets(context.Background(), query)
+			require.NoError(t, err)
+			require.Equal(t, 4, facets.Total)
+			require.Equal(t, map[string]int{"Apple": 2, "Dell": 1, "Lenovo": 1}, facets.Counts["brand"])
+			require.Equal(t, map[string]int{"64GB+": 1, "32GB": 1, "16GB": 1, "8GB": 1}, facets.Counts["ram"])
+			require.Equal(t, map[string]int{"Intel": 4}, facets.Counts["cpu_brand"])
+			require.Equal(t, map[string]int{"IPS": 4}, facets.Counts["screen_panel"])
+			// a laptop with two SSDs is counted once
+			require.Equal(t, map[string]int{"SSD": 3, "HDD": 2}, facets.Counts["storage_driver"])
+
+			query = &service.SearchQuery{Filter: &pd.Filter{MaxPriceUsd: 5000}, Text: "dell"}
+			facets, err = store.Facets(context.Background(), query)
+			require.NoError(t, err)
+			require.Equal(t, 2, facets.Total)
+			require.Equal(t, map[string]int{"Dell": 2}, facets.Counts["brand"])
+			require.Equal(t, map[string]int{"64GB+": 1, "<8GB": 1}, facets.Counts["ram"])
+		})
+	}
+}
+
+func TestLaptopStoreUpdateDelete(t *testing.T) {
+	t.Parallel()
+
+	for name, newStore := range laptopStoreFactories() {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := newStore(t)
+			laptop := sample.NewLaptop()
+			require.NoError(t, store.Save(laptop))
+			etag := service.LaptopEtag(laptop)
+
+			updated := sample.NewLaptop()
+			updated.Id = laptop.Id
+			require.NoError(t, store.Update(updated, etag))
+
+			other, err := store.Find(laptop.Id)
+			require.NoError(t, err)
+			requireSameLaptop(t, updated, other)
+
+			// the etag of the old version is stale now
+			err = store.Update(laptop, etag)
+			require.True(t, errors.Is(err, service.ErrConflict))
+			err = store.Delete(laptop.Id, etag)
+			require.True(t, errors.Is(err, service.ErrConflict))
+
+			require.NoError(t, store.Delete(laptop.Id, service.LaptopEtag(updated)))
+			other, err = store.Find(laptop.Id)
+			require.NoError(t, err)
+			require.Nil(t, other)
+
+			err = store.Update(laptop, "")
+			require.True(t, errors.Is(err, service.ErrNotFound))
+			err = store.Delete(laptop.Id, "")
+			require.True(t, errors.Is(err, service.ErrNotFound))
+		})
+	}
+}
+
+func TestLaptopStoreServerCreateLaptop(t *testing.T) {
+	t.Parallel()
+
+	for name, newStore := range laptopStoreFactories() {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			server := service.NewLaptopService(newStore(t), nil, nil)
+			laptop := sample.NewLaptop()
+
+			res, err := server.CreateLaptop(context.Background(), &pd.CreateLaptopRequest{Laptop: laptop})
+			require.NoError(t, err)
+			require.Equal(t, laptop.Id, res.Id)
+
+			_, err = server.CreateLaptop(context.Background(), &pd.CreateLaptopRequest{Laptop: laptop})
+			requireCode(t, codes.AlreadyExists, err)
+		})
+	}
+}
+
+func TestFileLaptopStoreReopen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+
+	laptops := []*pd.Laptop{sample.NewLaptop(), sample.NewLaptop(), sample.NewLaptop()}
+	for _, laptop := range laptops {
+		require.NoError(t, store.Save(laptop))
+	}
+	require.NoError(t, store.Close())
+
+	store, err = service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, laptop := range laptops {
+		other, err := store.Find(laptop.Id)
+		require.NoError(t, err)
+		require.NotNil(t, other)
+		requireSameLaptop(t, laptop, other)
+	}
+
+	err = store.Save(laptops[0])
+	require.True(t, errors.Is(err, service.ErrAlreadyExists))
+}
+
+func TestFileLaptopStoreReopenAfterBatch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+
+	laptops := []*pd.Laptop{sample.NewLaptop(), sample.NewLaptop(), sample.NewLaptop()}
+	require.NoError(t, store.SaveBatch(laptops))
+	require.NoError(t, store.Close())
+
+	store, err = service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, laptop := range laptops {
+		other, err := store.Find(laptop.Id)
+		require.NoError(t, err)
+		require.NotNil(t, other)
+		requireSameLaptop(t, laptop, other)
+	}
+}
+
+func TestFileLaptopStoreReopenAfterUpdate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+
+	laptop1 := sample.NewLaptop()
+	laptop2 := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop1))
+	require.NoError(t, store.Save(laptop2))
+
+	updated := sample.NewLaptop()
+	updated.Id = laptop1.Id
+	require.NoError(t, store.Update(updated, ""))
+	require.NoError(t, store.Delete(laptop2.Id, ""))
+	require.NoError(t, store.Close())
+
+	store, err = service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	other, err := store.Find(laptop1.Id)
+	require.NoError(t, err)
+	requireSameLaptop(t, updated, other)
+
+	other, err = store.Find(laptop2.Id)
+	require.NoError(t, err)
+	require.Nil(t, other)
+}
+
+func TestFileLaptopStoreTornRecord(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+
+	laptop1 := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop1))
+	require.NoError(t, store.Close())
+
+	// simulate a crash in the middle of writing the next record
+	wal, err := os.OpenFile(filepath.Join(dir, "laptops.wal"), os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = wal.Write([]byte{0, 0, 1, 0, 0xde, 0xad})
+	require.NoError(t, err)
+	require.NoError(t, wal.Close())
+
+	store, err = service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+
+	found, err := store.Find(laptop1.Id)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+
+	// new records are written after the last complete one
+	laptop2 := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop2))
+	require.NoError(t, store.Close())
+
+	store, err = service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, laptop := range []*pd.Laptop{laptop1, laptop2} {
+		found, err := store.Find(laptop.Id)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+	}
+}
+
+func TestFileLaptopStoreCorruptedRecord(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	require.NoError(t, store.Save(sample.NewLaptop()))
+	require.NoError(t, store.Save(sample.NewLaptop()))
+	require.NoError(t, store.Close())
+
+	walPath := filepath.Join(dir, "laptops.wal")
+	data, err := os.ReadFile(walPath)
+	require.NoError(t, err)
+
+	// a bad record followed by other records is not a crash, the laptops after it would be lost
+	corrupted := append([]byte{}, data...)
+	corrupted[10] ^= 0xff
+	require.NoError(t, os.WriteFile(walPath, corrupted, 0644))
+
+	_, err = service.NewFileLaptopStore(dir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "corrupted record at offset 0")
+
+	// zeros at the end are left by a crash while the file was extended
+	require.NoError(t, os.WriteFile(walPath, append(data, make([]byte, 20)...), 0644))
+
+	store, err = service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	stat, err := os.Stat(walPath)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(data)), stat.Size())
+}
+
+func TestFileLaptopStoreCompact(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	store.SetCompactThreshold(3)
+
+	laptops := make([]*pd.Laptop, 7)
+	for i := range laptops {
+		laptops[i] = sample.NewLaptop()
+		require.NoError(t, store.Save(laptops[i]))
+	}
+	require.NoError(t, store.Close())
+
+	require.FileExists(t, filepath.Join(dir, "laptops.snapshot"))
+	walInfo, err := os.Stat(filepath.Join(dir, "laptops.wal"))
+	require.NoError(t, err)
+	snapshotInfo, err := os.Stat(filepath.Join(dir, "laptops.snapshot"))
+	require.NoError(t, err)
+	require.Less(t, walInfo.Size(), snapshotInfo.Size())
+
+	store, err = service.NewFileLaptopStore(dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, laptop := range laptops {
+		other, err := store.Find(laptop.Id)
+		require.NoError(t, err)
+		require.NotNil(t, other)
+		requireSameLaptop(t, laptop, other)
+	}
+}
+
+func TestDBLaptopStoreReopen(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "laptops.db")
+	store, err := service.OpenSQLiteLaptopStore(path)
+	require.NoError(t, err)
+
+	laptop := sample.NewLaptop()
+	require.NoError(t, store.Save(laptop))
+	require.NoError(t, store.Close())
+
+	// the migrations are not applied again on an existing database
+	store, err = service.OpenSQLiteLaptopStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	other, err := store.Find(laptop.Id)
+	require.NoError(t, err)
+	require.NotNil(t, other)
+	requireSameLaptop(t, laptop, other)
+}
+
+func TestDBLaptopStoreMigrations(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "laptops.db")
+	store, err := service.OpenSQLiteLaptopStore(path)
+	require.NoError(t, err)
+
+	light := sample.NewLaptop()
+	light.Weight = &pd.Laptop_WeightLb{WeightLb: 2}
+	heavy := sample.NewLaptop()
+	heavy.Weight = &pd.Laptop_WeightKg{WeightKg: 2}
+	require.NoError(t, store.Save(light))
+	require.NoError(t, store.Save(heavy))
+	require.NoError(t, store.Close())
+
+	// roll the schema back to version 1, as if the laptops were saved by an older server
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`DROP TABLE laptop_terms;
+		DROP INDEX laptops_weight_kg;
+		ALTER TABLE laptops DROP COLUMN weight_kg;
+		DELETE FROM schema_migrations WHERE version > 1;`)
+	require.NoError(t, err)
+
+	dbStore, err := service.NewDBLaptopStore(db)
+	require.NoError(t, err)
+	defer dbStore.Close()
+
+	filter := &pd.Filter{
+		MaxPriceUsd: 5000,
+		MaxWeight:   &pd.Filter_MaxWeightKg{MaxWeightKg: 1.5},
+	}
+	found := make(map[string]bool)
+	err = dbStore.Search(context.Background(), &service.SearchQuery{Filter: filter}, func(laptop *pd.Laptop, score float64) error {
+		found[laptop.Id] = true
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{light.Id: true}, found)
+
+	var weight float64
+	err = db.QueryRow(`SELECT weight_kg FROM laptops WHERE id = ?`, heavy.Id).Scan(&weight)
+	require.NoError(t, err)
+	require.Equal(t, 2.0, weight)
+
+	// the words of the text search are filled too
+	found = make(map[string]bool)
+	query := &service.SearchQuery{Filter: &pd.Filter{MaxPriceUsd: 5000}, Text: heavy.Name}
+	err = dbStore.Search(context.Background(), query, func(laptop *pd.Laptop, score float64) error {
+		found[laptop.Id] = true
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, found[heavy.Id])
+}
+
+// saveSearchLaptops saves the laptops of TestClientSearchLaptop and returns the IDs that match its filter
+func saveSearchLaptops(t *testing.T, store service.LaptopStore) map[string]bool {
+	expectedIDs := make(map[string]bool)
+
+	for i := 0; i < 6; i++ {
+		laptop := sample.NewLaptop()
+
+		switch i {
+		case 0:
+			laptop.PriceUsd = 2500
+		case 1:
+			laptop.Cpu.NumberCores = 2
+		case 2:
+			laptop.Cpu.MinGhz = 2.0
+		case 3:
+			laptop.Ram = &pd.Memory{Value: 4096, Unit: pd.Memory_MEGABYTE}
+		case 4:
+			laptop.PriceUsd = 1999
+			laptop.Cpu.NumberCores = 4
+			laptop.Cpu.MinGhz = 2.5
+			laptop.Cpu.MaxGhz = 4.5
+			laptop.Ram = &pd.Memory{Value: 16, Unit: pd.Memory_GIGABYTE}
+			expectedIDs[laptop.Id] = true
+		case 5:
+			laptop.PriceUsd = 2000
+			laptop.Cpu.NumberCores = 6
+			laptop.Cpu.MinGhz = 2.5
+			laptop.Cpu.MaxGhz = 5.0
+			laptop.Ram = &pd.Memory{Value: 64, Unit: pd.Memory_GIGABYTE}
+			expectedIDs[laptop.Id] = true
+		}
+
+		require.NoError(t, store.Save(laptop))
+	}
+	return expectedIDs
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"pc_book/pd"
+	"strings"
+)
+
+// LaptopEtag returns a hash of the laptop content, it changes whenever the laptop is updated.
+// It is the etag expected by LaptopStore.Update and LaptopStore.Delete.
+func LaptopEtag(laptop *pd.Laptop) string {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(laptop)
+	if err != nil {
+		// a laptop that cannot be marshaled can never match an etag
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// immutableLaptopFields cannot be changed by UpdateLaptop
+var immutableLaptopFields = map[string]bool{
+	"id":         true,
+	"updated_at": true,
+}
+
+// updatableLaptopPaths returns the paths of all the fields that are replaced when the field mask is empty
+func updatableLaptopPaths() []string {
+	fields := (&pd.Laptop{}).ProtoReflect().Descriptor().Fields()
+
+	paths := make([]string, 0, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		name := string(fields.Get(i).Name())
+		if !immutableLaptopFields[name] {
+			paths = append(paths, name)
+		}
+	}
+	return paths
+}
+
+// applyLaptopMask copies the fields in paths from src to dst, a field that is not set in src is cleared in dst
+func applyLaptopMask(dst *pd.Laptop, src *pd.Laptop, paths []string) error {
+	if len(paths) == 0 {
+		paths = updatableLaptopPaths()
+	}
+
+	for _, path := range paths {
+		if immutableLaptopFields[strings.SplitN(path, ".", 2)[0]] {
+			return fmt.Errorf("field %s cannot be updated", path)
+		}
+
+		err := applyPath(dst.ProtoReflect(), src.ProtoReflect(), strings.Split(path, "."))
+		if err != nil {
+			return fmt.Errorf("invalid field mask path %q: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func applyPath(dst protoreflect.Message, src protoreflect.Message, names []string) error {
+	field := dst.Descriptor().Fields().ByName(protoreflect.Name(names[0]))
+	if field == nil {
+		return fmt.Errorf("unknown field %s", names[0])
+	}
+
+	if len(names) == 1 {
+		if src.IsValid() && src.Has(field) {
+			dst.Set(field, src.Get(field))
+		} else {
+			dst.Clear(field)
+		}
+		return nil
+	}
+
+	// only singular message fields can have sub-paths
+	if field.Message() == nil || field.IsList() || field.IsMap() {
+		return fmt.Errorf("field %s has no sub-fields", names[0])
+	}
+
+	return applyPath(dst.Mutable(field).Message(), src.Get(field).Message(), names[1:])
+}
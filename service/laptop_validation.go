@@ -0,0 +1,200 @@
+package service
+
+import (
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"pc_book/pd"
+	"strings"
+	"time"
+)
+
+// maxViolationsInMessage is the number of violations repeated in the message of the error, all of them are in the details
+const maxViolationsInMessage = 3
+
+// violations collects the invalid fields of a message, the field paths are like gpus[0].memory.value
+type violations []*errdetails.BadRequest_FieldViolation
+
+func (v *violations) add(field string, format string, args ...interface{}) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: fmt.Sprintf(format, args...),
+	})
+}
+
+// err returns an InvalidArgument error with a BadRequest detail, or nil if there is no violation
+func (v violations) err(message string) error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, 0, maxViolationsInMessage)
+	for _, violation := range v {
+		if len(descriptions) == maxViolationsInMessage {
+			descriptions = append(descriptions, fmt.Sprintf("and %d more", len(v)-maxViolationsInMessage))
+			break
+		}
+		descriptions = append(descriptions, violation.Field+": "+violation.Description)
+	}
+
+	st := status.New(codes.InvalidArgument, message+": "+strings.Join(descriptions, "; "))
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: v})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// validateLaptop checks every field of a laptop and its sub-messages, the id is checked by prepareNewLaptop
+func validateLaptop(laptop *pd.Laptop) error {
+	var v violations
+
+	requireString(&v, "brand", laptop.GetBrand())
+	requireString(&v, "name", laptop.GetName())
+
+	if laptop.GetCpu() == nil {
+		v.add("cpu", "is required")
+	} else {
+		validateCPU(&v, "cpu", laptop.GetCpu())
+	}
+
+	if laptop.GetRam() == nil {
+		v.add("ram", "is required")
+	} else {
+		validateMemory(&v, "ram", laptop.GetRam())
+	}
+
+	for i, gpu := range laptop.GetGpus() {
+		validateGPU(&v, fmt.Sprintf("gpus[%d]", i), gpu)
+	}
+	for i, storage := range laptop.GetStorages() {
+		validateStorage(&v, fmt.Sprintf("storages[%d]", i), storage)
+	}
+
+	if laptop.GetScreen() == nil {
+		v.add("screen", "is required")
+	} else {
+		validateScreen(&v, "screen", laptop.GetScreen())
+	}
+
+	if laptop.GetKeyboard() != nil {
+		validateKeyboard(&v, "keyboard", laptop.GetKeyboard())
+	}
+
+	switch weight := laptop.GetWeight().(type) {
+	case *pd.Laptop_WeightKg:
+		if weight.WeightKg <= 0 {
+			v.add("weight_kg", "must be positive")
+		}
+	case *pd.Laptop_WeightLb:
+		if weight.WeightLb <= 0 {
+			v.add("weight_lb", "must be positive")
+		}
+	}
+
+	if laptop.GetPriceUsd() < 0 {
+		v.add("price_usd", "must not be negative")
+	}
+	// 0 表示未知
+	if maxYear := uint32(time.Now().Year() + 1); laptop.GetReleaseYear() > maxYear {
+		v.add("release_year", "must not be after %d", maxYear)
+	}
+
+	return v.err("invalid laptop")
+}
+
+func validateCPU(v *violations, field string, cpu *pd.CPU) {
+	requireString(v, field+".brand", cpu.GetBrand())
+	requireString(v, field+".name", cpu.GetName())
+
+	if cpu.GetNumberCores() == 0 {
+		v.add(field+".number_cores", "must be positive")
+	}
+	if cpu.GetNumberThreads() < cpu.GetNumberCores() {
+		v.add(field+".number_threads", "must not be less than number_cores")
+	}
+	validateFrequency(v, field, cpu.GetMinGhz(), cpu.GetMaxGhz())
+}
+
+func validateGPU(v *violations, field string, gpu *pd.GPU) {
+	requireString(v, field+".brand", gpu.GetBrand())
+	requireString(v, field+".name", gpu.GetName())
+	validateFrequency(v, field, gpu.GetMinGhz(), gpu.GetMaxGhz())
+
+	if gpu.GetMemory() == nil {
+		v.add(field+".memory", "is required")
+	} else {
+		validateMemory(v, field+".memory", gpu.GetMemory())
+	}
+}
+
+func validateFrequency(v *violations, field string, minGhz float64, maxGhz float64) {
+	if minGhz <= 0 {
+		v.add(field+".min_ghz", "must be positive")
+	}
+	if maxGhz < minGhz {
+		v.add(field+".max_ghz", "must not be less than min_ghz")
+	}
+}
+
+func validateMemory(v *violations, field string, memory *pd.Memory) {
+	if memory.GetValue() == 0 {
+		v.add(field+".value", "must be positive")
+	}
+	if !isKnownEnum(pd.Memory_Unit_name, int32(memory.GetUnit())) {
+		v.add(field+".unit", "must be a known unit")
+	}
+}
+
+func validateStorage(v *violations, field string, storage *pd.Storage) {
+	if !isKnownEnum(pd.Storage_Driver_name, int32(storage.GetDriver())) {
+		v.add(field+".driver", "must be a known driver")
+	}
+
+	if storage.GetMemory() == nil {
+		v.add(field+".memory", "is required")
+	} else {
+		validateMemory(v, field+".memory", storage.GetMemory())
+	}
+}
+
+func validateScreen(v *violations, field string, screen *pd.Screen) {
+	if screen.GetSizeInch() <= 0 {
+		v.add(field+".size_inch", "must be positive")
+	}
+
+	resolution := screen.GetResolution()
+	if resolution == nil {
+		v.add(field+".resolution", "is required")
+	} else {
+		if resolution.GetWidth() == 0 {
+			v.add(field+".resolution.width", "must be positive")
+		}
+		if resolution.GetHeight() == 0 {
+			v.add(field+".resolution.height", "must be positive")
+		}
+	}
+
+	if !isKnownEnum(pd.Screen_Panel_name, int32(screen.GetPanel())) {
+		v.add(field+".panel", "must be a known panel")
+	}
+}
+
+func validateKeyboard(v *violations, field string, keyboard *pd.Keyboard) {
+	if !isKnownEnum(pd.Keyboard_Layout_name, int32(keyboard.GetLayout())) {
+		v.add(field+".layout", "must be a known layout")
+	}
+}
+
+func requireString(v *violations, field string, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+	}
+}
+
+// isKnownEnum returns true if value is a defined value of the enum other than UNKNOWN, which is always 0
+func isKnownEnum(names map[int32]string, value int32) bool {
+	_, ok := names[value]
+	return ok && value != 0
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"google.golang.org/protobuf/proto"
+	"pc_book/pd"
+)
+
+// pageToken is the content of SearchLaptopResponse.next_page_token.
+// It remembers the query, so that it cannot be used to resume a different search.
+type pageToken struct {
+	SortBy     pd.SearchLaptopRequest_SortBy `json:"s"`
+	Descending bool                          `json:"d,omitempty"`
+	// Filter is a hash of the filter and the text query
+	Filter string  `json:"f"`
+	Value  float64 `json:"v"`
+	ID     string  `json:"i"`
+}
+
+// encodePageToken returns an opaque token to resume the query after the cursor
+func encodePageToken(query *SearchQuery, cursor *SearchCursor) (string, error) {
+	data, err := json.Marshal(pageToken{
+		SortBy:     query.SortBy,
+		Descending: query.Descending,
+		Filter:     queryHash(query),
+		Value:      cursor.Value,
+		ID:         cursor.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodePageToken returns the cursor of a token, which must have been created for the same query
+func decodePageToken(query *SearchQuery, token string) (*SearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("malformed page token")
+	}
+
+	var page pageToken
+	err = json.Unmarshal(data, &page)
+	if err != nil {
+		return nil, errors.New("malformed page token")
+	}
+
+	if page.SortBy != query.SortBy || page.Descending != query.Descending || page.Filter != queryHash(query) {
+		return nil, errors.New("page token does not match the search request")
+	}
+
+	return &SearchCursor{Value: page.Value, ID: page.ID}, nil
+}
+
+// queryHash identifies the laptops that a query matches
+func queryHash(query *SearchQuery) string {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(query.Filter)
+	if err != nil {
+		return ""
+	}
+
+	hash := sha256.New()
+	hash.Write(data)
+	hash.Write([]byte(query.Text))
+	sum := hash.Sum(nil)
+	return hex.EncodeToString(sum[:8])
+}
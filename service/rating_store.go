@@ -6,6 +6,8 @@ import "sync"
 type RatingStore interface {
 	// Add adds a new laptop score to store and returns its rating
 	Add(laptopID string, score float64) (*Rating, error)
+	// Get returns the rating of a laptop, or nil if it has not been rated
+	Get(laptopID string) (*Rating, error)
 }
 
 // Rating contains the rating information of a laptop
@@ -28,8 +30,8 @@ func NewInMemoryRatingStore() *InMemoryRatingStore {
 
 // Add add a new laptop score to the store and returns its rating
 func (store *InMemoryRatingStore) Add(laptopID string, score float64) (*Rating, error) {
-	store.mutex.RLock()
-	defer store.mutex.RUnlock()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
 
 	// 去RatingStore中查找元素是否存在
 	rating := store.rating[laptopID]
@@ -46,4 +48,17 @@ func (store *InMemoryRatingStore) Add(laptopID string, score float64) (*Rating,
 
 	store.rating[laptopID] = rating
 	return rating, nil
-}
\ No newline at end of file
+}
+// Get returns a copy of the rating of a laptop, or nil if it has not been rated
+func (store *InMemoryRatingStore) Get(laptopID string) (*Rating, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	rating := store.rating[laptopID]
+	if rating == nil {
+		return nil, nil
+	}
+
+	other := *rating
+	return &other, nil
+}
@@ -0,0 +1,445 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultS3PartSize is the size of the parts of a multipart upload, S3 requires at least 5 MiB except for the last one
+const defaultS3PartSize = 8 << 20
+
+// S3Config is the configuration of a bucket of an S3-compatible endpoint
+type S3Config struct {
+	// Endpoint is the URL of the service, like https://s3.us-east-1.amazonaws.com or http://localhost:9000.
+	// The bucket is in the path of the requests, which every S3-compatible service supports.
+	Endpoint        string
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PartSize is the size of the parts of the multipart uploads, defaultS3PartSize if it is 0.
+	// The objects smaller than a part are sent with a single request.
+	PartSize int
+	// HTTPClient is http.DefaultClient if it is nil
+	HTTPClient *http.Client
+}
+
+// S3Error is an error response of an S3-compatible service
+type S3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (err *S3Error) Error() string {
+	return fmt.Sprintf("s3 error %d %s: %s", err.StatusCode, err.Code, err.Message)
+}
+
+// s3Client sends signed requests to a bucket
+type s3Client struct {
+	config   S3Config
+	endpoint *url.URL
+	http     *http.Client
+	now      func() time.Time
+}
+
+func newS3Client(config S3Config) (*s3Client, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", config.Endpoint)
+	}
+	if config.Bucket == "" || config.Region == "" {
+		return nil, fmt.Errorf("s3 bucket and region are required")
+	}
+	if config.PartSize <= 0 {
+		config.PartSize = defaultS3PartSize
+	}
+
+	client := &s3Client{
+		config:   config,
+		endpoint: endpoint,
+		http:     config.HTTPClient,
+		now:      time.Now,
+	}
+	if client.http == nil {
+		client.http = http.DefaultClient
+	}
+	return client, nil
+}
+
+// do sends a signed request for an object of the bucket, or for the bucket if key is empty.
+// An error response is returned as an *S3Error, the body of a successful response must be closed.
+func (client *s3Client) do(method string, key string, query url.Values, body []byte) (*http.Response, error) {
+	return client.doWithHeader(method, key, query, nil, body)
+}
+
+// doWithHeader is do with more headers, which are signed with the others
+func (client *s3Client) doWithHeader(method string, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := *client.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + client.config.Bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create s3 request: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	payloadHash := sha256.Sum256(body)
+	signS3Request(req, hex.EncodeToString(payloadHash[:]), client.config, client.now())
+
+	res, err := client.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot send s3 request: %w", err)
+	}
+	if res.StatusCode >= 300 {
+		defer res.Body.Close()
+		return nil, readS3Error(res)
+	}
+	return res, nil
+}
+
+// call sends a request and decodes the XML of the response into result if it is not nil
+func (client *s3Client) call(method string, key string, query url.Values, body []byte, result interface{}) (http.Header, error) {
+	res, err := client.do(method, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if result != nil {
+		err = xml.NewDecoder(res.Body).Decode(result)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode s3 response: %w", err)
+		}
+	} else {
+		io.Copy(ioutil.Discard, res.Body)
+	}
+	return res.Header, nil
+}
+
+func readS3Error(res *http.Response) error {
+	s3Err := &S3Error{StatusCode: res.StatusCode}
+	data, _ := ioutil.ReadAll(io.LimitReader(res.Body, 64<<10))
+	// HEAD and some DELETE responses have no body
+	if len(data) > 0 {
+		xml.Unmarshal(data, s3Err)
+	}
+	if s3Err.Code == "" {
+		s3Err.Code = http.StatusText(res.StatusCode)
+	}
+	return s3Err
+}
+
+// isS3NotFound returns true if the object or the multipart upload doesn't exist
+func isS3NotFound(err error) bool {
+	var s3Err *S3Error
+	return errors.As(err, &s3Err) && s3Err.StatusCode == http.StatusNotFound
+}
+
+// getObject returns the body of an object, or ErrNotFound
+func (client *s3Client) getObject(key string) (io.ReadCloser, error) {
+	res, err := client.do(http.MethodGet, key, nil, nil)
+	if isS3NotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// deleteObject removes an object, S3 doesn't fail if it doesn't exist
+func (client *s3Client) deleteObject(key string) error {
+	_, err := client.call(http.MethodDelete, key, nil, nil, nil)
+	if isS3NotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// headObject returns true if the object exists
+func (client *s3Client) headObject(key string) (bool, error) {
+	res, err := client.do(http.MethodHead, key, nil, nil)
+	if isS3NotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	return true, nil
+}
+
+// copyObject copies an object of the bucket to another key, without reading its data
+func (client *s3Client) copyObject(source string, key string) error {
+	header := http.Header{"X-Amz-Copy-Source": {uriEncode("/"+client.config.Bucket+"/"+source, false)}}
+	res, err := client.doWithHeader(http.MethodPut, key, nil, header, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// S3 can return an error in the body of a successful response once the copy has started
+	s3Err := &S3Error{StatusCode: res.StatusCode}
+	data, _ := ioutil.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if xml.Unmarshal(data, s3Err) == nil && s3Err.Code != "" {
+		return s3Err
+	}
+	return nil
+}
+
+// putObject writes the data to an object and returns its size.
+// The data is read by parts, and sent with a multipart upload if it is larger than one part.
+func (client *s3Client) putObject(key string, data io.Reader) (int64, error) {
+	reader := bufio.NewReader(data)
+	part := make([]byte, client.config.PartSize)
+	n, err := io.ReadFull(reader, part)
+	if err == nil {
+		// 数据正好是一个part
+		_, err = reader.Peek(1)
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		_, err = client.call(http.MethodPut, key, nil, part[:n], nil)
+		return int64(n), err
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	uploadID, err := client.createMultipart(key)
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := client.uploadParts(key, uploadID, part, n, reader)
+	if err != nil {
+		// 删除已经上传的part
+		client.abortMultipart(key, uploadID)
+		return 0, err
+	}
+	return size, nil
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// uploadParts sends the first part, of size n, and then the parts read from data, and completes the multipart upload
+func (client *s3Client) uploadParts(key string, uploadID string, part []byte, n int, data io.Reader) (int64, error) {
+	var parts []s3CompletedPart
+	var size int64
+
+	for n > 0 {
+		completed, err := client.uploadPart(key, uploadID, len(parts)+1, part[:n])
+		if err != nil {
+			return 0, err
+		}
+		parts = append(parts, completed)
+		size += int64(n)
+
+		n, err = io.ReadFull(data, part)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+	}
+
+	err := client.completeMultipart(key, uploadID, parts)
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// createMultipart starts a multipart upload of the object and returns its id
+func (client *s3Client) createMultipart(key string) (string, error) {
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	_, err := client.call(http.MethodPost, key, url.Values{"uploads": {""}}, nil, &initiated)
+	if err != nil {
+		return "", fmt.Errorf("cannot create multipart upload: %w", err)
+	}
+	return initiated.UploadID, nil
+}
+
+// uploadPart sends a part of a multipart upload, every part but the last one must have at least 5 MiB
+func (client *s3Client) uploadPart(key string, uploadID string, number int, data []byte) (s3CompletedPart, error) {
+	query := url.Values{
+		"partNumber": {strconv.Itoa(number)},
+		"uploadId":   {uploadID},
+	}
+	header, err := client.call(http.MethodPut, key, query, data, nil)
+	if err != nil {
+		return s3CompletedPart{}, fmt.Errorf("cannot upload part %d: %w", number, err)
+	}
+	return s3CompletedPart{PartNumber: number, ETag: header.Get("ETag")}, nil
+}
+
+// completeMultipart joins the parts into the object
+func (client *s3Client) completeMultipart(key string, uploadID string, parts []s3CompletedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.call(http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body, nil)
+	if err != nil {
+		return fmt.Errorf("cannot complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+// abortMultipart removes the parts of a multipart upload, it doesn't fail if the upload doesn't exist
+func (client *s3Client) abortMultipart(key string, uploadID string) error {
+	_, err := client.call(http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if isS3NotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// listObjects returns the keys of the objects starting with prefix, reading every page of the list
+func (client *s3Client) listObjects(prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		_, err := client.call(http.MethodGet, "", query, nil, &result)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list objects: %w", err)
+		}
+
+		for _, content := range result.Contents {
+			keys = append(keys, content.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// signS3Request adds the headers of the AWS Signature Version 4 to a request.
+// Every header of the request is signed, with the host.
+func signS3Request(req *http.Request, payloadHash string, config S3Config, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+config.SecretAccessKey), date)
+	key = hmacSHA256(key, config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes the query sorted by name, as the signature requires
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var params []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// uriEncode encodes every byte except the unreserved characters, and except / if encodeSlash is false
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '.' || c == '_' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,551 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3ImageStore stores images in a bucket of an S3-compatible service.
+// The info of an image is in the object images/<id>/info.json, and an empty object laptops/<laptop id>/<id> lists the image with its laptop.
+// The data is content-addressed: images and renditions with the same data share the object blobs/<hex SHA-256>,
+// and each of them has an empty object refs/<hex SHA-256>/<id> or <id>.<rendition>, the blob is removed with its last reference.
+type S3ImageStore struct {
+	// mutex serializes the updates of the info and reference objects
+	mutex  sync.Mutex
+	client *s3Client
+	// uploadMutex serializes the changes of the uploads
+	uploadMutex sync.Mutex
+}
+
+// NewS3ImageStore returns a store of the images in the bucket of the config, the bucket must exist
+func NewS3ImageStore(config S3Config) (*S3ImageStore, error) {
+	client, err := newS3Client(config)
+	if err != nil {
+		return nil, err
+	}
+	return &S3ImageStore{client: client}, nil
+}
+
+func s3ImagePrefix(imageID string) string {
+	return "images/" + imageID + "/"
+}
+
+func s3InfoKey(imageID string) string {
+	return s3ImagePrefix(imageID) + "info.json"
+}
+
+func s3LaptopPrefix(laptopID string) string {
+	return "laptops/" + laptopID + "/"
+}
+
+// s3BlobPrefix is the prefix of the objects of the image data, each one is named by the hex SHA-256 of its data
+const s3BlobPrefix = "blobs/"
+
+func s3BlobKey(checksum string) string {
+	return s3BlobPrefix + checksum
+}
+
+// s3RefPrefix is the prefix of the references to a blob
+func s3RefPrefix(blobKey string) string {
+	return "refs/" + strings.TrimPrefix(blobKey, s3BlobPrefix) + "/"
+}
+
+// s3RefKey is the reference of an image to a blob, or of its rendition if rendition is not empty
+func s3RefKey(blobKey string, imageID string, rendition string) string {
+	key := s3RefPrefix(blobKey) + imageID
+	if rendition != "" {
+		key += "." + rendition
+	}
+	return key
+}
+
+// s3TempKey is the temporary object of the data being saved, until its checksum is known
+func s3TempKey(name string) string {
+	return "tmp/" + name
+}
+
+// isS3BlobKey returns true if key is a blob followed by a hex SHA-256
+func isS3BlobKey(key string) bool {
+	checksum := strings.TrimPrefix(key, s3BlobPrefix)
+	if checksum == key || len(checksum) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range checksum {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// s3Blob is data read before its checksum is known, it is in memory if it is smaller than a part
+// and in the temporary object tmpKey otherwise
+type s3Blob struct {
+	data     []byte
+	tmpKey   string
+	size     int64
+	checksum string
+}
+
+// readBlob reads the data and computes its checksum, the data larger than a part is written to the object tmpKey
+func (store *S3ImageStore) readBlob(tmpKey string, data io.Reader) (*s3Blob, error) {
+	hash := sha256.New()
+	data = io.TeeReader(data, hash)
+
+	// 多读一个字节, 数据正好是一个part时也保存在内存中
+	part := make([]byte, store.client.config.PartSize+1)
+	n, err := io.ReadFull(data, part)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return &s3Blob{data: part[:n], size: int64(n), checksum: hex.EncodeToString(hash.Sum(nil))}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := store.client.putObject(tmpKey, io.MultiReader(bytes.NewReader(part), data))
+	if err != nil {
+		return nil, err
+	}
+	return &s3Blob{tmpKey: tmpKey, size: size, checksum: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// discard removes the temporary object of the blob
+func (store *S3ImageStore) discard(blob *s3Blob) {
+	if blob.tmpKey != "" {
+		store.client.deleteObject(blob.tmpKey)
+	}
+}
+
+// linkBlob writes the reference refKey to the blob of the data, then the blob if it doesn't exist yet.
+// It returns the key of the blob. The mutex must be held.
+func (store *S3ImageStore) linkBlob(blob *s3Blob, refKey string) (string, error) {
+	key := s3BlobKey(blob.checksum)
+	_, err := store.client.putObject(refKey, bytes.NewReader(nil))
+	if err != nil {
+		return "", fmt.Errorf("cannot write image reference: %w", err)
+	}
+
+	exists, err := store.client.headObject(key)
+	if err == nil && !exists {
+		if blob.tmpKey != "" {
+			err = store.client.copyObject(blob.tmpKey, key)
+		} else {
+			_, err = store.client.putObject(key, bytes.NewReader(blob.data))
+		}
+	}
+	if err != nil {
+		store.client.deleteObject(refKey)
+		return "", fmt.Errorf("cannot write image object: %w", err)
+	}
+	return key, nil
+}
+
+// releaseBlob removes the reference refKey to a blob, the blob is removed with its last reference. The mutex must be held.
+func (store *S3ImageStore) releaseBlob(key string, refKey string) error {
+	err := store.client.deleteObject(refKey)
+	if err != nil {
+		return fmt.Errorf("cannot remove image reference: %w", err)
+	}
+
+	refs, err := store.client.listObjects(s3RefPrefix(key))
+	if err != nil {
+		return err
+	}
+	if len(refs) > 0 {
+		return nil
+	}
+
+	err = store.client.deleteObject(key)
+	if err != nil {
+		return fmt.Errorf("cannot remove image object: %w", err)
+	}
+	return nil
+}
+
+// Save reads the data and links it to a new image, then writes the info and the object of the laptop
+func (store *S3ImageStore) Save(laptopID string, imageType string, imageData io.Reader) (*ImageInfo, error) {
+	if !isImageExtension(imageType) {
+		return nil, fmt.Errorf("invalid image type %q", imageType)
+	}
+	if laptopID == "" || strings.Contains(laptopID, "/") {
+		return nil, fmt.Errorf("invalid laptop id %q", laptopID)
+	}
+
+	imageID, err := uuid.NewUUID()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate image id: %w", err)
+	}
+	id := imageID.String()
+
+	blob, err := store.readBlob(s3TempKey(id), imageData)
+	if err != nil {
+		return nil, fmt.Errorf("cannot write image object: %w", err)
+	}
+	defer store.discard(blob)
+
+	return store.addImage(id, laptopID, imageType, blob)
+}
+
+// addImage links the blob to a new image, then writes its info and the object of its laptop
+func (store *S3ImageStore) addImage(id string, laptopID string, imageType string, blob *s3Blob) (*ImageInfo, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	refKey := s3RefKey(s3BlobKey(blob.checksum), id, "")
+	key, err := store.linkBlob(blob, refKey)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ImageInfo{
+		ID:        id,
+		LaptopID:  laptopID,
+		Type:      imageType,
+		Path:      key,
+		Size:      blob.size,
+		Checksum:  blob.checksum,
+		CreatedAt: time.Now(),
+	}
+
+	err = store.writeInfo(info)
+	if err == nil {
+		_, err = store.client.putObject(s3LaptopPrefix(laptopID)+id, bytes.NewReader(nil))
+	}
+	if err != nil {
+		store.client.deleteObject(s3InfoKey(id))
+		store.releaseBlob(key, refKey)
+		return nil, fmt.Errorf("cannot write image info: %w", err)
+	}
+	return info, nil
+}
+
+// writeInfo writes the sidecar of an image to its info object, the files are the keys of the objects
+func (store *S3ImageStore) writeInfo(info *ImageInfo) error {
+	sidecar := newImageSidecar(info, func(path string) string {
+		return path
+	})
+
+	data, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal image info: %w", err)
+	}
+	_, err = store.client.putObject(s3InfoKey(info.ID), bytes.NewReader(data))
+	return err
+}
+
+// Find reads the info object of an image, or returns nil if it doesn't exist
+func (store *S3ImageStore) Find(imageID string) (*ImageInfo, error) {
+	// image ids are uuids, which have the characters of an upload id
+	if !isUploadID(imageID) {
+		return nil, nil
+	}
+
+	body, err := store.client.getObject(s3InfoKey(imageID))
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read image info: %w", err)
+	}
+	defer body.Close()
+
+	var sidecar imageSidecar
+	err = json.NewDecoder(body).Decode(&sidecar)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read image info: %w", err)
+	}
+	if sidecar.ID != imageID {
+		return nil, fmt.Errorf("invalid image info")
+	}
+
+	// 文件必须是blob
+	return sidecar.imageInfo(func(file string) (string, bool) {
+		return file, isS3BlobKey(file)
+	})
+}
+
+// List finds the images of the objects of the laptop, from the oldest
+func (store *S3ImageStore) List(laptopID string) ([]*ImageInfo, error) {
+	if laptopID == "" || strings.Contains(laptopID, "/") {
+		return nil, nil
+	}
+
+	prefix := s3LaptopPrefix(laptopID)
+	keys, err := store.client.listObjects(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []*ImageInfo
+	for _, key := range keys {
+		info, err := store.Find(strings.TrimPrefix(key, prefix))
+		if err != nil {
+			return nil, err
+		}
+		// the image is being deleted
+		if info == nil {
+			continue
+		}
+		images = append(images, info)
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		if !images[i].CreatedAt.Equal(images[j].CreatedAt) {
+			return images[i].CreatedAt.Before(images[j].CreatedAt)
+		}
+		return images[i].ID < images[j].ID
+	})
+	return images, nil
+}
+
+// AddRendition links the data of the rendition to the image, then records it in the info of the image
+func (store *S3ImageStore) AddRendition(imageID string, rendition ImageRendition, data io.Reader) error {
+	if !isImageExtension(rendition.Type) || !isRenditionName(rendition.Name) {
+		return fmt.Errorf("invalid rendition %q of type %q", rendition.Name, rendition.Type)
+	}
+
+	info, err := store.Find(imageID)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return ErrNotFound
+	}
+
+	blob, err := store.readBlob(s3TempKey(imageID+"."+rendition.Name), data)
+	if err != nil {
+		return fmt.Errorf("cannot write image object: %w", err)
+	}
+	defer store.discard(blob)
+	rendition.Size = blob.size
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	// 重新读取info, 其他rendition可能已经加入
+	info, err = store.Find(imageID)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return ErrNotFound
+	}
+
+	refKey := s3RefKey(s3BlobKey(blob.checksum), imageID, rendition.Name)
+	rendition.Path, err = store.linkBlob(blob, refKey)
+	if err != nil {
+		return err
+	}
+
+	var replacedPath string
+	if replaced := info.rendition(rendition.Name); replaced != nil {
+		replacedPath = replaced.Path
+		*replaced = rendition
+	} else {
+		info.Renditions = append(info.Renditions, &rendition)
+	}
+
+	err = store.writeInfo(info)
+	if err != nil {
+		// the reference is still used by the replaced rendition if it has the same data
+		if replacedPath != rendition.Path {
+			store.releaseBlob(rendition.Path, refKey)
+		}
+		return fmt.Errorf("cannot write image info: %w", err)
+	}
+
+	if replacedPath != "" && replacedPath != rendition.Path {
+		return store.releaseBlob(replacedPath, s3RefKey(replacedPath, imageID, rendition.Name))
+	}
+	return nil
+}
+
+// Open reads the object of the image, or of its rendition
+func (store *S3ImageStore) Open(imageID string, rendition string) (io.ReadCloser, error) {
+	info, err := store.Find(imageID)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, ErrNotFound
+	}
+
+	key := info.Path
+	if rendition != "" {
+		r := info.rendition(rendition)
+		if r == nil {
+			return nil, ErrNotFound
+		}
+		key = r.Path
+	}
+
+	body, err := store.client.getObject(key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open image object: %w", err)
+	}
+	return body, nil
+}
+
+// Delete removes the info object first, so that the image is not found anymore, then its laptop object and its references
+func (store *S3ImageStore) Delete(imageID string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	info, err := store.Find(imageID)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return ErrNotFound
+	}
+
+	err = store.client.deleteObject(s3InfoKey(imageID))
+	if err != nil {
+		return fmt.Errorf("cannot remove image info: %w", err)
+	}
+
+	err = store.client.deleteObject(s3LaptopPrefix(info.LaptopID) + imageID)
+	if err != nil {
+		err = fmt.Errorf("cannot remove image object: %w", err)
+	}
+
+	// 继续释放其他blob, 返回第一个错误
+	if releaseErr := store.releaseBlob(info.Path, s3RefKey(info.Path, imageID, "")); err == nil {
+		err = releaseErr
+	}
+	for _, rendition := range info.Renditions {
+		releaseErr := store.releaseBlob(rendition.Path, s3RefKey(rendition.Path, imageID, rendition.Name))
+		if err == nil {
+			err = releaseErr
+		}
+	}
+	return err
+}
+
+// Reconcile compares the info objects with the other objects of the bucket and, when laptopExists is not nil, with the laptops.
+// If clean is true the orphan objects, the broken images and the orphan images are removed.
+// The objects of the uploads are removed by ExpireUploads, they are not checked.
+// It must run while no image is saved or changed, like before the server starts.
+func (store *S3ImageStore) Reconcile(laptopExists func(laptopID string) (bool, error), clean bool) (*ImageReport, error) {
+	var lists [5][]string
+	for i, prefix := range []string{"images/", "laptops/", s3BlobPrefix, "refs/", "tmp/"} {
+		keys, err := store.client.listObjects(prefix)
+		if err != nil {
+			return nil, err
+		}
+		lists[i] = keys
+	}
+	imageKeys, laptopKeys, blobKeys, refKeys, tmpKeys := lists[0], lists[1], lists[2], lists[3], lists[4]
+
+	report := &ImageReport{Cleaned: clean}
+	listed := make(map[string]bool)
+	objects := make(map[string][]string)
+	for _, key := range imageKeys {
+		id := strings.SplitN(strings.TrimPrefix(key, "images/"), "/", 2)[0]
+		objects[id] = append(objects[id], key)
+	}
+	for _, keys := range lists {
+		for _, key := range keys {
+			listed[key] = true
+		}
+	}
+
+	images := make(map[string]*ImageInfo)
+	// refs 每个image和rendition的引用, blobs 被引用的blob
+	refs := make(map[string]bool)
+	blobs := make(map[string]bool)
+	var removed []string
+	for id, keys := range objects {
+		info, err := store.Find(id)
+		if err != nil {
+			return nil, err
+		}
+		// 没有info: 保存失败后没有删除的object
+		if info == nil {
+			report.OrphanFiles = append(report.OrphanFiles, keys...)
+			continue
+		}
+		images[id] = info
+
+		for _, key := range keys {
+			if key != s3InfoKey(id) {
+				report.OrphanFiles = append(report.OrphanFiles, key)
+			}
+		}
+		refs[s3RefKey(info.Path, id, "")] = true
+		blobs[info.Path] = true
+		for _, rendition := range info.Renditions {
+			refs[s3RefKey(rendition.Path, id, rendition.Name)] = true
+			blobs[rendition.Path] = true
+		}
+
+		// the laptop object is missing if the save failed after the info was written
+		if !listed[info.Path] || !listed[s3LaptopPrefix(info.LaptopID)+id] {
+			report.BrokenImages = append(report.BrokenImages, id)
+			removed = append(removed, id)
+			continue
+		}
+
+		if laptopExists != nil {
+			exists, err := laptopExists(info.LaptopID)
+			if err != nil {
+				return nil, fmt.Errorf("cannot find laptop of image: %w", err)
+			}
+			if !exists {
+				report.OrphanImages = append(report.OrphanImages, id)
+				removed = append(removed, id)
+			}
+		}
+	}
+
+	// the object of a laptop must match the laptop of its image
+	for _, key := range laptopKeys {
+		parts := strings.Split(strings.TrimPrefix(key, "laptops/"), "/")
+		if len(parts) != 2 || images[parts[1]] == nil || images[parts[1]].LaptopID != parts[0] {
+			report.OrphanFiles = append(report.OrphanFiles, key)
+		}
+	}
+	for _, key := range refKeys {
+		if !refs[key] {
+			report.OrphanFiles = append(report.OrphanFiles, key)
+		}
+	}
+	for _, key := range blobKeys {
+		if !blobs[key] {
+			report.OrphanFiles = append(report.OrphanFiles, key)
+		}
+	}
+	// the temporary objects are left by a failed save
+	report.OrphanFiles = append(report.OrphanFiles, tmpKeys...)
+
+	sort.Strings(report.OrphanFiles)
+	sort.Strings(report.BrokenImages)
+	sort.Strings(report.OrphanImages)
+
+	if !clean {
+		return report, nil
+	}
+
+	for _, key := range report.OrphanFiles {
+		err := store.client.deleteObject(key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot remove orphan object: %w", err)
+		}
+	}
+	for _, id := range removed {
+		err := store.Delete(id)
+		if err != nil && err != ErrNotFound {
+			return nil, fmt.Errorf("cannot remove image: %w", err)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,545 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s3UploadState is the state object of an upload of S3ImageStore.
+// Each AppendUpload writes its data to a segment object named by its offset. Once the segments not sent yet
+// have the data of a part, they are also sent as the next part of a multipart upload of the data object.
+// The segments are kept until the upload is complete, to read the data received.
+type s3UploadState struct {
+	uploadState
+	// MultipartID is the multipart upload of the data object, it is empty until the first part is sent
+	MultipartID string            `json:"multipart_id,omitempty"`
+	Parts       []s3CompletedPart `json:"parts,omitempty"`
+	// PartsSize is the size of the data sent in the parts
+	PartsSize int64 `json:"parts_size"`
+	// Hash is the state of the SHA-256 of the data received
+	Hash []byte `json:"hash,omitempty"`
+}
+
+func s3UploadPrefix(uploadID string) string {
+	return "uploads/" + uploadID + "/"
+}
+
+func s3UploadStateKey(uploadID string) string {
+	return s3UploadPrefix(uploadID) + "state.json"
+}
+
+func s3UploadDataKey(uploadID string) string {
+	return s3UploadPrefix(uploadID) + "data"
+}
+
+func s3SegmentPrefix(uploadID string) string {
+	return s3UploadPrefix(uploadID) + "segments/"
+}
+
+// s3SegmentKey pads the offset, so that the segments are listed in order
+func s3SegmentKey(uploadID string, offset int64) string {
+	return fmt.Sprintf("%s%020d", s3SegmentPrefix(uploadID), offset)
+}
+
+// readUploadState reads the state object of an upload, or returns nil if it doesn't exist
+func (store *S3ImageStore) readUploadState(uploadID string) (*s3UploadState, error) {
+	body, err := store.client.getObject(s3UploadStateKey(uploadID))
+	if err == ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read upload state: %w", err)
+	}
+	defer body.Close()
+
+	state := &s3UploadState{}
+	err = json.NewDecoder(body).Decode(state)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read upload state: %w", err)
+	}
+	if state.ID != uploadID {
+		return nil, fmt.Errorf("invalid upload state")
+	}
+	return state, nil
+}
+
+func (store *S3ImageStore) writeUploadState(state *s3UploadState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal upload state: %w", err)
+	}
+	_, err = store.client.putObject(s3UploadStateKey(state.ID), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("cannot write upload state: %w", err)
+	}
+	return nil
+}
+
+// StartUpload writes the state object of the upload, or returns the existing upload
+func (store *S3ImageStore) StartUpload(uploadID string, laptopID string, imageType string) (*ImageUpload, error) {
+	if !isUploadID(uploadID) {
+		return nil, fmt.Errorf("invalid upload id %q", uploadID)
+	}
+
+	store.uploadMutex.Lock()
+	defer store.uploadMutex.Unlock()
+
+	state, err := store.readUploadState(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		if state.LaptopID != laptopID || state.Type != imageType {
+			return nil, ErrConflict
+		}
+		upload := ImageUpload(state.uploadState)
+		return &upload, nil
+	}
+
+	hash, err := sha256.New().(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("cannot save upload checksum: %w", err)
+	}
+	state = &s3UploadState{
+		uploadState: uploadState{
+			ID:        uploadID,
+			LaptopID:  laptopID,
+			Type:      imageType,
+			UpdatedAt: time.Now(),
+		},
+		Hash: hash,
+	}
+	err = store.writeUploadState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	upload := ImageUpload(state.uploadState)
+	return &upload, nil
+}
+
+// FindUpload reads the state object of the upload, or returns nil if it doesn't exist
+func (store *S3ImageStore) FindUpload(uploadID string) (*ImageUpload, error) {
+	if !isUploadID(uploadID) {
+		return nil, nil
+	}
+
+	state, err := store.readUploadState(uploadID)
+	if err != nil || state == nil {
+		return nil, err
+	}
+	upload := ImageUpload(state.uploadState)
+	return &upload, nil
+}
+
+// AppendUpload writes the data to a new segment, then the new offset to the state object.
+// The segment is removed if the state cannot be written, a segment after the offset is replaced by the next one.
+func (store *S3ImageStore) AppendUpload(uploadID string, offset int64, data []byte) (int64, error) {
+	if !isUploadID(uploadID) {
+		return 0, ErrNotFound
+	}
+
+	store.uploadMutex.Lock()
+	defer store.uploadMutex.Unlock()
+
+	state, err := store.readUploadState(uploadID)
+	if err != nil {
+		return 0, err
+	}
+	if state == nil {
+		return 0, ErrNotFound
+	}
+	if state.ImageID != "" || offset != state.Offset {
+		return 0, ErrConflict
+	}
+
+	hash := sha256.New()
+	err = hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(state.Hash)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read upload checksum: %w", err)
+	}
+	hash.Write(data)
+
+	next := *state
+	next.Offset += int64(len(data))
+	next.UpdatedAt = time.Now()
+	next.Hash, err = hash.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return 0, fmt.Errorf("cannot save upload checksum: %w", err)
+	}
+
+	key := s3SegmentKey(uploadID, offset)
+	_, err = store.client.putObject(key, bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("cannot write upload object: %w", err)
+	}
+
+	// 未发送的segment有一个part的数据时, 作为下一个part发送
+	if next.Offset-next.PartsSize >= int64(store.client.config.PartSize) {
+		err = store.sendPart(&next)
+	}
+	if err == nil {
+		err = store.writeUploadState(&next)
+	}
+	if err != nil {
+		if state.MultipartID == "" && next.MultipartID != "" {
+			store.client.abortMultipart(s3UploadDataKey(uploadID), next.MultipartID)
+		}
+		store.client.deleteObject(key)
+		return 0, err
+	}
+	return next.Offset, nil
+}
+
+// sendPart sends the segments after the parts as the next part of the multipart upload, which is created with the first part
+func (store *S3ImageStore) sendPart(state *s3UploadState) error {
+	data, err := store.readSegments(state.ID, state.PartsSize, state.Offset)
+	if err != nil {
+		return err
+	}
+
+	key := s3UploadDataKey(state.ID)
+	if state.MultipartID == "" {
+		state.MultipartID, err = store.client.createMultipart(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	part, err := store.client.uploadPart(key, state.MultipartID, len(state.Parts)+1, data)
+	if err != nil {
+		return err
+	}
+	state.Parts = append(state.Parts[:len(state.Parts):len(state.Parts)], part)
+	state.PartsSize = state.Offset
+	return nil
+}
+
+// segmentKeys returns the keys of the segments of the data from offset from to offset to, in order
+func (store *S3ImageStore) segmentKeys(uploadID string, from int64, to int64) ([]string, error) {
+	prefix := s3SegmentPrefix(uploadID)
+	keys, err := store.client.listObjects(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []string
+	for _, key := range keys {
+		offset, err := strconv.ParseInt(strings.TrimPrefix(key, prefix), 10, 64)
+		if err == nil && offset >= from && offset < to {
+			segments = append(segments, key)
+		}
+	}
+	return segments, nil
+}
+
+// readSegments reads the data of the segments from offset from to offset to, each one must start where the previous one ends
+func (store *S3ImageStore) readSegments(uploadID string, from int64, to int64) ([]byte, error) {
+	keys, err := store.segmentKeys(uploadID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, to-from)
+	for _, key := range keys {
+		if key != s3SegmentKey(uploadID, from+int64(len(data))) {
+			return nil, fmt.Errorf("upload object %s is not at offset %d", key, from+int64(len(data)))
+		}
+
+		body, err := store.client.getObject(key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read upload object: %w", err)
+		}
+		segment, err := ioutil.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read upload object: %w", err)
+		}
+		data = append(data, segment...)
+	}
+
+	if from+int64(len(data)) != to {
+		return nil, fmt.Errorf("upload data ends at %d instead of %d", from+int64(len(data)), to)
+	}
+	return data, nil
+}
+
+// OpenUpload reads the segments of an upload that is not complete, one after the other
+func (store *S3ImageStore) OpenUpload(uploadID string) (io.ReadCloser, error) {
+	if !isUploadID(uploadID) {
+		return nil, ErrNotFound
+	}
+
+	store.uploadMutex.Lock()
+	defer store.uploadMutex.Unlock()
+
+	state, err := store.readUploadState(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, ErrNotFound
+	}
+	if state.ImageID != "" {
+		return nil, ErrConflict
+	}
+
+	keys, err := store.segmentKeys(uploadID, 0, state.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &s3ObjectsReader{client: store.client, keys: keys}, nil
+}
+
+// CompleteUpload joins the data of the upload and links it to a new image.
+// The data smaller than a part is read from the segments, the larger data is the object of the multipart upload.
+func (store *S3ImageStore) CompleteUpload(uploadID string, imageType string) (*ImageInfo, error) {
+	if !isImageExtension(imageType) {
+		return nil, fmt.Errorf("invalid image type %q", imageType)
+	}
+	if !isUploadID(uploadID) {
+		return nil, ErrNotFound
+	}
+
+	store.uploadMutex.Lock()
+	defer store.uploadMutex.Unlock()
+
+	state, err := store.readUploadState(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, ErrNotFound
+	}
+
+	// 已经完成的上传返回同一个image
+	if state.ImageID != "" {
+		info, err := store.Find(state.ImageID)
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			return nil, ErrNotFound
+		}
+		return info, nil
+	}
+
+	hash := sha256.New()
+	err = hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(state.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read upload checksum: %w", err)
+	}
+	blob := &s3Blob{size: state.Offset, checksum: hex.EncodeToString(hash.Sum(nil))}
+
+	if state.MultipartID == "" {
+		blob.data, err = store.readSegments(uploadID, 0, state.Offset)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if state.Offset > state.PartsSize {
+			err = store.sendPart(state)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = store.client.completeMultipart(s3UploadDataKey(uploadID), state.MultipartID, state.Parts)
+		if err != nil {
+			return nil, err
+		}
+		blob.tmpKey = s3UploadDataKey(uploadID)
+	}
+
+	imageID, err := uuid.NewUUID()
+	if err == nil {
+		var info *ImageInfo
+		info, err = store.addImage(imageID.String(), state.LaptopID, imageType, blob)
+		if err == nil {
+			return store.completed(state, info), nil
+		}
+	}
+
+	// the multipart upload may be complete, so the upload cannot be resumed
+	store.removeUpload(state)
+	return nil, err
+}
+
+// completed records the image of the upload and removes its data, the upload is kept until it expires
+func (store *S3ImageStore) completed(state *s3UploadState, info *ImageInfo) *ImageInfo {
+	next := s3UploadState{uploadState: state.uploadState}
+	next.ImageID = info.ID
+	next.UpdatedAt = time.Now()
+	err := store.writeUploadState(&next)
+	if err != nil {
+		// the image is saved, only completing the upload again fails
+		log.Printf("cannot record image %s of upload %s: %v", info.ID, state.ID, err)
+		return info
+	}
+
+	keys, err := store.client.listObjects(s3UploadPrefix(state.ID))
+	if err == nil {
+		for _, key := range keys {
+			if key != s3UploadStateKey(state.ID) {
+				store.client.deleteObject(key)
+			}
+		}
+	}
+	return info
+}
+
+// CancelUpload removes the upload and its objects
+func (store *S3ImageStore) CancelUpload(uploadID string) error {
+	if !isUploadID(uploadID) {
+		return ErrNotFound
+	}
+
+	store.uploadMutex.Lock()
+	defer store.uploadMutex.Unlock()
+
+	state, err := store.readUploadState(uploadID)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return ErrNotFound
+	}
+	return store.removeUpload(state)
+}
+
+// ExpireUploads removes the uploads not updated since before, and the objects of the uploads without state
+func (store *S3ImageStore) ExpireUploads(before time.Time) (int, error) {
+	keys, err := store.client.listObjects("uploads/")
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []string
+	for _, key := range keys {
+		id := strings.SplitN(strings.TrimPrefix(key, "uploads/"), "/", 2)[0]
+		if len(ids) == 0 || ids[len(ids)-1] != id {
+			ids = append(ids, id)
+		}
+	}
+
+	count := 0
+	for _, id := range ids {
+		removed, err := store.expireUpload(id, before)
+		if err != nil {
+			return count, err
+		}
+		if removed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// expireUpload removes the upload if it is not updated since before, it returns false if it is kept or has no state
+func (store *S3ImageStore) expireUpload(uploadID string, before time.Time) (bool, error) {
+	store.uploadMutex.Lock()
+	defer store.uploadMutex.Unlock()
+
+	state, err := store.readUploadState(uploadID)
+	if err != nil {
+		return false, err
+	}
+	if state == nil {
+		// 删除失败后留下的object
+		return false, store.removeUploadObjects(uploadID)
+	}
+	if !state.UpdatedAt.Before(before) {
+		return false, nil
+	}
+	return true, store.removeUpload(state)
+}
+
+// removeUpload aborts the multipart upload and removes the objects of the upload, the uploadMutex must be held
+func (store *S3ImageStore) removeUpload(state *s3UploadState) error {
+	if state.MultipartID != "" {
+		err := store.client.abortMultipart(s3UploadDataKey(state.ID), state.MultipartID)
+		if err != nil {
+			return fmt.Errorf("cannot abort multipart upload: %w", err)
+		}
+	}
+	return store.removeUploadObjects(state.ID)
+}
+
+// removeUploadObjects removes the objects of an upload, the state is removed last
+func (store *S3ImageStore) removeUploadObjects(uploadID string) error {
+	keys, err := store.client.listObjects(s3UploadPrefix(uploadID))
+	if err != nil {
+		return err
+	}
+
+	// the upload can be removed again while its state exists
+	stateKey := s3UploadStateKey(uploadID)
+	for _, key := range keys {
+		if key == stateKey {
+			continue
+		}
+		err := store.client.deleteObject(key)
+		if err != nil {
+			return fmt.Errorf("cannot remove upload object: %w", err)
+		}
+	}
+
+	err = store.client.deleteObject(stateKey)
+	if err != nil {
+		return fmt.Errorf("cannot remove upload state: %w", err)
+	}
+	return nil
+}
+
+// s3ObjectsReader reads the objects one after the other, each one is opened once the previous one is read
+type s3ObjectsReader struct {
+	client *s3Client
+	keys   []string
+	body   io.ReadCloser
+}
+
+func (reader *s3ObjectsReader) Read(p []byte) (int, error) {
+	for {
+		if reader.body == nil {
+			if len(reader.keys) == 0 {
+				return 0, io.EOF
+			}
+			body, err := reader.client.getObject(reader.keys[0])
+			if err != nil {
+				return 0, fmt.Errorf("cannot read upload object: %w", err)
+			}
+			reader.body = body
+			reader.keys = reader.keys[1:]
+		}
+
+		n, err := reader.body.Read(p)
+		if err == io.EOF {
+			reader.body.Close()
+			reader.body = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (reader *s3ObjectsReader) Close() error {
+	if reader.body == nil {
+		return nil
+	}
+	err := reader.body.Close()
+	reader.body = nil
+	return err
+}
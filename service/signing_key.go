@@ -0,0 +1,133 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"io/ioutil"
+)
+
+// SigningKey is a key used by JWTManager to sign and verify tokens
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	// signKey is nil for keys that can only verify tokens
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACSigningKey returns a symmetric key that signs with HS256
+func NewHMACSigningKey(id string, secret string) *SigningKey {
+	return &SigningKey{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// NewSigningKey returns a key that signs with an RSA or ECDSA private key
+func NewSigningKey(id string, privateKey crypto.Signer) (*SigningKey, error) {
+	key, err := NewVerifyingKey(id, privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	key.signKey = privateKey
+	return key, nil
+}
+
+// NewVerifyingKey returns a key that can only verify tokens, e.g. a retired key during rotation
+func NewVerifyingKey(id string, publicKey crypto.PublicKey) (*SigningKey, error) {
+	var method jwt.SigningMethod
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			method = jwt.SigningMethodES256
+		case elliptic.P384():
+			method = jwt.SigningMethodES384
+		case elliptic.P521():
+			method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve: %s", key.Curve.Params().Name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public key type: %T", publicKey)
+	}
+
+	return &SigningKey{
+		ID:        id,
+		Method:    method,
+		verifyKey: publicKey,
+	}, nil
+}
+
+// LoadSigningKeyFromPEM loads a key from a PEM file.
+// A private key (PKCS#1, PKCS#8 or SEC 1) can sign tokens, a public key (PKIX) can only verify them.
+func LoadSigningKeyFromPEM(id string, filename string) (*SigningKey, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", filename)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse RSA private key: %w", err)
+		}
+		return NewSigningKey(id, privateKey)
+	case "EC PRIVATE KEY":
+		privateKey, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse EC private key: %w", err)
+		}
+		return NewSigningKey(id, privateKey)
+	case "PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse private key: %w", err)
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type: %T", privateKey)
+		}
+		return NewSigningKey(id, signer)
+	case "PUBLIC KEY":
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse public key: %w", err)
+		}
+		return NewVerifyingKey(id, publicKey)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
+	}
+}
+
+// CanSign reports whether the key holds the secret needed to sign tokens
+func (key *SigningKey) CanSign() bool {
+	return key.signKey != nil
+}
+
+// PublicKey returns the public key of an asymmetric key, or nil for an HMAC key
+func (key *SigningKey) PublicKey() crypto.PublicKey {
+	if _, ok := key.Method.(*jwt.SigningMethodHMAC); ok {
+		return nil
+	}
+	return key.verifyKey
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"math"
+	"pc_book/pd"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// weights of the laptop fields in the text search, a match on the name counts more than on the GPU
+const (
+	brandWeight   = 2.0
+	nameWeight    = 3.0
+	cpuNameWeight = 1.0
+	gpuNameWeight = 1.0
+
+	// prefixMatchFactor is applied to a word that only starts with the query word
+	prefixMatchFactor = 0.5
+)
+
+// tokenize splits a text into lower-case words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// queryTokens returns the distinct words of a search query
+func queryTokens(text string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, token := range tokenize(text) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// laptopTerms returns the words of the searchable fields of a laptop with their weights
+func laptopTerms(laptop *pd.Laptop) map[string]float64 {
+	terms := make(map[string]float64)
+	add := func(text string, weight float64) {
+		for _, token := range tokenize(text) {
+			terms[token] += weight
+		}
+	}
+
+	add(laptop.GetBrand(), brandWeight)
+	add(laptop.GetName(), nameWeight)
+	add(laptop.GetCpu().GetName(), cpuNameWeight)
+	for _, gpu := range laptop.GetGpus() {
+		add(gpu.GetName(), gpuNameWeight)
+	}
+	return terms
+}
+
+// posting is a word of a laptop in the inverted index
+type posting struct {
+	laptopID string
+	token    string
+	weight   float64
+}
+
+// scoreText returns the relevance of the laptops that match every query word.
+// matches holds the postings of the words starting with each query word, total is the number of laptops.
+func scoreText(tokens []string, matches [][]posting, total int) map[string]float64 {
+	var scores map[string]float64
+
+	for i, token := range tokens {
+		// the best matching word of each laptop
+		best := make(map[string]float64)
+		for _, p := range matches[i] {
+			weight := p.weight
+			if p.token != token {
+				weight *= prefixMatchFactor
+			}
+			if weight > best[p.laptopID] {
+				best[p.laptopID] = weight
+			}
+		}
+
+		// 越少见的词权重越高
+		idf := math.Log(1 + float64(total)/float64(len(best)+1))
+
+		next := make(map[string]float64)
+		for id, weight := range best {
+			if scores == nil {
+				next[id] = weight * idf
+			} else if score, ok := scores[id]; ok {
+				next[id] = score + weight*idf
+			}
+		}
+		scores = next
+	}
+	return scores
+}
+
+// textIndex is an inverted index from the words of the laptops to their ids
+type textIndex struct {
+	postings map[string]map[string]float64
+	// tokens are the indexed words in order, to find the words with a prefix
+	tokens []string
+	total  int
+}
+
+func newTextIndex() *textIndex {
+	return &textIndex{postings: make(map[string]map[string]float64)}
+}
+
+func (index *textIndex) add(laptop *pd.Laptop) {
+	for token, weight := range laptopTerms(laptop) {
+		ids := index.postings[token]
+		if ids == nil {
+			ids = make(map[string]float64)
+			index.postings[token] = ids
+
+			i := sort.SearchStrings(index.tokens, token)
+			index.tokens = append(index.tokens, "")
+			copy(index.tokens[i+1:], index.tokens[i:])
+			index.tokens[i] = token
+		}
+		ids[laptop.GetId()] = weight
+	}
+	index.total++
+}
+
+func (index *textIndex) remove(laptop *pd.Laptop) {
+	for token := range laptopTerms(laptop) {
+		ids := index.postings[token]
+		delete(ids, laptop.GetId())
+		if len(ids) == 0 {
+			delete(index.postings, token)
+
+			i := sort.SearchStrings(index.tokens, token)
+			if i < len(index.tokens) && index.tokens[i] == token {
+				index.tokens = append(index.tokens[:i], index.tokens[i+1:]...)
+			}
+		}
+	}
+	index.total--
+}
+
+// prefix returns the postings of all the words that start with prefix
+func (index *textIndex) prefix(prefix string) []posting {
+	var result []posting
+	for i := sort.SearchStrings(index.tokens, prefix); i < len(index.tokens); i++ {
+		token := index.tokens[i]
+		if !strings.HasPrefix(token, prefix) {
+			break
+		}
+		for id, weight := range index.postings[token] {
+			result = append(result, posting{laptopID: id, token: token, weight: weight})
+		}
+	}
+	return result
+}
+
+// search returns the relevance of the laptops that match every word of the query
+func (index *textIndex) search(tokens []string) map[string]float64 {
+	matches := make([][]posting, len(tokens))
+	for i, token := range tokens {
+		matches[i] = index.prefix(token)
+	}
+	return scoreText(tokens, matches, index.total)
+}
@@ -5,6 +5,17 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// The roles of the users, the auth interceptor gives each RPC to some of them
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// isUserRole returns true if the role is one of the roles above
+func isUserRole(role string) bool {
+	return role == RoleAdmin || role == RoleUser
+}
+
 // User contains user's information
 type User struct {
 	UserName       string
@@ -14,18 +25,28 @@ type User struct {
 
 // NewUser return a new user
 func NewUser(username, password, role string) (*User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := hashPassword(password)
 	if err != nil {
-		return nil, fmt.Errorf("cannot hash password: %v", err)
+		return nil, err
 	}
 	user := &User{
 		UserName: username,
-		HashedPassword: string(hashedPassword),
+		HashedPassword: hashedPassword,
 		Role: role,
 	}
 	return user, nil
 }
 
+// SetPassword replaces the password of the user
+func (user *User) SetPassword(password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.HashedPassword = hashedPassword
+	return nil
+}
+
 // IsCorrectPassword check the password provided is correct or not
 func (user *User) IsCorrectPassword(password string) bool {
 	if err := bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(password)); err != nil {
@@ -34,6 +55,14 @@ func (user *User) IsCorrectPassword(password string) bool {
 	return true
 }
 
+func hashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("cannot hash password: %v", err)
+	}
+	return string(hashedPassword), nil
+}
+
 // Clone returns a clone this user
 func (user *User) Clone() *User {
 	return &User{
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"io/ioutil"
+)
+
+// UserSeedConfig is the content of a user seed file
+type UserSeedConfig struct {
+	Users []UserSeed `json:"users"`
+}
+
+// UserSeed is a user to create at startup, the password is stored as a bcrypt hash
+type UserSeed struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	Role         string `json:"role"`
+}
+
+// LoadUserSeedFile reads the users from a JSON seed file
+func LoadUserSeedFile(filename string) ([]*User, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read user seed file: %w", err)
+	}
+
+	config := &UserSeedConfig{}
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse user seed file: %w", err)
+	}
+
+	users := make([]*User, 0, len(config.Users))
+	for i, seed := range config.Users {
+		if seed.Username == "" || seed.Role == "" {
+			return nil, fmt.Errorf("user #%d: username and role are required", i)
+		}
+		if !isUserRole(seed.Role) {
+			return nil, fmt.Errorf("user %s: unknown role %q", seed.Username, seed.Role)
+		}
+
+		_, err := bcrypt.Cost([]byte(seed.PasswordHash))
+		if err != nil {
+			return nil, fmt.Errorf("user %s: password_hash is not a bcrypt hash: %w", seed.Username, err)
+		}
+
+		users = append(users, &User{
+			UserName:       seed.Username,
+			HashedPassword: seed.PasswordHash,
+			Role:           seed.Role,
+		})
+	}
+	return users, nil
+}
+
+// SeedUsers saves the users to the store, users that already exist are kept unchanged
+func SeedUsers(userStore UserStore, users []*User) error {
+	for _, user := range users {
+		err := userStore.Save(user)
+		if err != nil && !errors.Is(err, ErrAlreadyExists) {
+			return fmt.Errorf("cannot save user %s: %w", user.UserName, err)
+		}
+	}
+	return nil
+}
@@ -1,7 +1,7 @@
 package service
 
 import (
-	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -11,6 +11,12 @@ type UserStore interface {
 	Save(user *User) error
 	// Find finds a user by username
 	Find(username string) (*User, error)
+	// Update replaces an existing user in the store
+	Update(user *User) error
+	// Delete deletes a user by username
+	Delete(username string) error
+	// List returns all users ordered by username
+	List() ([]*User, error)
 }
 
 // InMemoryUserStore stores users in memory
@@ -28,8 +34,8 @@ func NewInMemoryUserStore() *InMemoryUserStore {
 
 // Save save the user to the store
 func (store *InMemoryUserStore) Save(user *User) error {
-	store.mutex.RLock()
-	defer store.mutex.RUnlock()
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
 
 	user1 := store.users[user.UserName]
 	if user1 != nil {
@@ -39,15 +45,53 @@ func (store *InMemoryUserStore) Save(user *User) error {
 	return nil
 }
 
-// Find finds a user by username
+// Find finds a user by username, it returns nil if the user doesn't exist
 func (store *InMemoryUserStore) Find(username string) (*User, error) {
 	store.mutex.RLock()
 	defer store.mutex.RUnlock()
 
 	user := store.users[username]
 	if user == nil {
-		return nil, fmt.Errorf("user %v is not exist", username)
+		return nil, nil
 	}
 	return user.Clone(), nil
 }
 
+// Update replaces an existing user in the store
+func (store *InMemoryUserStore) Update(user *User) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.users[user.UserName] == nil {
+		return ErrNotFound
+	}
+	store.users[user.UserName] = user.Clone()
+	return nil
+}
+
+// Delete deletes a user by username
+func (store *InMemoryUserStore) Delete(username string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if store.users[username] == nil {
+		return ErrNotFound
+	}
+	delete(store.users, username)
+	return nil
+}
+
+// List returns all users ordered by username
+func (store *InMemoryUserStore) List() ([]*User, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	users := make([]*User, 0, len(store.users))
+	for _, user := range store.users {
+		users = append(users, user.Clone())
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].UserName < users[j].UserName
+	})
+	return users, nil
+}